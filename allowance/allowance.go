@@ -1,15 +1,17 @@
 package allowance
 
-type KReceiptDeduction struct {
-	KReceipt float64 `json:"kReceipt"`
-}
+import (
+	"math"
+	"strings"
+	"time"
 
-type PersonalDeduction struct {
-	Personal float64 `json:"personalDeduction"`
-}
+	"github.com/varissara-wo/assessment-tax/i18n"
+)
 
 type Amount struct {
-	Amount float64 `json:"amount"`
+	Amount        float64 `json:"amount"`
+	EffectiveFrom string  `json:"effectiveFrom,omitempty"`
+	Reason        string  `json:"reason,omitempty"`
 }
 
 type AllowanceType string
@@ -18,23 +20,83 @@ const (
 	Donation AllowanceType = "donation"
 	KReceipt AllowanceType = "k-receipt"
 	Personal AllowanceType = "personal"
+	RMF      AllowanceType = "rmf"
+	SSF      AllowanceType = "ssf"
 )
 
+const DateLayout = "2006-01-02"
+
+var Bangkok = time.FixedZone("Asia/Bangkok", 7*60*60)
+
+const fundIncomeShare = 0.3
+
 var ValidAllowanceTypes = []AllowanceType{Donation, KReceipt}
 
+var RecommendationAllowanceTypes = []AllowanceType{Donation, KReceipt, RMF, SSF}
+
+var AllowanceTypes = []AllowanceType{Personal, Donation, KReceipt, RMF, SSF}
+
 type Allowance struct {
-	AllowanceType AllowanceType
-	Amount        float64
+	AllowanceType AllowanceType `json:"allowanceType"`
+	Amount        float64       `json:"amount"`
 }
 
 type AllowanceAmount struct {
 	Donation float64
 	KReceipt float64
 	Personal float64
+	RMF      float64
+	SSF      float64
 }
 
 type MaxAllowance struct {
 	Donation float64
 	KReceipt float64
 	Personal float64
+	RMF      float64
+	SSF      float64
+}
+
+type CapRecord struct {
+	ID            int           `json:"id"`
+	AllowanceType AllowanceType `json:"allowanceType"`
+	Amount        float64       `json:"amount"`
+	EffectiveFrom time.Time     `json:"effectiveFrom"`
+	ChangedBy     string        `json:"changedBy"`
+	ApprovedBy    string        `json:"approvedBy,omitempty"`
+	Reason        string        `json:"reason"`
+	CreatedAt     time.Time     `json:"createdAt"`
+}
+
+type CapHistory struct {
+	History []CapRecord `json:"history"`
+}
+
+func (t AllowanceType) Name(l i18n.Lang) string {
+	if s, ok := l.Lookup("ALLOWANCE_" + strings.ToUpper(strings.ReplaceAll(string(t), "-", "_"))); ok {
+		return s
+	}
+	return string(t)
+}
+
+func (ma MaxAllowance) ForIncome(income float64) MaxAllowance {
+	ma.RMF = math.Min(ma.RMF, income*fundIncomeShare)
+	ma.SSF = math.Min(ma.SSF, income*fundIncomeShare)
+	return ma
+}
+
+func (aa AllowanceAmount) Get(t AllowanceType) float64 {
+	switch t {
+	case Donation:
+		return aa.Donation
+	case KReceipt:
+		return aa.KReceipt
+	case Personal:
+		return aa.Personal
+	case RMF:
+		return aa.RMF
+	case SSF:
+		return aa.SSF
+	}
+	return 0.0
 }
@@ -1,30 +1,41 @@
 package allowance
 
 func CalculateAllowances(allowances []Allowance, ma MaxAllowance) float64 {
+	aa := ClaimAllowances(allowances, ma)
+
+	return aa.Donation + aa.KReceipt + aa.Personal + aa.RMF + aa.SSF
+}
+
+func ClaimAllowances(allowances []Allowance, ma MaxAllowance) AllowanceAmount {
 	aa := AllowanceAmount{
 		Donation: 0.0,
 		KReceipt: 0.0,
 		Personal: 0.0,
+		RMF:      0.0,
+		SSF:      0.0,
 	}
 
 	aa.Personal = ma.Personal
 
 	for _, a := range allowances {
 		switch a.AllowanceType {
-		case "donation":
-			if aa.Donation+a.Amount <= ma.Donation {
-				aa.Donation += a.Amount
-			} else {
-				aa.Donation = ma.Donation
-			}
+		case Donation:
+			aa.Donation = claim(aa.Donation, a.Amount, ma.Donation)
 		case KReceipt:
-			if aa.KReceipt+a.Amount <= ma.KReceipt {
-				aa.KReceipt += a.Amount
-			} else {
-				aa.KReceipt = ma.KReceipt
-			}
+			aa.KReceipt = claim(aa.KReceipt, a.Amount, ma.KReceipt)
+		case RMF:
+			aa.RMF = claim(aa.RMF, a.Amount, ma.RMF)
+		case SSF:
+			aa.SSF = claim(aa.SSF, a.Amount, ma.SSF)
 		}
 	}
 
-	return aa.Donation + aa.KReceipt + aa.Personal
+	return aa
+}
+
+func claim(claimed, amount, max float64) float64 {
+	if claimed+amount <= max {
+		return claimed + amount
+	}
+	return max
 }
@@ -55,3 +55,55 @@ func TestAllowancesCalculation(t *testing.T) {
 		}
 	})
 }
+
+func TestClaimAllowances(t *testing.T) {
+	t.Run("should claim each allowance type up to its max", func(t *testing.T) {
+		ma := MaxAllowance{
+			Donation: 100000.0,
+			KReceipt: 50000.0,
+			Personal: 60000.0,
+			RMF:      500000.0,
+			SSF:      200000.0,
+		}
+
+		allowances := []Allowance{
+			{AllowanceType: Donation, Amount: 30000.0},
+			{AllowanceType: KReceipt, Amount: 80000.0},
+			{AllowanceType: RMF, Amount: 100000.0},
+			{AllowanceType: SSF, Amount: 150000.0},
+			{AllowanceType: SSF, Amount: 100000.0},
+		}
+
+		want := AllowanceAmount{
+			Donation: 30000.0,
+			KReceipt: 50000.0,
+			Personal: 60000.0,
+			RMF:      100000.0,
+			SSF:      200000.0,
+		}
+
+		got := ClaimAllowances(allowances, ma)
+
+		if got != want {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+}
+
+func TestMaxAllowanceForIncome(t *testing.T) {
+	ma := MaxAllowance{Donation: 100000.0, KReceipt: 50000.0, Personal: 60000.0, RMF: 500000.0, SSF: 200000.0}
+
+	t.Run("should cap retirement funds at 30% of income", func(t *testing.T) {
+		want := MaxAllowance{Donation: 100000.0, KReceipt: 50000.0, Personal: 60000.0, RMF: 150000.0, SSF: 150000.0}
+
+		if got := ma.ForIncome(500000.0); got != want {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+
+	t.Run("should keep the fixed caps for high incomes", func(t *testing.T) {
+		if got := ma.ForIncome(3000000.0); got != ma {
+			t.Errorf("expected %v but got %v", ma, got)
+		}
+	})
+}
@@ -1,64 +1,306 @@
 package allowance
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/audit"
+	"github.com/varissara-wo/assessment-tax/auth"
+	"github.com/varissara-wo/assessment-tax/problem"
+	"github.com/varissara-wo/assessment-tax/webhook"
 )
 
+var events = map[string]string{
+	audit.ActionPropose: webhook.EventDeductionProposed,
+	audit.ActionApprove: webhook.EventDeductionApproved,
+	audit.ActionReject:  webhook.EventDeductionRejected,
+}
+
+// Audit builds the audit entry of a change from the stored proposal. Stores
+// append it in the same transaction as the change, or skip it when nil.
+type Audit func(Proposal) audit.Entry
+
 type Storer interface {
-	SetPersonal(amount float64) (PersonalDeduction, error)
-	SetKReceipt(amount float64) (KReceiptDeduction, error)
+	CreateProposal(Proposal, Audit) (Proposal, error)
+	Proposal(id int) (Proposal, error)
+	Proposals(ProposalStatus) ([]Proposal, error)
+	ApproveProposal(id int, reviewer string, a Audit) (Proposal, error)
+	RejectProposal(id int, reviewer string, a Audit) (Proposal, error)
+	AllowanceHistory(AllowanceType) ([]CapRecord, error)
+	GetAllowances(at time.Time) (MaxAllowance, error)
 }
 
 type Handler struct {
-	store Storer
+	store           Storer
+	auditor         audit.Recorder
+	publisher       webhook.Publisher
+	allowSelfReview bool
 }
 
-func New(store Storer) *Handler {
-	return &Handler{store: store}
+type Option func(*Handler)
+
+func New(store Storer, opts ...Option) *Handler {
+	h := &Handler{store: store}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
-type Err struct {
-	Message string `json:"message"`
+func WithAuditor(r audit.Recorder) Option {
+	return func(h *Handler) {
+		h.auditor = r
+	}
+}
+
+func WithPublisher(p webhook.Publisher) Option {
+	return func(h *Handler) {
+		h.publisher = p
+	}
+}
+
+// WithSelfReview lets an admin approve or reject their own proposals, for
+// deployments with a single admin identity. Reviews are still audited.
+func WithSelfReview(allowed bool) Option {
+	return func(h *Handler) {
+		h.allowSelfReview = allowed
+	}
 }
 
 func (h *Handler) SetPersonalHandler(c echo.Context) error {
+	return h.propose(c, Personal)
+}
+
+func (h *Handler) SetKReceiptHandler(c echo.Context) error {
+	return h.propose(c, KReceipt)
+}
+
+func (h *Handler) ProposeHandler(c echo.Context) error {
+	return h.propose(c, AllowanceType(c.Param("type")))
+}
+
+func (h *Handler) propose(c echo.Context, t AllowanceType) error {
 	a := Amount{}
 
-	if err := c.Bind(&a); err != nil {
-		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	if _, ok := ManagedAllowances[t]; ok {
+		if err := c.Bind(&a); err != nil {
+			h.record(c, audit.ActionPropose, t, nil, err)
+			return problem.Respond(c, http.StatusBadRequest, err)
+		}
 	}
 
-	if err := a.ValidatePersonal(); err != nil {
-		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	p, status, err := h.Propose(c, t, a)
+	if err != nil {
+		return problem.Respond(c, status, err)
+	}
+
+	return c.JSON(status, p)
+}
+
+func (h *Handler) Propose(c echo.Context, t AllowanceType, a Amount) (Proposal, int, error) {
+	validate, ok := ManagedAllowances[t]
+	if !ok {
+		h.record(c, audit.ActionPropose, t, nil, errUnmanagedAllowance)
+		return Proposal{}, http.StatusNotFound, errUnmanagedAllowance
 	}
 
-	p, err := h.store.SetPersonal(a.Amount)
+	if err := validate(a); err != nil {
+		h.record(c, audit.ActionPropose, t, a, err)
+		return Proposal{}, http.StatusBadRequest, err
+	}
+
+	before := h.current(t)
+	p, err := h.store.CreateProposal(a.Proposal(t, auth.Username(c)), h.change(c, audit.ActionPropose, before, func(p Proposal) interface{} { return p }))
 
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+		h.recordChange(c, audit.ActionPropose, t, before, a, err)
+		return Proposal{}, http.StatusInternalServerError, err
 	}
 
-	return c.JSON(http.StatusOK, p)
+	h.publish(c, audit.ActionPropose, p)
+	return p, http.StatusAccepted, nil
 }
 
-func (h *Handler) SetKReceiptHandler(c echo.Context) error {
-	a := Amount{}
+func (h *Handler) ProposalsHandler(c echo.Context) error {
+	s := ProposalStatus(c.QueryParam("status"))
+	if s == "" {
+		s = Pending
+	}
+
+	if err := ValidateProposalStatus(s); err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	ps, err := h.store.Proposals(s)
+
+	if err != nil {
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, Proposals{Proposals: ps})
+}
+
+func (h *Handler) ApproveProposalHandler(c echo.Context) error {
+	return h.review(c, audit.ActionApprove, h.store.ApproveProposal)
+}
+
+func (h *Handler) RejectProposalHandler(c echo.Context) error {
+	return h.review(c, audit.ActionReject, h.store.RejectProposal)
+}
+
+type decision func(id int, reviewer string, a Audit) (Proposal, error)
+
+func (h *Handler) Approve(c echo.Context, id int) (Proposal, int, error) {
+	return h.decide(c, audit.ActionApprove, id, h.store.ApproveProposal)
+}
+
+func (h *Handler) Reject(c echo.Context, id int) (Proposal, int, error) {
+	return h.decide(c, audit.ActionReject, id, h.store.RejectProposal)
+}
+
+func (h *Handler) review(c echo.Context, action string, decide decision) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		h.record(c, action, "", c.Param("id"), errInvalidProposalID)
+		return problem.Respond(c, http.StatusBadRequest, errInvalidProposalID)
+	}
+
+	p, status, err := h.decide(c, action, id, decide)
+	if err != nil {
+		return problem.Respond(c, status, err)
+	}
+
+	return c.JSON(status, p)
+}
+
+func (h *Handler) decide(c echo.Context, action string, id int, decide decision) (Proposal, int, error) {
+	if id <= 0 {
+		h.record(c, action, "", id, errInvalidProposalID)
+		return Proposal{}, http.StatusBadRequest, errInvalidProposalID
+	}
+
+	p, err := h.store.Proposal(id)
+
+	if errors.Is(err, ErrNotFound) {
+		h.record(c, action, "", id, err)
+		return Proposal{}, http.StatusNotFound, err
+	}
+
+	if err != nil {
+		h.record(c, action, "", id, err)
+		return Proposal{}, http.StatusInternalServerError, err
+	}
+
+	if p.Status != Pending {
+		h.record(c, action, p.AllowanceType, p, ErrNotPending)
+		return Proposal{}, http.StatusConflict, ErrNotPending
+	}
+
+	reviewer := auth.Username(c)
+	if p.ProposedBy == reviewer && !h.allowSelfReview {
+		h.record(c, action, p.AllowanceType, p, errSelfReview)
+		return Proposal{}, http.StatusForbidden, errSelfReview
+	}
+
+	// An approval changes the cap, a rejection only the proposal.
+	var change Audit
+	if action == audit.ActionApprove {
+		change = h.change(c, action, h.current(p.AllowanceType), func(r Proposal) interface{} { return r.Cap() })
+	} else {
+		change = h.change(c, action, p, func(r Proposal) interface{} { return r })
+	}
+	reviewed, err := decide(id, reviewer, change)
+
+	if errors.Is(err, ErrNotPending) {
+		h.record(c, action, p.AllowanceType, p, err)
+		return Proposal{}, http.StatusConflict, err
+	}
+
+	if err != nil {
+		h.record(c, action, p.AllowanceType, p, err)
+		return Proposal{}, http.StatusInternalServerError, err
+	}
+
+	h.publish(c, action, reviewed)
+	return reviewed, http.StatusOK, nil
+}
 
-	if err := c.Bind(&a); err != nil {
-		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+func (h *Handler) publish(c echo.Context, action string, p Proposal) {
+	if h.publisher == nil {
+		return
 	}
+	h.publisher.Publish(c, events[action], p)
+}
+
+func (h *Handler) record(c echo.Context, action string, t AllowanceType, after interface{}, err error) {
+	if h.auditor == nil {
+		return
+	}
+	h.recordChange(c, action, t, h.current(t), after, err)
+}
+
+func (h *Handler) recordChange(c echo.Context, action string, t AllowanceType, before, after interface{}, err error) {
+	if h.auditor == nil {
+		return
+	}
+	h.auditor.Record(c, entry(c, action, t, before, after, err))
+}
+
+func (h *Handler) change(c echo.Context, action string, before interface{}, after func(Proposal) interface{}) Audit {
+	if h.auditor == nil {
+		return nil
+	}
+	return func(p Proposal) audit.Entry {
+		return audit.Stamp(c, entry(c, action, p.AllowanceType, before, after(p), nil))
+	}
+}
+
+func entry(c echo.Context, action string, t AllowanceType, before, after interface{}, err error) audit.Entry {
+	e := audit.Entry{
+		Actor:   auth.Username(c),
+		Action:  action,
+		Outcome: audit.Success,
+		Target:  string(t),
+		Before:  audit.Value(before),
+		After:   audit.Value(after),
+	}
+	if err != nil {
+		e.Outcome = audit.Failure
+		e.Detail = err.Error()
+	}
+	return e
+}
+
+func (h *Handler) current(t AllowanceType) interface{} {
+	if h.auditor == nil || ValidateAllowanceType(t) != nil {
+		return nil
+	}
+
+	ma, err := h.store.GetAllowances(time.Now())
+	if err != nil {
+		return nil
+	}
+
+	return Amount{Amount: AllowanceAmount(ma).Get(t)}
+}
+
+func (h *Handler) AllowanceHistoryHandler(c echo.Context) error {
+	t := AllowanceType(c.QueryParam("type"))
 
-	if err := a.ValidateKReceipt(); err != nil {
-		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	if t != "" {
+		if err := ValidateAllowanceType(t); err != nil {
+			return problem.Respond(c, http.StatusBadRequest, err)
+		}
 	}
 
-	k, err := h.store.SetKReceipt(a.Amount)
+	history, err := h.store.AllowanceHistory(t)
 
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+		return problem.Respond(c, http.StatusInternalServerError, err)
 	}
 
-	return c.JSON(http.StatusOK, k)
+	return c.JSON(http.StatusOK, CapHistory{History: history})
 }
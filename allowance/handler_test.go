@@ -7,24 +7,84 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/audit"
+	"github.com/varissara-wo/assessment-tax/auth"
+	"github.com/varissara-wo/assessment-tax/problem"
+	"github.com/varissara-wo/assessment-tax/webhook"
 )
 
 type stub struct {
-	Amount   Amount
-	Personal PersonalDeduction
-	KReceipt KReceiptDeduction
-	err      error
+	Amount    Amount
+	Pending   Proposal
+	Created   Proposal
+	Listed    []Proposal
+	History   []CapRecord
+	Reviewed  Proposal
+	Reviewer  string
+	Max       MaxAllowance
+	Audited   []audit.Entry
+	err       error
+	reviewErr error
 }
 
-func (s *stub) SetPersonal(amount float64) (PersonalDeduction, error) {
-	return s.Personal, s.err
+type recorder struct {
+	entries []audit.Entry
 }
 
-func (s *stub) SetKReceipt(amount float64) (KReceiptDeduction, error) {
-	return s.KReceipt, s.err
+func (r *recorder) Record(c echo.Context, e audit.Entry) error {
+	r.entries = append(r.entries, e)
+	return nil
+}
+
+func (s *stub) audit(a Audit, p Proposal) {
+	if a != nil {
+		s.Audited = append(s.Audited, a(p))
+	}
+}
+
+func (s *stub) CreateProposal(p Proposal, a Audit) (Proposal, error) {
+	s.Created = p
+	if s.err == nil {
+		s.audit(a, p)
+	}
+	return p, s.err
+}
+
+func (s *stub) Proposal(id int) (Proposal, error) {
+	return s.Pending, s.err
+}
+
+func (s *stub) Proposals(status ProposalStatus) ([]Proposal, error) {
+	return s.Listed, s.err
+}
+
+func (s *stub) ApproveProposal(id int, reviewer string, a Audit) (Proposal, error) {
+	s.Reviewer = reviewer
+	if s.reviewErr == nil {
+		s.audit(a, s.Reviewed)
+	}
+	return s.Reviewed, s.reviewErr
+}
+
+func (s *stub) RejectProposal(id int, reviewer string, a Audit) (Proposal, error) {
+	s.Reviewer = reviewer
+	if s.reviewErr == nil {
+		s.audit(a, s.Reviewed)
+	}
+	return s.Reviewed, s.reviewErr
+}
+
+func (s *stub) AllowanceHistory(t AllowanceType) ([]CapRecord, error) {
+	return s.History, s.err
+}
+
+func (s *stub) GetAllowances(at time.Time) (MaxAllowance, error) {
+	return s.Max, nil
 }
 
 func TestSetPersonalHandler(t *testing.T) {
@@ -47,12 +107,11 @@ func TestSetPersonalHandler(t *testing.T) {
 			t.Errorf("expected error message but got %v", err)
 		}
 
-		var got Err
+		var got problem.Problem
 		json.Unmarshal(rec.Body.Bytes(), &got)
 
-		want := "code=400, message=Unmarshal type error: expected=float64, got=string, field=amount, offset=20, internal=json: cannot unmarshal string into Go struct field Amount.amount of type float64"
-		if got.Message != want {
-			t.Errorf("expected error message %v but got %v", want, got.Message)
+		if got.Code != problem.CodeInvalidBody || !strings.EqualFold(got.Field, "amount") {
+			t.Errorf("expected %v on field amount but got %v on %v", problem.CodeInvalidBody, got.Code, got.Field)
 		}
 
 		if rec.Code != http.StatusBadRequest {
@@ -79,11 +138,11 @@ func TestSetPersonalHandler(t *testing.T) {
 			t.Errorf("expected error message but got %v", err)
 		}
 
-		var gotErr Err
+		var gotErr problem.Problem
 		json.Unmarshal(rec.Body.Bytes(), &gotErr)
 
-		if gotErr.Message != ErrInvalidPersonalGreaterAmount {
-			t.Errorf("expected error message %v but got %v", ErrInvalidPersonalGreaterAmount, gotErr.Message)
+		if gotErr.Detail != ErrInvalidPersonalGreaterAmount {
+			t.Errorf("expected error message %v but got %v", ErrInvalidPersonalGreaterAmount, gotErr.Detail)
 		}
 
 		if rec.Code != http.StatusBadRequest {
@@ -91,21 +150,18 @@ func TestSetPersonalHandler(t *testing.T) {
 		}
 	})
 
-	t.Run("should return 200 and personal deduction if amount is valid", func(t *testing.T) {
+	t.Run("should return 202 and a pending personal deduction proposal if amount is valid", func(t *testing.T) {
 		mockAmount := Amount{Amount: 20000.0}
 		mockAmountJSON, _ := json.Marshal(mockAmount)
 
 		e := echo.New()
-		req := httptest.NewRequest(http.MethodPost, "/admin/personal-deduction", bytes.NewBuffer(mockAmountJSON))
+		req := httptest.NewRequest(http.MethodPost, "/admin/deductions/personal", bytes.NewBuffer(mockAmountJSON))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
+		c.Set(auth.IdentityContextKey, auth.Identity{Username: "alice"})
 
-		st := stub{
-			Personal: PersonalDeduction{
-				Personal: 20000.0,
-			},
-		}
+		st := stub{}
 
 		p := New(&st)
 		err := p.SetPersonalHandler(c)
@@ -114,19 +170,22 @@ func TestSetPersonalHandler(t *testing.T) {
 			t.Errorf("expected nil but got %v", err)
 		}
 
-		var got PersonalDeduction
+		var got Proposal
 		json.Unmarshal(rec.Body.Bytes(), &got)
 
-		want := PersonalDeduction{
-			Personal: 20000.0,
+		want := Proposal{
+			AllowanceType: Personal,
+			Amount:        20000.0,
+			Status:        Pending,
+			ProposedBy:    "alice",
 		}
 
 		if !reflect.DeepEqual(got, want) {
 			t.Errorf("expected %v but got %v", want, got)
 		}
 
-		if rec.Code != http.StatusOK {
-			t.Errorf("expected status code %v but got %v", http.StatusOK, rec.Code)
+		if rec.Code != http.StatusAccepted {
+			t.Errorf("expected status code %v but got %v", http.StatusAccepted, rec.Code)
 		}
 	})
 
@@ -135,7 +194,7 @@ func TestSetPersonalHandler(t *testing.T) {
 		mockAmountJSON, _ := json.Marshal(mockAmount)
 
 		e := echo.New()
-		req := httptest.NewRequest(http.MethodPost, "/admin/personal-deduction", bytes.NewBuffer(mockAmountJSON))
+		req := httptest.NewRequest(http.MethodPost, "/admin/deductions/personal", bytes.NewBuffer(mockAmountJSON))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
@@ -151,13 +210,11 @@ func TestSetPersonalHandler(t *testing.T) {
 			t.Errorf("expected nil but got %v", err)
 		}
 
-		var gotErr Err
+		var gotErr problem.Problem
 		json.Unmarshal(rec.Body.Bytes(), &gotErr)
 
-		want := Err{Message: "failed to update personal deduction"}
-
-		if !reflect.DeepEqual(gotErr, want) {
-			t.Errorf("expected %v but got %v", want, gotErr)
+		if gotErr.Code != "INTERNAL_ERROR" || gotErr.Detail != "internal server error" {
+			t.Errorf("expected internal error without the store error but got %v", gotErr)
 		}
 
 		if rec.Code != http.StatusInternalServerError {
@@ -185,12 +242,11 @@ func TestSetKReceiptHandler(t *testing.T) {
 			t.Errorf("expected error message but got %v", err)
 		}
 
-		var got Err
+		var got problem.Problem
 		json.Unmarshal(rec.Body.Bytes(), &got)
 
-		want := "code=400, message=Unmarshal type error: expected=float64, got=string, field=amount, offset=20, internal=json: cannot unmarshal string into Go struct field Amount.amount of type float64"
-		if got.Message != want {
-			t.Errorf("expected error message %v but got %v", want, got.Message)
+		if got.Code != problem.CodeInvalidBody || !strings.EqualFold(got.Field, "amount") {
+			t.Errorf("expected %v on field amount but got %v on %v", problem.CodeInvalidBody, got.Code, got.Field)
 		}
 
 		if rec.Code != http.StatusBadRequest {
@@ -217,11 +273,11 @@ func TestSetKReceiptHandler(t *testing.T) {
 			t.Errorf("expected error message but got %v", err)
 		}
 
-		var gotErr Err
+		var gotErr problem.Problem
 		json.Unmarshal(rec.Body.Bytes(), &gotErr)
 
-		if gotErr.Message != ErrInvalidKReceiptGreaterAmount {
-			t.Errorf("expected error message %v but got %v", ErrInvalidKReceiptGreaterAmount, gotErr.Message)
+		if gotErr.Detail != ErrInvalidKReceiptGreaterAmount {
+			t.Errorf("expected error message %v but got %v", ErrInvalidKReceiptGreaterAmount, gotErr.Detail)
 		}
 
 		if rec.Code != http.StatusBadRequest {
@@ -250,13 +306,11 @@ func TestSetKReceiptHandler(t *testing.T) {
 			t.Errorf("expected nil but got %v", err)
 		}
 
-		var gotErr Err
+		var gotErr problem.Problem
 		json.Unmarshal(rec.Body.Bytes(), &gotErr)
 
-		want := Err{Message: "failed to update kreceipt deduction"}
-
-		if !reflect.DeepEqual(gotErr, want) {
-			t.Errorf("expected %v but got %v", want, gotErr)
+		if gotErr.Code != "INTERNAL_ERROR" || gotErr.Detail != "internal server error" {
+			t.Errorf("expected internal error without the store error but got %v", gotErr)
 		}
 
 		if rec.Code != http.StatusInternalServerError {
@@ -264,7 +318,7 @@ func TestSetKReceiptHandler(t *testing.T) {
 		}
 	})
 
-	t.Run("should return 200 and kreceipt deduction if amount is valid", func(t *testing.T) {
+	t.Run("should return 202 and a pending kreceipt deduction proposal if amount is valid", func(t *testing.T) {
 		mockAmount := Amount{Amount: 20000.0}
 		mockAmountJSON, _ := json.Marshal(mockAmount)
 
@@ -273,12 +327,9 @@ func TestSetKReceiptHandler(t *testing.T) {
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
+		c.Set(auth.IdentityContextKey, auth.Identity{Username: "alice"})
 
-		st := stub{
-			KReceipt: KReceiptDeduction{
-				KReceipt: 20000.0,
-			},
-		}
+		st := stub{}
 
 		p := New(&st)
 		err := p.SetKReceiptHandler(c)
@@ -287,19 +338,581 @@ func TestSetKReceiptHandler(t *testing.T) {
 			t.Errorf("expected nil but got %v", err)
 		}
 
-		var got KReceiptDeduction
+		var got Proposal
 		json.Unmarshal(rec.Body.Bytes(), &got)
 
-		want := KReceiptDeduction{
-			KReceipt: 20000.0,
+		want := Proposal{
+			AllowanceType: KReceipt,
+			Amount:        20000.0,
+			Status:        Pending,
+			ProposedBy:    "alice",
 		}
 
 		if !reflect.DeepEqual(got, want) {
 			t.Errorf("expected %v but got %v", want, got)
 		}
 
+		if rec.Code != http.StatusAccepted {
+			t.Errorf("expected status code %v but got %v", http.StatusAccepted, rec.Code)
+		}
+	})
+}
+
+func TestProposeHandler(t *testing.T) {
+	t.Run("should record the admin, reason and scheduled date of the proposal", func(t *testing.T) {
+		mockAmountJSON := []byte(`{"amount": 70000.0, "effectiveFrom": "2999-01-01", "reason": "budget act"}`)
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/admin/deductions/personal", bytes.NewBuffer(mockAmountJSON))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("type")
+		c.SetParamValues("personal")
+		c.Set(auth.IdentityContextKey, auth.Identity{Username: "adminTax"})
+
+		st := stub{}
+
+		p := New(&st)
+		err := p.ProposeHandler(c)
+
+		if err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		effectiveFrom := time.Date(2999, 1, 1, 0, 0, 0, 0, Bangkok)
+		want := Proposal{
+			AllowanceType: Personal,
+			Amount:        70000.0,
+			EffectiveFrom: &effectiveFrom,
+			Reason:        "budget act",
+			Status:        Pending,
+			ProposedBy:    "adminTax",
+		}
+
+		if !reflect.DeepEqual(st.Created, want) {
+			t.Errorf("expected %v but got %v", want, st.Created)
+		}
+	})
+
+	t.Run("should return 404 if allowance type is not managed by admins", func(t *testing.T) {
+		mockAmountJSON := []byte(`{"amount": 70000.0}`)
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/admin/deductions/donation", bytes.NewBuffer(mockAmountJSON))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("type")
+		c.SetParamValues("donation")
+
+		p := New(&stub{})
+		err := p.ProposeHandler(c)
+
+		if err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		var gotErr problem.Problem
+		json.Unmarshal(rec.Body.Bytes(), &gotErr)
+
+		if gotErr.Detail != ErrUnmanagedAllowance {
+			t.Errorf("expected error message %v but got %v", ErrUnmanagedAllowance, gotErr.Detail)
+		}
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected status code %v but got %v", http.StatusNotFound, rec.Code)
+		}
+	})
+
+	t.Run("should return 400 if effective from is in the past", func(t *testing.T) {
+		mockAmountJSON := []byte(`{"amount": 70000.0, "effectiveFrom": "2000-01-01"}`)
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/admin/deductions/personal", bytes.NewBuffer(mockAmountJSON))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		p := New(&stub{})
+		err := p.SetPersonalHandler(c)
+
+		if err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		var gotErr problem.Problem
+		json.Unmarshal(rec.Body.Bytes(), &gotErr)
+
+		if gotErr.Detail != ErrInvalidEffectiveFrom {
+			t.Errorf("expected error message %v but got %v", ErrInvalidEffectiveFrom, gotErr.Detail)
+		}
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %v but got %v", http.StatusBadRequest, rec.Code)
+		}
+	})
+}
+
+func TestAllowanceHistoryHandler(t *testing.T) {
+	t.Run("should return 400 if type is invalid", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/admin/deductions/history?type=invalid", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		p := New(&stub{})
+		err := p.AllowanceHistoryHandler(c)
+
+		if err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		var gotErr problem.Problem
+		json.Unmarshal(rec.Body.Bytes(), &gotErr)
+
+		if gotErr.Detail != ErrInvalidHistoryType {
+			t.Errorf("expected error message %v but got %v", ErrInvalidHistoryType, gotErr.Detail)
+		}
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %v but got %v", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("should return 500 if history can't be read", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/admin/deductions/history", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		p := New(&stub{err: errors.New("failed to read history")})
+		err := p.AllowanceHistoryHandler(c)
+
+		if err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("expected status code %v but got %v", http.StatusInternalServerError, rec.Code)
+		}
+	})
+
+	t.Run("should return 200 and the cap history", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/admin/deductions/history?type=personal", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		history := []CapRecord{
+			{
+				ID:            2,
+				AllowanceType: Personal,
+				Amount:        70000.0,
+				EffectiveFrom: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+				ChangedBy:     "adminTax",
+				Reason:        "budget act",
+				CreatedAt:     time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
+			},
+		}
+
+		p := New(&stub{History: history})
+		err := p.AllowanceHistoryHandler(c)
+
+		if err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		var got CapHistory
+		json.Unmarshal(rec.Body.Bytes(), &got)
+
+		if !reflect.DeepEqual(got.History, history) {
+			t.Errorf("expected %v but got %v", history, got.History)
+		}
+
 		if rec.Code != http.StatusOK {
 			t.Errorf("expected status code %v but got %v", http.StatusOK, rec.Code)
 		}
 	})
 }
+
+func TestProposalsHandler(t *testing.T) {
+	t.Run("should return 400 if status is invalid", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/admin/deductions/proposals?status=invalid", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		p := New(&stub{})
+		err := p.ProposalsHandler(c)
+
+		if err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %v but got %v", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("should return 200 and pending proposals", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/admin/deductions/proposals", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		proposals := []Proposal{
+			{ID: 1, AllowanceType: Personal, Amount: 70000.0, Status: Pending, ProposedBy: "alice", CreatedAt: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)},
+		}
+
+		p := New(&stub{Listed: proposals})
+		err := p.ProposalsHandler(c)
+
+		if err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		var got Proposals
+		json.Unmarshal(rec.Body.Bytes(), &got)
+
+		if !reflect.DeepEqual(got.Proposals, proposals) {
+			t.Errorf("expected %v but got %v", proposals, got.Proposals)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status code %v but got %v", http.StatusOK, rec.Code)
+		}
+	})
+}
+
+func TestReviewProposalHandler(t *testing.T) {
+	reviewRequest := func(id string, reviewer string) (echo.Context, *httptest.ResponseRecorder) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/admin/deductions/proposals/"+id+"/approve", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		c.Set(auth.IdentityContextKey, auth.Identity{Username: reviewer})
+		return c, rec
+	}
+
+	testCases := []struct {
+		name       string
+		id         string
+		st         stub
+		wantStatus int
+		wantErr    string
+	}{
+		{
+			name:       "should return 400 if id is not a number",
+			id:         "abc",
+			st:         stub{},
+			wantStatus: http.StatusBadRequest,
+			wantErr:    ErrInvalidProposalID,
+		},
+		{
+			name:       "should return 404 if proposal does not exist",
+			id:         "1",
+			st:         stub{err: ErrNotFound},
+			wantStatus: http.StatusNotFound,
+			wantErr:    ErrProposalNotFound,
+		},
+		{
+			name:       "should return 409 if proposal has already been reviewed",
+			id:         "1",
+			st:         stub{Pending: Proposal{ID: 1, Status: Approved, ProposedBy: "alice"}},
+			wantStatus: http.StatusConflict,
+			wantErr:    ErrProposalNotPending,
+		},
+		{
+			name:       "should return 403 if the proposer reviews their own proposal",
+			id:         "1",
+			st:         stub{Pending: Proposal{ID: 1, Status: Pending, ProposedBy: "bob"}},
+			wantStatus: http.StatusForbidden,
+			wantErr:    ErrSelfReview,
+		},
+		{
+			name:       "should return 409 if proposal was reviewed concurrently",
+			id:         "1",
+			st:         stub{Pending: Proposal{ID: 1, Status: Pending, ProposedBy: "alice"}, reviewErr: ErrNotPending},
+			wantStatus: http.StatusConflict,
+			wantErr:    ErrProposalNotPending,
+		},
+		{
+			name:       "should return 500 if proposal can't be applied",
+			id:         "1",
+			st:         stub{Pending: Proposal{ID: 1, Status: Pending, ProposedBy: "alice"}, reviewErr: errors.New("failed to apply proposal")},
+			wantStatus: http.StatusInternalServerError,
+			wantErr:    "internal server error",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, rec := reviewRequest(tc.id, "bob")
+
+			p := New(&tc.st)
+			err := p.ApproveProposalHandler(c)
+
+			if err != nil {
+				t.Errorf("expected nil but got %v", err)
+			}
+
+			var gotErr problem.Problem
+			json.Unmarshal(rec.Body.Bytes(), &gotErr)
+
+			if gotErr.Detail != tc.wantErr {
+				t.Errorf("expected error message %v but got %v", tc.wantErr, gotErr.Detail)
+			}
+
+			if rec.Code != tc.wantStatus {
+				t.Errorf("expected status code %v but got %v", tc.wantStatus, rec.Code)
+			}
+		})
+	}
+
+	t.Run("should return 200 and the approved proposal if reviewed by a different admin", func(t *testing.T) {
+		c, rec := reviewRequest("1", "bob")
+
+		approved := Proposal{ID: 1, AllowanceType: Personal, Amount: 70000.0, Status: Approved, ProposedBy: "alice", ReviewedBy: "bob"}
+		st := stub{Pending: Proposal{ID: 1, Status: Pending, ProposedBy: "alice"}, Reviewed: approved}
+
+		p := New(&st)
+		err := p.ApproveProposalHandler(c)
+
+		if err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		var got Proposal
+		json.Unmarshal(rec.Body.Bytes(), &got)
+
+		if !reflect.DeepEqual(got, approved) {
+			t.Errorf("expected %v but got %v", approved, got)
+		}
+
+		if st.Reviewer != "bob" {
+			t.Errorf("expected reviewer bob but got %v", st.Reviewer)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status code %v but got %v", http.StatusOK, rec.Code)
+		}
+	})
+
+	t.Run("should let an admin review their own proposal if self review is allowed", func(t *testing.T) {
+		c, rec := reviewRequest("1", "bob")
+
+		approved := Proposal{ID: 1, AllowanceType: Personal, Amount: 70000.0, Status: Approved, ProposedBy: "bob", ReviewedBy: "bob"}
+		st := stub{Pending: Proposal{ID: 1, Status: Pending, ProposedBy: "bob"}, Reviewed: approved}
+
+		p := New(&st, WithSelfReview(true))
+		p.ApproveProposalHandler(c)
+
+		if rec.Code != http.StatusOK || st.Reviewer != "bob" {
+			t.Errorf("expected status code %v and reviewer bob but got %v %v", http.StatusOK, rec.Code, st.Reviewer)
+		}
+	})
+
+	t.Run("should return 200 and the rejected proposal", func(t *testing.T) {
+		c, rec := reviewRequest("1", "bob")
+
+		rejected := Proposal{ID: 1, AllowanceType: Personal, Amount: 70000.0, Status: Rejected, ProposedBy: "alice", ReviewedBy: "bob"}
+		st := stub{Pending: Proposal{ID: 1, Status: Pending, ProposedBy: "alice"}, Reviewed: rejected}
+
+		p := New(&st)
+		err := p.RejectProposalHandler(c)
+
+		if err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		var got Proposal
+		json.Unmarshal(rec.Body.Bytes(), &got)
+
+		if got.Status != Rejected {
+			t.Errorf("expected status %v but got %v", Rejected, got.Status)
+		}
+	})
+}
+
+func TestAuditRecording(t *testing.T) {
+	propose := func(body string, st *stub, r *recorder) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/admin/deductions/personal", bytes.NewBufferString(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set(auth.IdentityContextKey, auth.Identity{Username: "alice"})
+
+		New(st, WithAuditor(r)).SetPersonalHandler(c)
+		return rec
+	}
+
+	t.Run("should hand a successful change's entry to the store with before and after values", func(t *testing.T) {
+		r := &recorder{}
+		st := &stub{Max: MaxAllowance{Personal: 60000.0}}
+		propose(`{"amount": 70000.0}`, st, r)
+
+		if len(st.Audited) != 1 || len(r.entries) != 0 {
+			t.Fatalf("expected 1 entry written with the change but got %v and %v recorded separately", len(st.Audited), len(r.entries))
+		}
+
+		got := st.Audited[0]
+		if got.Actor != "alice" || got.Action != audit.ActionPropose || got.Outcome != audit.Success || got.Target != string(Personal) {
+			t.Errorf("expected successful personal proposal by alice but got %v", got)
+		}
+
+		if string(got.Before) != `{"amount":60000}` {
+			t.Errorf("expected before value of 60000 but got %s", got.Before)
+		}
+
+		var after Proposal
+		json.Unmarshal(got.After, &after)
+		if after.Amount != 70000.0 {
+			t.Errorf("expected after value of 70000 but got %s", got.After)
+		}
+	})
+
+	t.Run("should record a failed change with the reason", func(t *testing.T) {
+		r := &recorder{}
+		propose(`{"amount": 5000.0}`, &stub{}, r)
+
+		if len(r.entries) != 1 || r.entries[0].Outcome != audit.Failure || r.entries[0].Detail != ErrInvalidPersonalGreaterAmount {
+			t.Errorf("expected failed entry with %v but got %v", ErrInvalidPersonalGreaterAmount, r.entries)
+		}
+	})
+
+	t.Run("should record the new cap after an approval", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+		c.Set(auth.IdentityContextKey, auth.Identity{Username: "bob"})
+
+		pending := Proposal{ID: 1, AllowanceType: Personal, Amount: 70000.0, Status: Pending, ProposedBy: "alice"}
+		approved := pending
+		approved.Status = Approved
+		st := &stub{Pending: pending, Reviewed: approved, Max: MaxAllowance{Personal: 60000.0}}
+		New(st, WithAuditor(&recorder{})).ApproveProposalHandler(c)
+
+		if len(st.Audited) != 1 {
+			t.Fatalf("expected 1 entry written with the approval but got %v", len(st.Audited))
+		}
+
+		got := st.Audited[0]
+		if got.Actor != "bob" || got.Action != audit.ActionApprove || string(got.Before) != `{"amount":60000}` || string(got.After) != `{"amount":70000}` {
+			t.Errorf("expected approval by bob from 60000 to 70000 but got %v with %s and %s", got, got.Before, got.After)
+		}
+	})
+
+	t.Run("should fail a change whose entry cannot be written", func(t *testing.T) {
+		st := &stub{err: audit.ErrNotRecorded}
+		rec := propose(`{"amount": 70000.0}`, st, &recorder{})
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("expected status code %v but got %v", http.StatusInternalServerError, rec.Code)
+		}
+	})
+
+	t.Run("should record a self review attempt", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+		c.Set(auth.IdentityContextKey, auth.Identity{Username: "alice"})
+
+		r := &recorder{}
+		st := &stub{Pending: Proposal{ID: 1, AllowanceType: Personal, Status: Pending, ProposedBy: "alice"}}
+		New(st, WithAuditor(r)).ApproveProposalHandler(c)
+
+		if len(r.entries) != 1 || r.entries[0].Action != audit.ActionApprove || r.entries[0].Detail != ErrSelfReview {
+			t.Errorf("expected failed approve entry with %v but got %v", ErrSelfReview, r.entries)
+		}
+	})
+}
+
+type published struct {
+	event string
+	data  interface{}
+}
+
+type publisher struct {
+	events []published
+}
+
+func (p *publisher) Publish(c echo.Context, event string, data interface{}) {
+	p.events = append(p.events, published{event: event, data: data})
+}
+
+func TestWebhookPublishing(t *testing.T) {
+	review := func(handler func(*Handler) echo.HandlerFunc, st *stub, p *publisher) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+		c.Set(auth.IdentityContextKey, auth.Identity{Username: "bob"})
+
+		handler(New(st, WithPublisher(p)))(c)
+		return rec
+	}
+
+	pending := Proposal{ID: 1, AllowanceType: Personal, Amount: 70000.0, Status: Pending, ProposedBy: "alice"}
+
+	t.Run("should publish a proposal", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/admin/deductions/personal", bytes.NewBufferString(`{"amount": 70000.0}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		c := e.NewContext(req, httptest.NewRecorder())
+		c.Set(auth.IdentityContextKey, auth.Identity{Username: "alice"})
+
+		p := &publisher{}
+		New(&stub{}, WithPublisher(p)).SetPersonalHandler(c)
+
+		if len(p.events) != 1 || p.events[0].event != webhook.EventDeductionProposed {
+			t.Fatalf("expected %v but got %v", webhook.EventDeductionProposed, p.events)
+		}
+
+		if got := p.events[0].data.(Proposal); got.Amount != 70000.0 || got.ProposedBy != "alice" {
+			t.Errorf("expected the created proposal but got %+v", got)
+		}
+	})
+
+	t.Run("should publish an approval with the reviewed proposal", func(t *testing.T) {
+		approved := pending
+		approved.Status = Approved
+		approved.ReviewedBy = "bob"
+
+		p := &publisher{}
+		review(func(h *Handler) echo.HandlerFunc { return h.ApproveProposalHandler }, &stub{Pending: pending, Reviewed: approved}, p)
+
+		if len(p.events) != 1 || p.events[0].event != webhook.EventDeductionApproved || p.events[0].data.(Proposal).Status != Approved {
+			t.Errorf("expected %v with the approved proposal but got %v", webhook.EventDeductionApproved, p.events)
+		}
+	})
+
+	t.Run("should publish a rejection", func(t *testing.T) {
+		p := &publisher{}
+		review(func(h *Handler) echo.HandlerFunc { return h.RejectProposalHandler }, &stub{Pending: pending, Reviewed: pending}, p)
+
+		if len(p.events) != 1 || p.events[0].event != webhook.EventDeductionRejected {
+			t.Errorf("expected %v but got %v", webhook.EventDeductionRejected, p.events)
+		}
+	})
+
+	t.Run("should not publish failed changes", func(t *testing.T) {
+		p := &publisher{}
+		review(func(h *Handler) echo.HandlerFunc { return h.ApproveProposalHandler }, &stub{Pending: pending, reviewErr: errors.New("db down")}, p)
+
+		if len(p.events) != 0 {
+			t.Errorf("expected no events but got %v", p.events)
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package allowance
+
+import (
+	"time"
+
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+type ProposalStatus string
+
+const (
+	Pending  ProposalStatus = "pending"
+	Approved ProposalStatus = "approved"
+	Rejected ProposalStatus = "rejected"
+)
+
+var ProposalStatuses = []ProposalStatus{Pending, Approved, Rejected}
+
+var ManagedAllowances = map[AllowanceType]func(Amount) error{
+	Personal: Amount.ValidatePersonal,
+	KReceipt: Amount.ValidateKReceipt,
+}
+
+var (
+	ErrNotFound   = problem.New("PROPOSAL_NOT_FOUND", "id", ErrProposalNotFound)
+	ErrNotPending = problem.New("PROPOSAL_NOT_PENDING", "id", ErrProposalNotPending)
+)
+
+type Proposal struct {
+	ID            int            `json:"id"`
+	AllowanceType AllowanceType  `json:"allowanceType"`
+	Amount        float64        `json:"amount"`
+	EffectiveFrom *time.Time     `json:"effectiveFrom,omitempty"`
+	Reason        string         `json:"reason"`
+	Status        ProposalStatus `json:"status"`
+	ProposedBy    string         `json:"proposedBy"`
+	ReviewedBy    string         `json:"reviewedBy,omitempty"`
+	ReviewedAt    *time.Time     `json:"reviewedAt,omitempty"`
+	CreatedAt     time.Time      `json:"createdAt"`
+}
+
+type Proposals struct {
+	Proposals []Proposal `json:"proposals"`
+}
+
+func (a Amount) Proposal(t AllowanceType, proposedBy string) Proposal {
+	p := Proposal{
+		AllowanceType: t,
+		Amount:        a.Amount,
+		Reason:        a.Reason,
+		Status:        Pending,
+		ProposedBy:    proposedBy,
+	}
+
+	if a.EffectiveFrom != "" {
+		d, _ := time.ParseInLocation(DateLayout, a.EffectiveFrom, Bangkok)
+		p.EffectiveFrom = &d
+	}
+
+	return p
+}
+
+// FilingYearStart is the earliest date a cap can take effect from: the start
+// of the tax year being filed, which calculations use by default, so its caps
+// can still be corrected.
+func FilingYearStart(now time.Time) time.Time {
+	return time.Date(now.In(Bangkok).Year()-1, time.January, 1, 0, 0, 0, 0, Bangkok)
+}
+
+// EffectiveAt is when the cap takes effect if the proposal is approved at now:
+// its effective date, but not before the start of the tax year being filed,
+// or now when it has none.
+func (p Proposal) EffectiveAt(now time.Time) time.Time {
+	if p.EffectiveFrom == nil {
+		return now
+	}
+	if start := FilingYearStart(now); p.EffectiveFrom.Before(start) {
+		return start
+	}
+	return *p.EffectiveFrom
+}
+
+// Cap is the cap a proposal sets once it is approved.
+func (p Proposal) Cap() Amount {
+	a := Amount{Amount: p.Amount}
+	if p.EffectiveFrom != nil {
+		a.EffectiveFrom = p.EffectiveFrom.In(Bangkok).Format(DateLayout)
+	}
+	return a
+}
@@ -0,0 +1,33 @@
+package allowance
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEffectiveAt(t *testing.T) {
+	now := time.Date(2025, 4, 8, 10, 0, 0, 0, Bangkok)
+	date := func(y int, m time.Month, d int) *time.Time {
+		t := time.Date(y, m, d, 0, 0, 0, 0, Bangkok)
+		return &t
+	}
+
+	testCases := []struct {
+		name string
+		from *time.Time
+		want time.Time
+	}{
+		{"should take effect now without a date", nil, now},
+		{"should take effect from a date in the tax year being filed", date(2024, time.January, 1), *date(2024, time.January, 1)},
+		{"should take effect from a future date", date(2025, time.July, 1), *date(2025, time.July, 1)},
+		{"should not take effect before the tax year being filed", date(2023, time.June, 1), *date(2024, time.January, 1)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := (Proposal{EffectiveFrom: tc.from}).EffectiveAt(now); !got.Equal(tc.want) {
+				t.Errorf("expected %v but got %v", tc.want, got)
+			}
+		})
+	}
+}
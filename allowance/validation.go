@@ -1,6 +1,10 @@
 package allowance
 
-import "errors"
+import (
+	"time"
+
+	"github.com/varissara-wo/assessment-tax/problem"
+)
 
 const (
 	ErrInvalidPersonalGreaterAmount = "amount must be greater than 10000.0"
@@ -8,48 +12,136 @@ const (
 	ErrInvalidKReceiptGreaterAmount = "amount must be greater than 0.0"
 	ErrInvalidKReceiptLessAmount    = "amount must be less than 100000.0"
 	ErrInvalidAllowance             = "allowances must be donation and k-receipt only"
+	ErrInvalidRecommendAllowance    = "allowances must be donation, k-receipt, rmf or ssf only"
 	ErrInvalidAllowanceAmount       = "allowance amount must be greater than or equal to 0"
+	ErrInvalidEffectiveFrom         = "effective from must be a date in YYYY-MM-DD format and not before the start of the tax year being filed"
+	ErrInvalidHistoryType           = "type must be personal, donation, k-receipt, rmf or ssf"
+	ErrInvalidProposalStatus        = "status must be pending, approved or rejected"
+	ErrInvalidProposalID            = "proposal id must be a positive integer"
+	ErrProposalNotFound             = "proposal not found"
+	ErrProposalNotPending           = "proposal has already been reviewed"
+	ErrSelfReview                   = "proposal must be reviewed by a different admin"
+	ErrUnmanagedAllowance           = "allowance type can't be changed by admins"
+)
+
+var (
+	errPersonalTooLow        = problem.New("ALLOWANCE_PERSONAL_TOO_LOW", "amount", ErrInvalidPersonalGreaterAmount)
+	errPersonalTooHigh       = problem.New("ALLOWANCE_PERSONAL_TOO_HIGH", "amount", ErrInvalidPersonalLessAmount)
+	errKReceiptTooLow        = problem.New("ALLOWANCE_K_RECEIPT_TOO_LOW", "amount", ErrInvalidKReceiptGreaterAmount)
+	errKReceiptTooHigh       = problem.New("ALLOWANCE_K_RECEIPT_TOO_HIGH", "amount", ErrInvalidKReceiptLessAmount)
+	errInvalidAllowance      = problem.New("ALLOWANCE_TYPE_INVALID", "allowanceType", ErrInvalidAllowance)
+	errInvalidRecommend      = problem.New("ALLOWANCE_RECOMMENDATION_TYPE_INVALID", "allowanceType", ErrInvalidRecommendAllowance)
+	errNegativeAllowance     = problem.New("ALLOWANCE_AMOUNT_NEGATIVE", "amount", ErrInvalidAllowanceAmount)
+	errInvalidEffectiveFrom  = problem.New("ALLOWANCE_EFFECTIVE_FROM_INVALID", "effectiveFrom", ErrInvalidEffectiveFrom)
+	errInvalidHistoryType    = problem.New("ALLOWANCE_HISTORY_TYPE_INVALID", "type", ErrInvalidHistoryType)
+	errInvalidProposalStatus = problem.New("PROPOSAL_STATUS_INVALID", "status", ErrInvalidProposalStatus)
+	errInvalidProposalID     = problem.New("PROPOSAL_ID_INVALID", "id", ErrInvalidProposalID)
+	errSelfReview            = problem.New("PROPOSAL_SELF_REVIEW", "", ErrSelfReview)
+	errUnmanagedAllowance    = problem.New("ALLOWANCE_NOT_MANAGED", "type", ErrUnmanagedAllowance)
 )
 
 func (a Amount) ValidatePersonal() error {
+	var es problem.Errors
+
 	if a.Amount < 10000 {
-		return errors.New(ErrInvalidPersonalGreaterAmount)
+		es.Add(errPersonalTooLow)
 	}
 	if a.Amount > 100000 {
-		return errors.New(ErrInvalidPersonalLessAmount)
+		es.Add(errPersonalTooHigh)
 	}
-	return nil
+	es.Add(a.validateEffectiveFrom())
+
+	return es.Err()
 }
 
 func (a Amount) ValidateKReceipt() error {
+	var es problem.Errors
+
 	if a.Amount <= 0 {
-		return errors.New(ErrInvalidKReceiptGreaterAmount)
+		es.Add(errKReceiptTooLow)
 	}
-
 	if a.Amount > 100000 {
-		return errors.New(ErrInvalidKReceiptLessAmount)
+		es.Add(errKReceiptTooHigh)
+	}
+	es.Add(a.validateEffectiveFrom())
+
+	return es.Err()
+}
+
+func (a Amount) validateEffectiveFrom() error {
+	if a.EffectiveFrom == "" {
+		return nil
+	}
+
+	d, err := time.ParseInLocation(DateLayout, a.EffectiveFrom, Bangkok)
+	if err != nil {
+		return errInvalidEffectiveFrom
+	}
+
+	if d.Before(FilingYearStart(time.Now())) {
+		return errInvalidEffectiveFrom
 	}
 
 	return nil
 }
 
-func ValidateAllowance(a Allowance) error {
-	if err := validateAllowanceType(a); err != nil {
-		return err
+func ValidateAllowanceType(t AllowanceType) error {
+	for _, validType := range AllowanceTypes {
+		if t == validType {
+			return nil
+		}
 	}
+	return errInvalidHistoryType
+}
+
+func ValidateAllowance(a Allowance) error {
+	return validateAllowance(a, validateAllowanceType(a))
+}
+
+func ValidateRecommendationAllowance(a Allowance) error {
+	return validateAllowance(a, validateRecommendationAllowanceType(a))
+}
+
+func validateAllowance(a Allowance, typeErr error) error {
+	var es problem.Errors
+
+	es.Add(typeErr)
 
 	if a.Amount < 0 {
-		return errors.New(ErrInvalidAllowanceAmount)
+		es.Add(errNegativeAllowance)
 	}
 
-	return nil
+	return es.Err()
 }
 
 func validateAllowanceType(a Allowance) error {
-	for _, validType := range ValidAllowanceTypes {
-		if a.AllowanceType == validType {
+	if !isOneOf(a.AllowanceType, ValidAllowanceTypes) {
+		return errInvalidAllowance
+	}
+	return nil
+}
+
+func validateRecommendationAllowanceType(a Allowance) error {
+	if !isOneOf(a.AllowanceType, RecommendationAllowanceTypes) {
+		return errInvalidRecommend
+	}
+	return nil
+}
+
+func isOneOf(t AllowanceType, types []AllowanceType) bool {
+	for _, validType := range types {
+		if t == validType {
+			return true
+		}
+	}
+	return false
+}
+
+func ValidateProposalStatus(s ProposalStatus) error {
+	for _, validStatus := range ProposalStatuses {
+		if s == validStatus {
 			return nil
 		}
 	}
-	return errors.New(ErrInvalidAllowance)
+	return errInvalidProposalStatus
 }
@@ -3,6 +3,9 @@ package allowance
 import (
 	"errors"
 	"testing"
+	"time"
+
+	"github.com/varissara-wo/assessment-tax/problem"
 )
 
 func TestValidatePersoanlAmount(t *testing.T) {
@@ -121,3 +124,77 @@ func TestValidateAllowanceAmount(t *testing.T) {
 		}
 	})
 }
+
+func TestValidateEffectiveFrom(t *testing.T) {
+	t.Run("should return nil if effective from is empty", func(t *testing.T) {
+		a := Amount{Amount: 20000.0}
+
+		if got := a.ValidatePersonal(); got != nil {
+			t.Errorf("expected nil but got %v", got)
+		}
+	})
+
+	t.Run("should return nil if effective from is in the future", func(t *testing.T) {
+		a := Amount{Amount: 20000.0, EffectiveFrom: "2999-01-01"}
+
+		if got := a.ValidateKReceipt(); got != nil {
+			t.Errorf("expected nil but got %v", got)
+		}
+	})
+
+	t.Run("should accept the start of the tax year being filed", func(t *testing.T) {
+		a := Amount{Amount: 20000.0, EffectiveFrom: FilingYearStart(time.Now()).Format(DateLayout)}
+
+		if got := a.ValidatePersonal(); got != nil {
+			t.Errorf("expected nil but got %v", got)
+		}
+	})
+
+	t.Run("should return error if effective from is before the tax year being filed", func(t *testing.T) {
+		a := Amount{Amount: 20000.0, EffectiveFrom: FilingYearStart(time.Now()).AddDate(0, 0, -1).Format(DateLayout)}
+
+		if got := a.ValidatePersonal(); got == nil || got.Error() != ErrInvalidEffectiveFrom {
+			t.Errorf("expected %v but got %v", ErrInvalidEffectiveFrom, got)
+		}
+	})
+
+	t.Run("should return error if effective from is not a date", func(t *testing.T) {
+		a := Amount{Amount: 20000.0, EffectiveFrom: "next year"}
+
+		want := errors.New(ErrInvalidEffectiveFrom)
+		got := a.ValidatePersonal()
+
+		if got == nil || got.Error() != want.Error() {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+}
+
+func TestValidateAllowanceTypeHistory(t *testing.T) {
+	t.Run("should accept personal as an allowance type", func(t *testing.T) {
+		if got := ValidateAllowanceType(Personal); got != nil {
+			t.Errorf("expected nil but got %v", got)
+		}
+	})
+
+	t.Run("should return error if allowance type is unknown", func(t *testing.T) {
+		got := ValidateAllowanceType("unknown")
+
+		if got == nil || got.Error() != ErrInvalidHistoryType {
+			t.Errorf("expected %v but got %v", ErrInvalidHistoryType, got)
+		}
+	})
+}
+
+func TestValidatePersonalCollectsErrors(t *testing.T) {
+	err := Amount{Amount: 5000.0, EffectiveFrom: "2020-01-01"}.ValidatePersonal()
+
+	var got problem.Errors
+	if !errors.As(err, &got) || len(got) != 2 {
+		t.Fatalf("expected 2 errors but got %v", err)
+	}
+
+	if got[0].Code != "ALLOWANCE_PERSONAL_TOO_LOW" || got[1].Code != "ALLOWANCE_EFFECTIVE_FROM_INVALID" || got[1].Field != "effectiveFrom" {
+		t.Errorf("expected amount and effective from errors but got %v, %v", got[0], got[1])
+	}
+}
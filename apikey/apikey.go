@@ -0,0 +1,162 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+const KeyContextKey = "apiKey"
+
+const HeaderAPIKey = "X-API-Key"
+
+const keyPrefix = "ktx_"
+
+const (
+	ErrInvalidName   = "name must be between 1 and 100 characters"
+	ErrInvalidScope  = "scopes must be calc or batch only"
+	ErrEmptyScopes   = "at least one scope is required"
+	ErrInvalidKeyID  = "api key id must be a positive integer"
+	ErrMissingKey    = "missing api key"
+	ErrInvalidKey    = "invalid or revoked api key"
+	ErrScopeRequired = "api key does not have scope %q"
+)
+
+var (
+	ErrNotFound = problem.New("API_KEY_NOT_FOUND", "id", "api key not found")
+
+	errInvalidName  = problem.New("API_KEY_NAME_INVALID", "name", ErrInvalidName)
+	errInvalidScope = problem.New("API_KEY_SCOPE_INVALID", "scopes", ErrInvalidScope)
+	errEmptyScopes  = problem.New("API_KEY_SCOPES_EMPTY", "scopes", ErrEmptyScopes)
+	errInvalidKeyID = problem.New("API_KEY_ID_INVALID", "id", ErrInvalidKeyID)
+	errMissingKey   = problem.New("API_KEY_MISSING", "", ErrMissingKey)
+	errInvalidKey   = problem.New("API_KEY_INVALID", "", ErrInvalidKey)
+)
+
+type Scope string
+
+const (
+	Calc  Scope = "calc"
+	Batch Scope = "batch"
+)
+
+var Scopes = []Scope{Calc, Batch}
+
+type Key struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []Scope    `json:"scopes"`
+	Hash       string     `json:"-"`
+	CreatedBy  string     `json:"createdBy"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
+
+type Keys struct {
+	Keys []Key `json:"keys"`
+}
+
+type NewKey struct {
+	Name   string  `json:"name"`
+	Scopes []Scope `json:"scopes"`
+}
+
+type IssuedKey struct {
+	Key
+	Secret string `json:"key"`
+}
+
+type Config struct {
+	Anonymous []Scope
+}
+
+func (k Key) Has(s Scope) bool {
+	for _, ks := range k.Scopes {
+		if ks == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Scope) Validate() error {
+	for _, v := range Scopes {
+		if s == v {
+			return nil
+		}
+	}
+	return errInvalidScope
+}
+
+func (nk NewKey) Validate() error {
+	var es problem.Errors
+
+	if len(strings.TrimSpace(nk.Name)) == 0 || len(nk.Name) > 100 {
+		es.Add(errInvalidName)
+	}
+
+	if len(nk.Scopes) == 0 {
+		es.Add(errEmptyScopes)
+	}
+
+	for i, s := range nk.Scopes {
+		if err := s.Validate(); err != nil {
+			es.Add(problem.Nest(err, fmt.Sprintf("[%d]", i), nil))
+		}
+	}
+
+	return es.Err()
+}
+
+func ParseScopes(s string) ([]Scope, error) {
+	scopes := []Scope{}
+	for _, v := range strings.Split(s, ",") {
+		sc := Scope(strings.TrimSpace(v))
+		if sc == "" {
+			continue
+		}
+		if err := sc.Validate(); err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, sc)
+	}
+	return scopes, nil
+}
+
+func Generate() (secret, prefix, hash string, err error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+
+	secret = keyPrefix + hex.EncodeToString(b)
+	return secret, secret[:len(keyPrefix)+8], Hash(secret), nil
+}
+
+func Hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func KeyFrom(c echo.Context) (Key, bool) {
+	k, ok := c.Get(KeyContextKey).(Key)
+	return k, ok
+}
+
+// Owner identifies the API key of the request, or returns "" for anonymous
+// callers.
+func Owner(c echo.Context) string {
+	if k, ok := KeyFrom(c); ok {
+		return "key:" + strconv.Itoa(k.ID)
+	}
+	return ""
+}
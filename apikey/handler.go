@@ -0,0 +1,90 @@
+package apikey
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/auth"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+type Storer interface {
+	CreateKey(Key) (Key, error)
+	Keys() ([]Key, error)
+	RevokeKey(id int) (Key, error)
+	KeyByHash(hash string) (Key, error)
+	TouchKey(id int, at time.Time) error
+}
+
+type Handler struct {
+	store Storer
+	cfg   Config
+}
+
+func New(store Storer, cfg Config) *Handler {
+	return &Handler{store: store, cfg: cfg}
+}
+
+func (h *Handler) CreateKeyHandler(c echo.Context) error {
+	nk := NewKey{}
+
+	if err := c.Bind(&nk); err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	if err := nk.Validate(); err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	secret, prefix, hash, err := Generate()
+
+	if err != nil {
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+
+	k, err := h.store.CreateKey(Key{
+		Name:      nk.Name,
+		Prefix:    prefix,
+		Scopes:    nk.Scopes,
+		Hash:      hash,
+		CreatedBy: auth.Username(c),
+	})
+
+	if err != nil {
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusCreated, IssuedKey{Key: k, Secret: secret})
+}
+
+func (h *Handler) KeysHandler(c echo.Context) error {
+	ks, err := h.store.Keys()
+
+	if err != nil {
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, Keys{Keys: ks})
+}
+
+func (h *Handler) RevokeKeyHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return problem.Respond(c, http.StatusBadRequest, errInvalidKeyID)
+	}
+
+	k, err := h.store.RevokeKey(id)
+
+	if errors.Is(err, ErrNotFound) {
+		return problem.Respond(c, http.StatusNotFound, err)
+	}
+
+	if err != nil {
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, k)
+}
@@ -0,0 +1,224 @@
+package apikey
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/auth"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+type stub struct {
+	keys    []Key
+	touched map[int]time.Time
+	err     error
+}
+
+func newStub(keys ...Key) *stub {
+	return &stub{keys: keys, touched: map[int]time.Time{}}
+}
+
+func (s *stub) CreateKey(k Key) (Key, error) {
+	if s.err != nil {
+		return Key{}, s.err
+	}
+	k.ID = len(s.keys) + 1
+	k.CreatedAt = time.Now()
+	s.keys = append(s.keys, k)
+	return k, nil
+}
+
+func (s *stub) Keys() ([]Key, error) {
+	return s.keys, s.err
+}
+
+func (s *stub) RevokeKey(id int) (Key, error) {
+	if s.err != nil {
+		return Key{}, s.err
+	}
+	for i, k := range s.keys {
+		if k.ID == id {
+			now := time.Now()
+			s.keys[i].RevokedAt = &now
+			return s.keys[i], nil
+		}
+	}
+	return Key{}, ErrNotFound
+}
+
+func (s *stub) KeyByHash(hash string) (Key, error) {
+	if s.err != nil {
+		return Key{}, s.err
+	}
+	for _, k := range s.keys {
+		if k.Hash == hash {
+			return k, nil
+		}
+	}
+	return Key{}, ErrNotFound
+}
+
+func (s *stub) TouchKey(id int, at time.Time) error {
+	s.touched[id] = at
+	return s.err
+}
+
+func TestCreateKeyHandler(t *testing.T) {
+	testCases := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"should return 400 if name is empty", `{"name": " ", "scopes": ["calc"]}`, ErrInvalidName},
+		{"should return 400 if scopes are empty", `{"name": "payroll", "scopes": []}`, ErrEmptyScopes},
+		{"should return 400 if scope is unknown", `{"name": "payroll", "scopes": ["admin"]}`, ErrInvalidScope},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/admin/api-keys", bytes.NewBufferString(tc.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			h := New(newStub(), Config{})
+			err := h.CreateKeyHandler(c)
+
+			if err != nil {
+				t.Errorf("expected nil but got %v", err)
+			}
+
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected status code %v but got %v", http.StatusBadRequest, rec.Code)
+			}
+
+			var gotErr problem.Problem
+			json.Unmarshal(rec.Body.Bytes(), &gotErr)
+
+			if gotErr.Detail != tc.wantErr {
+				t.Errorf("expected error message %v but got %v", tc.wantErr, gotErr.Detail)
+			}
+		})
+	}
+
+	t.Run("should return 201 with the key once and store only its hash", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/admin/api-keys", bytes.NewBufferString(`{"name": "payroll", "scopes": ["calc", "batch"]}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set(auth.IdentityContextKey, auth.Identity{Username: "alice"})
+
+		st := newStub()
+		h := New(st, Config{})
+		err := h.CreateKeyHandler(c)
+
+		if err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		if rec.Code != http.StatusCreated {
+			t.Errorf("expected status code %v but got %v", http.StatusCreated, rec.Code)
+		}
+
+		var got IssuedKey
+		json.Unmarshal(rec.Body.Bytes(), &got)
+
+		if !strings.HasPrefix(got.Secret, got.Prefix) || got.CreatedBy != "alice" {
+			t.Errorf("expected key starting with %v created by alice but got %v", got.Prefix, got)
+		}
+
+		stored := st.keys[0]
+		if stored.Hash != Hash(got.Secret) || strings.Contains(rec.Body.String(), stored.Hash) {
+			t.Errorf("expected only the key hash to be stored and not returned")
+		}
+	})
+
+	t.Run("should return 500 if key can't be stored", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/admin/api-keys", bytes.NewBufferString(`{"name": "payroll", "scopes": ["calc"]}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		st := newStub()
+		st.err = errors.New("failed to create key")
+		h := New(st, Config{})
+		h.CreateKeyHandler(c)
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("expected status code %v but got %v", http.StatusInternalServerError, rec.Code)
+		}
+	})
+}
+
+func TestKeysHandler(t *testing.T) {
+	t.Run("should return all keys without hashes", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/admin/api-keys", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := New(newStub(Key{ID: 1, Name: "payroll", Scopes: []Scope{Calc}, Hash: "secret-hash"}), Config{})
+		err := h.KeysHandler(c)
+
+		if err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		var got Keys
+		json.Unmarshal(rec.Body.Bytes(), &got)
+
+		if len(got.Keys) != 1 || got.Keys[0].Name != "payroll" || strings.Contains(rec.Body.String(), "secret-hash") {
+			t.Errorf("expected payroll key without hash but got %v", rec.Body.String())
+		}
+	})
+}
+
+func TestRevokeKeyHandler(t *testing.T) {
+	testCases := []struct {
+		name       string
+		id         string
+		wantStatus int
+	}{
+		{"should return 400 if id is invalid", "abc", http.StatusBadRequest},
+		{"should return 404 if key does not exist", "2", http.StatusNotFound},
+		{"should return 200 if key is revoked", "1", http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodDelete, "/", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/admin/api-keys/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(tc.id)
+
+			st := newStub(Key{ID: 1, Name: "payroll"})
+			h := New(st, Config{})
+			err := h.RevokeKeyHandler(c)
+
+			if err != nil {
+				t.Errorf("expected nil but got %v", err)
+			}
+
+			if rec.Code != tc.wantStatus {
+				t.Errorf("expected status code %v but got %v", tc.wantStatus, rec.Code)
+			}
+
+			if tc.wantStatus == http.StatusOK && st.keys[0].RevokedAt == nil {
+				t.Errorf("expected key to be revoked")
+			}
+		})
+	}
+}
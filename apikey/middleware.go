@@ -0,0 +1,76 @@
+package apikey
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+const touchInterval = time.Minute
+
+func (h *Handler) Require(s Scope) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			k, status, err := h.Authorize(c.Request().Header.Get(HeaderAPIKey), s)
+			if err != nil {
+				return problem.Respond(c, status, err)
+			}
+
+			if k.ID == 0 {
+				return next(c)
+			}
+
+			if err := h.Touch(k); err != nil {
+				c.Logger().Error(err)
+			}
+
+			c.Set(KeyContextKey, k)
+			return next(c)
+		}
+	}
+}
+
+func (h *Handler) Authorize(secret string, s Scope) (Key, int, error) {
+	if secret == "" {
+		if h.anonymous(s) {
+			return Key{}, http.StatusOK, nil
+		}
+		return Key{}, http.StatusUnauthorized, errMissingKey
+	}
+
+	k, err := h.store.KeyByHash(Hash(secret))
+
+	if errors.Is(err, ErrNotFound) || (err == nil && k.RevokedAt != nil) {
+		return Key{}, http.StatusUnauthorized, errInvalidKey
+	}
+
+	if err != nil {
+		return Key{}, http.StatusInternalServerError, err
+	}
+
+	if !k.Has(s) {
+		return Key{}, http.StatusForbidden, problem.Newf("API_KEY_SCOPE_REQUIRED", "", ErrScopeRequired, s)
+	}
+
+	return k, http.StatusOK, nil
+}
+
+func (h *Handler) Touch(k Key) error {
+	now := time.Now()
+	if k.LastUsedAt != nil && now.Sub(*k.LastUsedAt) < touchInterval {
+		return nil
+	}
+	return h.store.TouchKey(k.ID, now)
+}
+
+func (h *Handler) anonymous(s Scope) bool {
+	for _, a := range h.cfg.Anonymous {
+		if a == s {
+			return true
+		}
+	}
+	return false
+}
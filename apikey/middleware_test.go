@@ -0,0 +1,141 @@
+package apikey
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+func require(h *Handler, s Scope, secret string) (*httptest.ResponseRecorder, Key) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/tax/calculations", nil)
+	if secret != "" {
+		req.Header.Set(HeaderAPIKey, secret)
+	}
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	var got Key
+	h.Require(s)(func(c echo.Context) error {
+		got, _ = KeyFrom(c)
+		return c.NoContent(http.StatusOK)
+	})(c)
+
+	return rec, got
+}
+
+func TestRequire(t *testing.T) {
+	revokedAt := time.Now()
+	recentlyUsed := time.Now()
+	keys := []Key{
+		{ID: 1, Name: "payroll", Scopes: []Scope{Calc, Batch}, Hash: Hash("ktx_payroll")},
+		{ID: 2, Name: "calculator", Scopes: []Scope{Calc}, Hash: Hash("ktx_calculator"), LastUsedAt: &recentlyUsed},
+		{ID: 3, Name: "old", Scopes: []Scope{Calc}, Hash: Hash("ktx_old"), RevokedAt: &revokedAt},
+	}
+
+	testCases := []struct {
+		name       string
+		cfg        Config
+		scope      Scope
+		secret     string
+		wantStatus int
+		wantKey    int
+	}{
+		{"should allow anonymous request if scope is anonymous", Config{Anonymous: []Scope{Calc}}, Calc, "", http.StatusOK, 0},
+		{"should return 401 if key is missing and scope is not anonymous", Config{Anonymous: []Scope{Calc}}, Batch, "", http.StatusUnauthorized, 0},
+		{"should return 401 if key is unknown", Config{Anonymous: []Scope{Calc}}, Calc, "ktx_unknown", http.StatusUnauthorized, 0},
+		{"should return 401 if key is revoked", Config{}, Calc, "ktx_old", http.StatusUnauthorized, 0},
+		{"should return 403 if key lacks scope", Config{}, Batch, "ktx_calculator", http.StatusForbidden, 0},
+		{"should allow key with scope", Config{}, Batch, "ktx_payroll", http.StatusOK, 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := New(newStub(keys...), tc.cfg)
+
+			rec, got := require(h, tc.scope, tc.secret)
+
+			if rec.Code != tc.wantStatus {
+				t.Errorf("expected status code %v but got %v", tc.wantStatus, rec.Code)
+			}
+
+			if got.ID != tc.wantKey {
+				t.Errorf("expected key %v but got %v", tc.wantKey, got.ID)
+			}
+		})
+	}
+
+	t.Run("should explain which scope is missing", func(t *testing.T) {
+		rec, _ := require(New(newStub(keys...), Config{}), Batch, "ktx_calculator")
+
+		var gotErr problem.Problem
+		json.Unmarshal(rec.Body.Bytes(), &gotErr)
+
+		want := fmt.Sprintf(ErrScopeRequired, Batch)
+		if gotErr.Detail != want {
+			t.Errorf("expected error message %v but got %v", want, gotErr.Detail)
+		}
+	})
+
+	t.Run("should record last used time at most once a minute", func(t *testing.T) {
+		st := newStub(keys...)
+		h := New(st, Config{})
+
+		require(h, Calc, "ktx_payroll")
+		require(h, Calc, "ktx_calculator")
+
+		if _, ok := st.touched[1]; !ok {
+			t.Errorf("expected payroll key to be touched")
+		}
+
+		if _, ok := st.touched[2]; ok {
+			t.Errorf("expected recently used key not to be touched again")
+		}
+	})
+}
+
+func TestOwner(t *testing.T) {
+	keys := []Key{{ID: 7, Name: "payroll", Scopes: []Scope{Batch}, Hash: Hash("ktx_payroll")}}
+	h := New(newStub(keys...), Config{Anonymous: []Scope{Batch}})
+
+	for secret, want := range map[string]string{"ktx_payroll": "key:7", "": ""} {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/batch", nil)
+		if secret != "" {
+			req.Header.Set(HeaderAPIKey, secret)
+		}
+		c := e.NewContext(req, httptest.NewRecorder())
+
+		var got string
+		h.Require(Batch)(func(c echo.Context) error {
+			got = Owner(c)
+			return nil
+		})(c)
+
+		if got != want {
+			t.Errorf("expected owner %q but got %q", want, got)
+		}
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	got, err := ParseScopes("calc, batch")
+	if err != nil || len(got) != 2 || got[0] != Calc || got[1] != Batch {
+		t.Errorf("expected [calc batch] but got %v, %v", got, err)
+	}
+
+	got, err = ParseScopes("")
+	if err != nil || len(got) != 0 {
+		t.Errorf("expected no scopes but got %v, %v", got, err)
+	}
+
+	if _, err := ParseScopes("calc,admin"); err == nil || err.Error() != ErrInvalidScope {
+		t.Errorf("expected %v but got %v", ErrInvalidScope, err)
+	}
+}
@@ -0,0 +1,103 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+const (
+	ActionLogin   = "admin.login"
+	ActionLogout  = "admin.logout"
+	ActionPropose = "deduction.propose"
+	ActionApprove = "deduction.approve"
+	ActionReject  = "deduction.reject"
+)
+
+const (
+	Success = "success"
+	Failure = "failure"
+)
+
+type Entry struct {
+	ID        int64           `json:"id"`
+	At        time.Time       `json:"at"`
+	Actor     string          `json:"actor"`
+	Action    string          `json:"action"`
+	Outcome   string          `json:"outcome"`
+	IP        string          `json:"ip"`
+	RequestID string          `json:"requestId"`
+	Target    string          `json:"target,omitempty"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	Detail    string          `json:"detail,omitempty"`
+	PrevHash  string          `json:"prevHash"`
+	Hash      string          `json:"hash"`
+}
+
+type Entries struct {
+	Entries []Entry `json:"entries"`
+}
+
+type Verification struct {
+	Valid    bool  `json:"valid"`
+	Checked  int   `json:"checked"`
+	BrokenAt int64 `json:"brokenAt,omitempty"`
+}
+
+type Filter struct {
+	Actor   string
+	Action  string
+	From    *time.Time
+	To      *time.Time
+	AfterID int64
+	Limit   int
+}
+
+func Value(v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+func (e Entry) Seal(prevHash string) Entry {
+	e.At = e.At.UTC().Truncate(time.Microsecond)
+	e.PrevHash = prevHash
+	e.Hash = e.digest()
+	return e
+}
+
+func (e Entry) digest() string {
+	b, _ := json.Marshal([]string{
+		e.PrevHash,
+		e.At.UTC().Format(time.RFC3339Nano),
+		e.Actor,
+		e.Action,
+		e.Outcome,
+		e.IP,
+		e.RequestID,
+		e.Target,
+		string(e.Before),
+		string(e.After),
+		e.Detail,
+	})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func Verify(es []Entry) Verification {
+	prev := ""
+	for i, e := range es {
+		if e.PrevHash != prev || e.digest() != e.Hash {
+			return Verification{Valid: false, Checked: i, BrokenAt: e.ID}
+		}
+		prev = e.Hash
+	}
+	return Verification{Valid: true, Checked: len(es)}
+}
@@ -0,0 +1,70 @@
+package audit
+
+import (
+	"testing"
+	"time"
+)
+
+func chain(n int) []Entry {
+	es := []Entry{}
+	prev := ""
+	for i := 0; i < n; i++ {
+		e := Entry{
+			ID:      int64(i + 1),
+			At:      time.Date(2025, 4, 8, 10, i, 0, 0, time.UTC),
+			Actor:   "alice",
+			Action:  ActionPropose,
+			Outcome: Success,
+			Target:  "personal",
+			Before:  Value(map[string]float64{"amount": 60000.0}),
+			After:   Value(map[string]float64{"amount": 70000.0}),
+		}.Seal(prev)
+		prev = e.Hash
+		es = append(es, e)
+	}
+	return es
+}
+
+func TestVerify(t *testing.T) {
+	t.Run("should accept an untouched chain", func(t *testing.T) {
+		got := Verify(chain(3))
+
+		if !got.Valid || got.Checked != 3 {
+			t.Errorf("expected valid chain of 3 but got %v", got)
+		}
+	})
+
+	t.Run("should detect an edited entry", func(t *testing.T) {
+		es := chain(3)
+		es[1].After = Value(map[string]float64{"amount": 100000.0})
+
+		got := Verify(es)
+
+		if got.Valid || got.BrokenAt != 2 {
+			t.Errorf("expected chain broken at 2 but got %v", got)
+		}
+	})
+
+	t.Run("should detect a removed entry", func(t *testing.T) {
+		es := chain(3)
+		es = append(es[:1], es[2:]...)
+
+		got := Verify(es)
+
+		if got.Valid || got.BrokenAt != 3 {
+			t.Errorf("expected chain broken at 3 but got %v", got)
+		}
+	})
+
+	t.Run("should detect a rehashed entry", func(t *testing.T) {
+		es := chain(3)
+		es[1].Actor = "mallory"
+		es[1] = es[1].Seal(es[1].PrevHash)
+
+		got := Verify(es)
+
+		if got.Valid || got.BrokenAt != 3 {
+			t.Errorf("expected chain broken at 3 but got %v", got)
+		}
+	})
+}
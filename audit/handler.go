@@ -0,0 +1,216 @@
+package audit
+
+import (
+	"encoding/csv"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+const (
+	ErrInvalidFrom  = "from must be a date (YYYY-MM-DD) or an RFC 3339 timestamp"
+	ErrInvalidTo    = "to must be a date (YYYY-MM-DD) or an RFC 3339 timestamp"
+	ErrInvalidLimit = "limit must be between 1 and 1000"
+	ErrInvalidAfter = "after must be a positive entry id"
+	ErrWriteFailed  = "the change could not be recorded in the audit log"
+)
+
+const (
+	defaultLimit = 100
+	maxActor     = 512
+	maxRequestID = 64
+	maxTarget    = 50
+)
+
+var (
+	errInvalidFrom  = problem.New("AUDIT_FROM_INVALID", "from", ErrInvalidFrom)
+	errInvalidTo    = problem.New("AUDIT_TO_INVALID", "to", ErrInvalidTo)
+	errInvalidLimit = problem.New("AUDIT_LIMIT_INVALID", "limit", ErrInvalidLimit)
+	errInvalidAfter = problem.New("AUDIT_AFTER_INVALID", "after", ErrInvalidAfter)
+
+	ErrNotRecorded = problem.New("AUDIT_WRITE_FAILED", "", ErrWriteFailed)
+)
+
+type Storer interface {
+	Append(Entry) (Entry, error)
+	Entries(Filter) ([]Entry, error)
+}
+
+type Recorder interface {
+	Record(c echo.Context, e Entry) error
+}
+
+type Handler struct {
+	store Storer
+}
+
+func New(store Storer) *Handler {
+	return &Handler{store: store}
+}
+
+func (h *Handler) Record(c echo.Context, e Entry) error {
+	e = Stamp(c, e)
+
+	if _, err := h.store.Append(e); err != nil {
+		c.Logger().Errorf("failed to write audit entry %s for %s: %v", e.Action, e.Actor, err)
+		return ErrNotRecorded
+	}
+	return nil
+}
+
+// Stamp sets the time, client IP and request ID of an entry. The request ID,
+// a failed login's actor and an unknown target come from the client, so they
+// are cut to a bounded length.
+func Stamp(c echo.Context, e Entry) Entry {
+	e.At = time.Now()
+	e.Actor = truncate(e.Actor, maxActor)
+	e.Target = truncate(e.Target, maxTarget)
+
+	e.IP = c.RealIP()
+	if net.ParseIP(e.IP) == nil {
+		e.IP = ""
+	}
+
+	e.RequestID = c.Response().Header().Get(echo.HeaderXRequestID)
+	if e.RequestID == "" {
+		e.RequestID = c.Request().Header.Get(echo.HeaderXRequestID)
+	}
+	e.RequestID = truncate(e.RequestID, maxRequestID)
+
+	return e
+}
+
+func truncate(s string, n int) string {
+	s = strings.ToValidUTF8(s, "")
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func (h *Handler) EntriesHandler(c echo.Context) error {
+	f, err := filter(c, defaultLimit)
+
+	if err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	es, err := h.store.Entries(f)
+
+	if err != nil {
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, Entries{Entries: es})
+}
+
+func (h *Handler) ExportHandler(c echo.Context) error {
+	f, err := filter(c, 0)
+
+	if err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	es, err := h.store.Entries(f)
+
+	if err != nil {
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="audit.csv"`)
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv; charset=UTF-8")
+	c.Response().WriteHeader(http.StatusOK)
+
+	return WriteCSV(c.Response(), es)
+}
+
+func (h *Handler) VerifyHandler(c echo.Context) error {
+	es, err := h.store.Entries(Filter{})
+
+	if err != nil {
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, Verify(es))
+}
+
+func WriteCSV(w http.ResponseWriter, es []Entry) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "at", "actor", "action", "outcome", "ip", "requestId", "target", "before", "after", "detail", "prevHash", "hash"})
+
+	for _, e := range es {
+		cw.Write([]string{
+			strconv.FormatInt(e.ID, 10),
+			e.At.UTC().Format(time.RFC3339Nano),
+			e.Actor,
+			e.Action,
+			e.Outcome,
+			e.IP,
+			e.RequestID,
+			e.Target,
+			string(e.Before),
+			string(e.After),
+			e.Detail,
+			e.PrevHash,
+			e.Hash,
+		})
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func filter(c echo.Context, limit int) (Filter, error) {
+	var es problem.Errors
+	f := Filter{Actor: c.QueryParam("actor"), Action: c.QueryParam("action"), Limit: limit}
+
+	if v := c.QueryParam("from"); v != "" {
+		if t, err := parseTime(v); err != nil {
+			es.Add(errInvalidFrom)
+		} else {
+			f.From = &t
+		}
+	}
+
+	if v := c.QueryParam("to"); v != "" {
+		if t, err := parseTime(v); err != nil {
+			es.Add(errInvalidTo)
+		} else {
+			f.To = &t
+		}
+	}
+
+	if v := c.QueryParam("after"); v != "" {
+		if id, err := strconv.ParseInt(v, 10, 64); err != nil || id <= 0 {
+			es.Add(errInvalidAfter)
+		} else {
+			f.AfterID = id
+		}
+	}
+
+	if v := c.QueryParam("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err != nil || n < 1 || n > 1000 {
+			es.Add(errInvalidLimit)
+		} else {
+			f.Limit = n
+		}
+	}
+
+	return f, es.Err()
+}
+
+func parseTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", v, time.FixedZone("Asia/Bangkok", 7*60*60))
+}
@@ -0,0 +1,220 @@
+package audit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+type stub struct {
+	appended []Entry
+	entries  []Entry
+	filter   Filter
+	err      error
+}
+
+func (s *stub) Append(e Entry) (Entry, error) {
+	prev := ""
+	if len(s.appended) > 0 {
+		prev = s.appended[len(s.appended)-1].Hash
+	}
+	e = e.Seal(prev)
+	s.appended = append(s.appended, e)
+	return e, s.err
+}
+
+func (s *stub) Entries(f Filter) ([]Entry, error) {
+	s.filter = f
+	return s.entries, s.err
+}
+
+func TestRecord(t *testing.T) {
+	t.Run("should record the source ip and request id", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/admin/login", nil)
+		req.Header.Set(echo.HeaderXRealIP, "10.0.0.1")
+		rec := httptest.NewRecorder()
+		rec.Header().Set(echo.HeaderXRequestID, "req-1")
+		c := e.NewContext(req, rec)
+
+		st := &stub{}
+		New(st).Record(c, Entry{Actor: "alice", Action: ActionLogin, Outcome: Success})
+
+		if len(st.appended) != 1 {
+			t.Fatalf("expected 1 entry but got %v", len(st.appended))
+		}
+
+		got := st.appended[0]
+		if got.IP != "10.0.0.1" || got.RequestID != "req-1" || got.At.IsZero() || got.Hash == "" {
+			t.Errorf("expected sealed entry from 10.0.0.1 with request id req-1 but got %v", got)
+		}
+	})
+
+	t.Run("should return the error if the entry cannot be written", func(t *testing.T) {
+		e := echo.New()
+		c := e.NewContext(httptest.NewRequest(http.MethodPost, "/admin/login", nil), httptest.NewRecorder())
+
+		err := New(&stub{err: errors.New("db down")}).Record(c, Entry{Actor: "alice", Action: ActionLogin, Outcome: Success})
+
+		if err == nil {
+			t.Errorf("expected an error but got nil")
+		}
+	})
+}
+
+func TestStamp(t *testing.T) {
+	t.Run("should bound client controlled values", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/admin/login", nil)
+		req.Header.Set(echo.HeaderXRealIP, "not an ip")
+		req.Header.Set(echo.HeaderXRequestID, strings.Repeat("r", 100))
+		c := e.NewContext(req, httptest.NewRecorder())
+
+		got := Stamp(c, Entry{Actor: strings.Repeat("ก", 300), Target: strings.Repeat("t", 100)})
+
+		if got.IP != "" || len(got.RequestID) != maxRequestID || len(got.Target) != maxTarget || len(got.Actor) > maxActor || !utf8.ValidString(got.Actor) {
+			t.Errorf("expected bounded values but got ip %q, request id of %v bytes and actor of %v bytes", got.IP, len(got.RequestID), len(got.Actor))
+		}
+	})
+}
+
+func TestEntriesHandler(t *testing.T) {
+	testCases := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantErr    string
+	}{
+		{"should return 400 if from is invalid", "?from=yesterday", http.StatusBadRequest, ErrInvalidFrom},
+		{"should return 400 if to is invalid", "?to=08/04/2025", http.StatusBadRequest, ErrInvalidTo},
+		{"should return 400 if limit is too large", "?limit=5000", http.StatusBadRequest, ErrInvalidLimit},
+		{"should return 400 if after is invalid", "?after=-1", http.StatusBadRequest, ErrInvalidAfter},
+		{"should return 200 with entries", "?actor=alice&action=admin.login&from=2025-04-01&to=2025-04-08T00:00:00Z&after=10&limit=5", http.StatusOK, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/admin/audit"+tc.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			st := &stub{entries: chain(1)}
+			err := New(st).EntriesHandler(c)
+
+			if err != nil {
+				t.Errorf("expected nil but got %v", err)
+			}
+
+			if rec.Code != tc.wantStatus {
+				t.Errorf("expected status code %v but got %v", tc.wantStatus, rec.Code)
+			}
+
+			if tc.wantStatus != http.StatusOK {
+				var gotErr problem.Problem
+				json.Unmarshal(rec.Body.Bytes(), &gotErr)
+
+				if gotErr.Detail != tc.wantErr {
+					t.Errorf("expected error message %v but got %v", tc.wantErr, gotErr.Detail)
+				}
+				return
+			}
+
+			wantFrom := time.Date(2025, 4, 1, 0, 0, 0, 0, time.FixedZone("Asia/Bangkok", 7*60*60))
+			f := st.filter
+			if f.Actor != "alice" || f.Action != ActionLogin || !f.From.Equal(wantFrom) || f.AfterID != 10 || f.Limit != 5 {
+				t.Errorf("expected filter to match query but got %+v", f)
+			}
+
+			var got Entries
+			json.Unmarshal(rec.Body.Bytes(), &got)
+			if len(got.Entries) != 1 || got.Entries[0].Hash == "" {
+				t.Errorf("expected 1 entry with hash but got %v", got)
+			}
+		})
+	}
+
+	t.Run("should default to 100 entries", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/admin/audit", nil)
+		c := e.NewContext(req, httptest.NewRecorder())
+
+		st := &stub{}
+		New(st).EntriesHandler(c)
+
+		if st.filter.Limit != 100 {
+			t.Errorf("expected limit 100 but got %v", st.filter.Limit)
+		}
+	})
+}
+
+func TestExportHandler(t *testing.T) {
+	t.Run("should export all entries as csv", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/admin/audit/export", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		st := &stub{entries: chain(2)}
+		err := New(st).ExportHandler(c)
+
+		if err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		if st.filter.Limit != 0 {
+			t.Errorf("expected export to be unlimited but got %v", st.filter.Limit)
+		}
+
+		rows, err := csv.NewReader(rec.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("expected valid csv but got %v", err)
+		}
+
+		if len(rows) != 3 || rows[2][12] != st.entries[1].Hash || rows[2][11] != st.entries[0].Hash {
+			t.Errorf("expected header and 2 chained rows but got %v", rows)
+		}
+	})
+}
+
+func TestVerifyHandler(t *testing.T) {
+	testCases := []struct {
+		name       string
+		st         *stub
+		wantStatus int
+		wantValid  bool
+	}{
+		{"should return valid for an untouched chain", &stub{entries: chain(3)}, http.StatusOK, true},
+		{"should return 500 if entries can't be read", &stub{err: errors.New("failed to read audit log")}, http.StatusInternalServerError, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/admin/audit/verify", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			New(tc.st).VerifyHandler(c)
+
+			if rec.Code != tc.wantStatus {
+				t.Errorf("expected status code %v but got %v", tc.wantStatus, rec.Code)
+			}
+
+			var got Verification
+			json.Unmarshal(rec.Body.Bytes(), &got)
+			if got.Valid != tc.wantValid {
+				t.Errorf("expected valid %v but got %v", tc.wantValid, got.Valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/audit"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+const IdentityContextKey = "identity"
+
+const (
+	ErrInvalidCredentials = "invalid username or password"
+	ErrMissingToken       = "missing or malformed authorization header"
+	ErrInvalidToken       = "invalid or expired token"
+	ErrInvalidUsername    = "username must be between 3 and 100 characters"
+	ErrInvalidPassword    = "password must be between 8 and 72 characters"
+	ErrAdminExists        = "admin already exists"
+	ErrInvalidRole        = "role must be viewer, editor, approver, auditor or super-admin"
+	ErrPermissionDenied   = "role %q does not have permission %q"
+)
+
+var (
+	ErrNotFound  = problem.New("ADMIN_NOT_FOUND", "username", "admin not found")
+	ErrDuplicate = problem.New("ADMIN_EXISTS", "username", ErrAdminExists)
+
+	errInvalidCredentials = problem.New("AUTH_CREDENTIALS_INVALID", "", ErrInvalidCredentials)
+	errMissingToken       = problem.New("AUTH_TOKEN_MISSING", "", ErrMissingToken)
+	errInvalidUsername    = problem.New("ADMIN_USERNAME_INVALID", "username", ErrInvalidUsername)
+	errInvalidPassword    = problem.New("ADMIN_PASSWORD_INVALID", "password", ErrInvalidPassword)
+	errInvalidRole        = problem.New("ADMIN_ROLE_INVALID", "role", ErrInvalidRole)
+)
+
+type Admin struct {
+	ID           int       `json:"id"`
+	Username     string    `json:"username"`
+	Role         Role      `json:"role"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+type Admins struct {
+	Admins []Admin `json:"admins"`
+}
+
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type NewAdmin struct {
+	Credentials
+	Role Role `json:"role"`
+}
+
+type RoleChange struct {
+	Role Role `json:"role"`
+}
+
+type Token struct {
+	AccessToken string `json:"accessToken"`
+	TokenType   string `json:"tokenType"`
+	ExpiresIn   int    `json:"expiresIn"`
+}
+
+// Username is the stable identity recorded in proposals, keys and the audit
+// log. Name is only for display.
+type Identity struct {
+	Username  string
+	Name      string
+	Role      Role
+	TokenID   string
+	ExpiresAt time.Time
+}
+
+type Config struct {
+	Secret    []byte
+	TTL       time.Duration
+	BasicAuth bool
+	OIDC      *OIDC
+	Auditor   audit.Recorder
+}
+
+func IdentityFrom(c echo.Context) (Identity, bool) {
+	id, ok := c.Get(IdentityContextKey).(Identity)
+	return id, ok
+}
+
+func Username(c echo.Context) string {
+	id, _ := IdentityFrom(c)
+	return id.Username
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/audit"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+type Storer interface {
+	AdminByUsername(username string) (Admin, error)
+	CreateAdmin(Admin) (Admin, error)
+	Admins() ([]Admin, error)
+	SetAdminRole(username string, role Role) (Admin, error)
+	SetAdminPassword(username, hash string) error
+	RevokeToken(id string, expiresAt time.Time) error
+	IsTokenRevoked(id string) (bool, error)
+}
+
+type Handler struct {
+	store Storer
+	cfg   Config
+}
+
+func New(store Storer, cfg Config) *Handler {
+	return &Handler{store: store, cfg: cfg}
+}
+
+func (h *Handler) LoginHandler(c echo.Context) error {
+	cr := Credentials{}
+
+	if err := c.Bind(&cr); err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	a, ok := h.verify(cr)
+	if !ok {
+		h.record(c, cr.Username, audit.ActionLogin, errInvalidCredentials)
+		return problem.Respond(c, http.StatusUnauthorized, errInvalidCredentials)
+	}
+
+	t, err := h.issue(a)
+
+	if err != nil {
+		h.record(c, cr.Username, audit.ActionLogin, err)
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+
+	if err := h.record(c, cr.Username, audit.ActionLogin, nil); err != nil {
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+	return c.JSON(http.StatusOK, t)
+}
+
+func (h *Handler) LogoutHandler(c echo.Context) error {
+	id, _ := IdentityFrom(c)
+
+	if id.TokenID == "" {
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	if err := h.store.RevokeToken(id.TokenID, id.ExpiresAt); err != nil {
+		h.record(c, id.Username, audit.ActionLogout, err)
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+
+	if err := h.record(c, id.Username, audit.ActionLogout, nil); err != nil {
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) record(c echo.Context, actor, action string, err error) error {
+	if h.cfg.Auditor == nil {
+		return nil
+	}
+
+	e := audit.Entry{Actor: actor, Action: action, Outcome: audit.Success}
+	if err != nil {
+		e.Outcome = audit.Failure
+		e.Detail = err.Error()
+	}
+
+	return h.cfg.Auditor.Record(c, e)
+}
+
+func (h *Handler) CreateAdminHandler(c echo.Context) error {
+	na := NewAdmin{Role: Viewer}
+
+	if err := c.Bind(&na); err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	if err := na.Validate(); err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	hash, err := HashPassword(na.Password)
+
+	if err != nil {
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+
+	a, err := h.store.CreateAdmin(Admin{Username: na.Username, Role: na.Role, PasswordHash: hash})
+
+	if errors.Is(err, ErrDuplicate) {
+		return problem.Respond(c, http.StatusConflict, err)
+	}
+
+	if err != nil {
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusCreated, a)
+}
+
+func (h *Handler) AdminsHandler(c echo.Context) error {
+	as, err := h.store.Admins()
+
+	if err != nil {
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, Admins{Admins: as})
+}
+
+func (h *Handler) SetRoleHandler(c echo.Context) error {
+	rc := RoleChange{}
+
+	if err := c.Bind(&rc); err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	if err := rc.Role.Validate(); err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	a, err := h.store.SetAdminRole(c.Param("username"), rc.Role)
+
+	if errors.Is(err, ErrNotFound) {
+		return problem.Respond(c, http.StatusNotFound, err)
+	}
+
+	if err != nil {
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, a)
+}
@@ -0,0 +1,450 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/audit"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+type stub struct {
+	admins  map[string]Admin
+	revoked map[string]time.Time
+	err     error
+}
+
+func newStub(t *testing.T, credentials ...Credentials) *stub {
+	t.Helper()
+
+	s := &stub{admins: map[string]Admin{}, revoked: map[string]time.Time{}}
+	for i, cr := range credentials {
+		hash, err := HashPassword(cr.Password)
+		if err != nil {
+			t.Fatalf("failed to hash password: %v", err)
+		}
+		s.admins[cr.Username] = Admin{ID: i + 1, Username: cr.Username, Role: SuperAdmin, PasswordHash: hash}
+	}
+	return s
+}
+
+func (s *stub) AdminByUsername(username string) (Admin, error) {
+	if s.err != nil {
+		return Admin{}, s.err
+	}
+	a, ok := s.admins[username]
+	if !ok {
+		return Admin{}, ErrNotFound
+	}
+	return a, nil
+}
+
+func (s *stub) CreateAdmin(a Admin) (Admin, error) {
+	if s.err != nil {
+		return Admin{}, s.err
+	}
+	if _, ok := s.admins[a.Username]; ok {
+		return Admin{}, ErrDuplicate
+	}
+	a.ID = len(s.admins) + 1
+	s.admins[a.Username] = a
+	return a, nil
+}
+
+func (s *stub) Admins() ([]Admin, error) {
+	as := []Admin{}
+	for _, a := range s.admins {
+		as = append(as, a)
+	}
+	return as, s.err
+}
+
+func (s *stub) SetAdminRole(username string, role Role) (Admin, error) {
+	if s.err != nil {
+		return Admin{}, s.err
+	}
+	a, ok := s.admins[username]
+	if !ok {
+		return Admin{}, ErrNotFound
+	}
+	a.Role = role
+	s.admins[username] = a
+	return a, nil
+}
+
+func (s *stub) SetAdminPassword(username, hash string) error {
+	if s.err != nil {
+		return s.err
+	}
+	a, ok := s.admins[username]
+	if !ok {
+		return ErrNotFound
+	}
+	a.PasswordHash = hash
+	s.admins[username] = a
+	return nil
+}
+
+func (s *stub) RevokeToken(id string, expiresAt time.Time) error {
+	s.revoked[id] = expiresAt
+	return s.err
+}
+
+func (s *stub) IsTokenRevoked(id string) (bool, error) {
+	_, ok := s.revoked[id]
+	return ok, s.err
+}
+
+var mockConfig = Config{Secret: []byte("secret"), TTL: time.Hour}
+
+var mockCredentials = Credentials{Username: "adminTax", Password: "admin!admin"}
+
+func TestLoginHandler(t *testing.T) {
+	testCases := []struct {
+		name        string
+		credentials Credentials
+		wantStatus  int
+	}{
+		{"should return 401 if password is wrong", Credentials{Username: "adminTax", Password: "wrong password"}, http.StatusUnauthorized},
+		{"should return 401 if admin does not exist", Credentials{Username: "unknown", Password: "admin!admin"}, http.StatusUnauthorized},
+		{"should return 200 and a token if credentials are valid", mockCredentials, http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body, _ := json.Marshal(tc.credentials)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/admin/login", bytes.NewBuffer(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			h := New(newStub(t, mockCredentials), mockConfig)
+			err := h.LoginHandler(c)
+
+			if err != nil {
+				t.Errorf("expected nil but got %v", err)
+			}
+
+			if rec.Code != tc.wantStatus {
+				t.Errorf("expected status code %v but got %v", tc.wantStatus, rec.Code)
+			}
+
+			if tc.wantStatus != http.StatusOK {
+				var gotErr problem.Problem
+				json.Unmarshal(rec.Body.Bytes(), &gotErr)
+
+				if gotErr.Detail != ErrInvalidCredentials {
+					t.Errorf("expected error message %v but got %v", ErrInvalidCredentials, gotErr.Detail)
+				}
+				return
+			}
+
+			var got Token
+			json.Unmarshal(rec.Body.Bytes(), &got)
+
+			if got.AccessToken == "" || got.TokenType != "Bearer" || got.ExpiresIn != 3600 {
+				t.Errorf("expected a bearer token valid for an hour but got %v", got)
+			}
+		})
+	}
+}
+
+type recorder struct {
+	entries []audit.Entry
+	err     error
+}
+
+func (r *recorder) Record(c echo.Context, e audit.Entry) error {
+	r.entries = append(r.entries, e)
+	return r.err
+}
+
+func TestLoginAudit(t *testing.T) {
+	testCases := []struct {
+		name        string
+		credentials Credentials
+		wantOutcome string
+	}{
+		{"should record a failed login attempt", Credentials{Username: "adminTax", Password: "wrong password"}, audit.Failure},
+		{"should record a successful login", mockCredentials, audit.Success},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body, _ := json.Marshal(tc.credentials)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/admin/login", bytes.NewBuffer(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			c := e.NewContext(req, httptest.NewRecorder())
+
+			r := &recorder{}
+			h := New(newStub(t, mockCredentials), Config{Secret: mockConfig.Secret, TTL: time.Hour, Auditor: r})
+			h.LoginHandler(c)
+
+			if len(r.entries) != 1 {
+				t.Fatalf("expected 1 audit entry but got %v", len(r.entries))
+			}
+
+			got := r.entries[0]
+			if got.Actor != "adminTax" || got.Action != audit.ActionLogin || got.Outcome != tc.wantOutcome {
+				t.Errorf("expected %v login by adminTax but got %v", tc.wantOutcome, got)
+			}
+
+			if strings.Contains(got.Detail, tc.credentials.Password) {
+				t.Errorf("expected password not to be recorded")
+			}
+		})
+	}
+
+	t.Run("should return 500 without a token if the login cannot be recorded", func(t *testing.T) {
+		body, _ := json.Marshal(mockCredentials)
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/admin/login", bytes.NewBuffer(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		r := &recorder{err: audit.ErrNotRecorded}
+		New(newStub(t, mockCredentials), Config{Secret: mockConfig.Secret, TTL: time.Hour, Auditor: r}).LoginHandler(c)
+
+		if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "accessToken") {
+			t.Errorf("expected status code %v without a token but got %v %v", http.StatusInternalServerError, rec.Code, rec.Body.String())
+		}
+	})
+}
+
+func TestLogoutHandler(t *testing.T) {
+	t.Run("should revoke the current token", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/admin/logout", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set(IdentityContextKey, Identity{Username: "adminTax", TokenID: "token-id", ExpiresAt: time.Now().Add(time.Hour)})
+
+		st := newStub(t)
+		h := New(st, mockConfig)
+		err := h.LogoutHandler(c)
+
+		if err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		if _, ok := st.revoked["token-id"]; !ok {
+			t.Errorf("expected token to be revoked")
+		}
+
+		if rec.Code != http.StatusNoContent {
+			t.Errorf("expected status code %v but got %v", http.StatusNoContent, rec.Code)
+		}
+	})
+}
+
+func TestCreateAdminHandler(t *testing.T) {
+	testCases := []struct {
+		name       string
+		body       string
+		st         func(t *testing.T) *stub
+		wantStatus int
+		wantErr    string
+	}{
+		{
+			name:       "should return 400 if password is too short",
+			body:       `{"username": "alice", "password": "short"}`,
+			st:         func(t *testing.T) *stub { return newStub(t) },
+			wantStatus: http.StatusBadRequest,
+			wantErr:    ErrInvalidPassword,
+		},
+		{
+			name:       "should return 400 if username is too short",
+			body:       `{"username": "al", "password": "long enough"}`,
+			st:         func(t *testing.T) *stub { return newStub(t) },
+			wantStatus: http.StatusBadRequest,
+			wantErr:    ErrInvalidUsername,
+		},
+		{
+			name:       "should return 400 if role is unknown",
+			body:       `{"username": "alice", "password": "long enough", "role": "owner"}`,
+			st:         func(t *testing.T) *stub { return newStub(t) },
+			wantStatus: http.StatusBadRequest,
+			wantErr:    ErrInvalidRole,
+		},
+		{
+			name:       "should return 409 if admin already exists",
+			body:       `{"username": "adminTax", "password": "long enough"}`,
+			st:         func(t *testing.T) *stub { return newStub(t, mockCredentials) },
+			wantStatus: http.StatusConflict,
+			wantErr:    ErrAdminExists,
+		},
+		{
+			name:       "should return 500 if admin can't be stored",
+			body:       `{"username": "alice", "password": "long enough"}`,
+			st:         func(t *testing.T) *stub { s := newStub(t); s.err = errors.New("failed to create admin"); return s },
+			wantStatus: http.StatusInternalServerError,
+			wantErr:    "internal server error",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/admin/users", bytes.NewBufferString(tc.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			h := New(tc.st(t), mockConfig)
+			err := h.CreateAdminHandler(c)
+
+			if err != nil {
+				t.Errorf("expected nil but got %v", err)
+			}
+
+			var gotErr problem.Problem
+			json.Unmarshal(rec.Body.Bytes(), &gotErr)
+
+			if gotErr.Detail != tc.wantErr {
+				t.Errorf("expected error message %v but got %v", tc.wantErr, gotErr.Detail)
+			}
+
+			if rec.Code != tc.wantStatus {
+				t.Errorf("expected status code %v but got %v", tc.wantStatus, rec.Code)
+			}
+		})
+	}
+
+	t.Run("should return 201 and store a hashed password", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/admin/users", bytes.NewBufferString(`{"username": "alice", "password": "long enough"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		st := newStub(t)
+		h := New(st, mockConfig)
+		err := h.CreateAdminHandler(c)
+
+		if err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		if rec.Code != http.StatusCreated {
+			t.Errorf("expected status code %v but got %v", http.StatusCreated, rec.Code)
+		}
+
+		if bytes.Contains(rec.Body.Bytes(), []byte("long enough")) {
+			t.Errorf("expected password not to be returned")
+		}
+
+		a := st.admins["alice"]
+		if a.Role != Viewer {
+			t.Errorf("expected default role %v but got %v", Viewer, a.Role)
+		}
+
+		if a.PasswordHash == "long enough" || !CheckPassword(a.PasswordHash, "long enough") {
+			t.Errorf("expected a bcrypt hash of the password but got %v", a.PasswordHash)
+		}
+	})
+}
+
+func TestSetRoleHandler(t *testing.T) {
+	testCases := []struct {
+		name       string
+		username   string
+		body       string
+		wantStatus int
+		wantErr    string
+	}{
+		{"should return 400 if role is unknown", "adminTax", `{"role": "owner"}`, http.StatusBadRequest, ErrInvalidRole},
+		{"should return 404 if admin does not exist", "unknown", `{"role": "editor"}`, http.StatusNotFound, ErrNotFound.Error()},
+		{"should return 200 if role is changed", "adminTax", `{"role": "editor"}`, http.StatusOK, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPut, "/", bytes.NewBufferString(tc.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/admin/users/:username/role")
+			c.SetParamNames("username")
+			c.SetParamValues(tc.username)
+
+			st := newStub(t, mockCredentials)
+			h := New(st, mockConfig)
+			err := h.SetRoleHandler(c)
+
+			if err != nil {
+				t.Errorf("expected nil but got %v", err)
+			}
+
+			if rec.Code != tc.wantStatus {
+				t.Errorf("expected status code %v but got %v", tc.wantStatus, rec.Code)
+			}
+
+			var gotErr problem.Problem
+			json.Unmarshal(rec.Body.Bytes(), &gotErr)
+
+			if gotErr.Detail != tc.wantErr {
+				t.Errorf("expected error message %v but got %v", tc.wantErr, gotErr.Detail)
+			}
+
+			if tc.wantStatus == http.StatusOK && st.admins["adminTax"].Role != Editor {
+				t.Errorf("expected role %v but got %v", Editor, st.admins["adminTax"].Role)
+			}
+		})
+	}
+}
+
+func TestBootstrap(t *testing.T) {
+	t.Run("should create the admin from credentials if it does not exist", func(t *testing.T) {
+		st := newStub(t)
+
+		if err := Bootstrap(st, "adminTax", "admin!"); err != nil {
+			t.Fatalf("expected nil but got %v", err)
+		}
+
+		a := st.admins["adminTax"]
+		if !CheckPassword(a.PasswordHash, "admin!") || a.Role != SuperAdmin {
+			t.Errorf("expected bootstrap super-admin to be created but got %v", a)
+		}
+	})
+
+	t.Run("should update the password hash of an existing admin", func(t *testing.T) {
+		st := newStub(t, mockCredentials)
+		st.admins["adminTax"] = Admin{Username: "adminTax", Role: Editor, PasswordHash: st.admins["adminTax"].PasswordHash}
+
+		if err := Bootstrap(st, "adminTax", "changed password"); err != nil {
+			t.Fatalf("expected nil but got %v", err)
+		}
+
+		a := st.admins["adminTax"]
+		if !CheckPassword(a.PasswordHash, "changed password") || a.Role != Editor {
+			t.Errorf("expected the password to change and the role to be kept but got %v", a)
+		}
+	})
+
+	t.Run("should keep the hash if the password has not changed", func(t *testing.T) {
+		st := newStub(t, mockCredentials)
+		hash := st.admins["adminTax"].PasswordHash
+
+		if err := Bootstrap(st, "adminTax", mockCredentials.Password); err != nil {
+			t.Fatalf("expected nil but got %v", err)
+		}
+
+		if st.admins["adminTax"].PasswordHash != hash {
+			t.Errorf("expected the existing hash to be kept")
+		}
+	})
+}
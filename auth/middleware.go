@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+func (h *Handler) Authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		scheme, credentials, _ := strings.Cut(c.Request().Header.Get(echo.HeaderAuthorization), " ")
+
+		var id Identity
+		switch {
+		case strings.EqualFold(scheme, "Bearer") && credentials != "":
+			i, err := h.parse(credentials)
+			if errors.Is(err, errInvalidToken) && h.cfg.OIDC != nil {
+				i, err = h.verifyOIDC(credentials)
+			}
+			if errors.Is(err, errInvalidToken) {
+				return h.unauthorized(c, err)
+			}
+			if err != nil {
+				return problem.Respond(c, http.StatusInternalServerError, err)
+			}
+			id = i
+		case strings.EqualFold(scheme, "Basic") && h.cfg.BasicAuth:
+			cr, ok := basicCredentials(credentials)
+			if !ok {
+				return h.unauthorized(c, errMissingToken)
+			}
+			a, ok := h.verify(cr)
+			if !ok {
+				return h.unauthorized(c, errInvalidCredentials)
+			}
+			id = Identity{Username: a.Username, Name: a.Username, Role: a.Role}
+		default:
+			return h.unauthorized(c, errMissingToken)
+		}
+
+		c.Set(IdentityContextKey, id)
+		return next(c)
+	}
+}
+
+func (h *Handler) verifyOIDC(token string) (Identity, error) {
+	id, err := h.cfg.OIDC.Verify(token)
+	if err != nil || id.TokenID == "" {
+		return id, err
+	}
+
+	revoked, err := h.store.IsTokenRevoked(id.TokenID)
+	if err != nil {
+		return Identity{}, err
+	}
+	if revoked {
+		return Identity{}, errInvalidToken
+	}
+
+	return id, nil
+}
+
+func (h *Handler) unauthorized(c echo.Context, err error) error {
+	challenge := `Bearer realm="admin"`
+	if h.cfg.BasicAuth {
+		challenge += `, Basic realm="admin"`
+	}
+	c.Response().Header().Set(echo.HeaderWWWAuthenticate, challenge)
+	return problem.Respond(c, http.StatusUnauthorized, err)
+}
+
+func basicCredentials(encoded string) (Credentials, bool) {
+	b, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return Credentials{}, false
+	}
+
+	username, password, ok := strings.Cut(string(b), ":")
+	return Credentials{Username: username, Password: password}, ok
+}
@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+func authenticate(h *Handler, authorization string) (*httptest.ResponseRecorder, Identity) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/admin/deductions/history", nil)
+	if authorization != "" {
+		req.Header.Set(echo.HeaderAuthorization, authorization)
+	}
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	var got Identity
+	h.Authenticate(func(c echo.Context) error {
+		got, _ = IdentityFrom(c)
+		return c.NoContent(http.StatusOK)
+	})(c)
+
+	return rec, got
+}
+
+func basic(username, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+func TestAuthenticate(t *testing.T) {
+	t.Run("should return 401 if authorization header is missing", func(t *testing.T) {
+		rec, _ := authenticate(New(newStub(t), mockConfig), "")
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status code %v but got %v", http.StatusUnauthorized, rec.Code)
+		}
+
+		if rec.Header().Get(echo.HeaderWWWAuthenticate) == "" {
+			t.Errorf("expected an authentication challenge")
+		}
+	})
+
+	t.Run("should accept a token issued at login", func(t *testing.T) {
+		h := New(newStub(t, mockCredentials), mockConfig)
+		token, err := h.issue(Admin{Username: "adminTax", Role: SuperAdmin})
+		if err != nil {
+			t.Fatalf("expected nil but got %v", err)
+		}
+
+		rec, got := authenticate(h, "Bearer "+token.AccessToken)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status code %v but got %v", http.StatusOK, rec.Code)
+		}
+
+		if got.Username != "adminTax" || got.Role != SuperAdmin || got.TokenID == "" {
+			t.Errorf("expected identity of adminTax but got %v", got)
+		}
+	})
+
+	t.Run("should use the stored role instead of the role at login", func(t *testing.T) {
+		st := newStub(t, mockCredentials)
+		h := New(st, mockConfig)
+		token, _ := h.issue(Admin{Username: "adminTax", Role: SuperAdmin})
+
+		st.SetAdminRole("adminTax", Viewer)
+		rec, got := authenticate(h, "Bearer "+token.AccessToken)
+
+		if rec.Code != http.StatusOK || got.Role != Viewer {
+			t.Errorf("expected status code %v as viewer but got %v as %v", http.StatusOK, rec.Code, got.Role)
+		}
+	})
+
+	t.Run("should return 401 if the admin no longer exists", func(t *testing.T) {
+		h := New(newStub(t), mockConfig)
+		token, _ := h.issue(Admin{Username: "adminTax", Role: SuperAdmin})
+
+		rec, _ := authenticate(h, "Bearer "+token.AccessToken)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status code %v but got %v", http.StatusUnauthorized, rec.Code)
+		}
+	})
+
+	t.Run("should return 401 if token is expired", func(t *testing.T) {
+		h := New(newStub(t), Config{Secret: mockConfig.Secret, TTL: -time.Minute})
+		token, _ := h.issue(Admin{Username: "adminTax"})
+
+		rec, _ := authenticate(h, "Bearer "+token.AccessToken)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status code %v but got %v", http.StatusUnauthorized, rec.Code)
+		}
+	})
+
+	t.Run("should return 401 if token is revoked", func(t *testing.T) {
+		st := newStub(t)
+		h := New(st, mockConfig)
+		token, _ := h.issue(Admin{Username: "adminTax"})
+		id, _ := h.parse(token.AccessToken)
+		st.revoked[id.TokenID] = id.ExpiresAt
+
+		rec, _ := authenticate(h, "Bearer "+token.AccessToken)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status code %v but got %v", http.StatusUnauthorized, rec.Code)
+		}
+	})
+
+	t.Run("should return 401 if token is signed with another secret", func(t *testing.T) {
+		other := New(newStub(t), Config{Secret: []byte("other"), TTL: time.Hour})
+		token, _ := other.issue(Admin{Username: "adminTax"})
+
+		rec, _ := authenticate(New(newStub(t), mockConfig), "Bearer "+token.AccessToken)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status code %v but got %v", http.StatusUnauthorized, rec.Code)
+		}
+	})
+
+	t.Run("should return 401 if token has no expiry", func(t *testing.T) {
+		s, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{SuperAdmin, jwt.RegisteredClaims{ID: "id", Subject: "adminTax"}}).SignedString(mockConfig.Secret)
+
+		rec, _ := authenticate(New(newStub(t), mockConfig), "Bearer "+s)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status code %v but got %v", http.StatusUnauthorized, rec.Code)
+		}
+	})
+
+	t.Run("should reject basic auth unless enabled", func(t *testing.T) {
+		rec, _ := authenticate(New(newStub(t, mockCredentials), mockConfig), basic(mockCredentials.Username, mockCredentials.Password))
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status code %v but got %v", http.StatusUnauthorized, rec.Code)
+		}
+	})
+
+	t.Run("should accept basic auth against the admin store if enabled", func(t *testing.T) {
+		h := New(newStub(t, mockCredentials), Config{Secret: mockConfig.Secret, TTL: time.Hour, BasicAuth: true})
+
+		rec, got := authenticate(h, basic(mockCredentials.Username, mockCredentials.Password))
+
+		if rec.Code != http.StatusOK || got.Username != "adminTax" || got.Role != SuperAdmin {
+			t.Errorf("expected status code %v for adminTax but got %v for %v", http.StatusOK, rec.Code, got)
+		}
+
+		rec, _ = authenticate(h, basic(mockCredentials.Username, "wrong password"))
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status code %v but got %v", http.StatusUnauthorized, rec.Code)
+		}
+	})
+}
@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	ErrInvalidRoleGroups = "role groups must be a comma separated list of group=role"
+	ErrIssuerMismatch    = "discovery document issuer does not match configured issuer"
+	ErrMissingAudience   = "OIDC audience must be set to the client id of this service"
+)
+
+const jwksRefreshInterval = time.Minute
+
+var roleRank = []Role{SuperAdmin, Approver, Editor, Auditor, Viewer}
+
+type OIDCConfig struct {
+	Issuer      string
+	Audience    string
+	GroupsClaim string
+	RoleGroups  map[string]Role
+	Client      *http.Client
+}
+
+type OIDC struct {
+	cfg OIDCConfig
+
+	mu        sync.Mutex
+	jwksURI   string
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+type discovery struct {
+	Issuer  string `json:"issuer"`
+	JWKSURI string `json:"jwks_uri"`
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+// NewOIDC requires an audience: without one the aud claim is not checked and
+// tokens the issuer made for any other client would be accepted.
+func NewOIDC(cfg OIDCConfig) (*OIDC, error) {
+	if strings.TrimSpace(cfg.Audience) == "" {
+		return nil, errors.New(ErrMissingAudience)
+	}
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &OIDC{cfg: cfg}, nil
+}
+
+func ParseRoleGroups(s string) (map[string]Role, error) {
+	rg := map[string]Role{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		group, role, ok := strings.Cut(pair, "=")
+		r := Role(strings.TrimSpace(role))
+		if !ok || strings.TrimSpace(group) == "" || r.Validate() != nil {
+			return nil, errors.New(ErrInvalidRoleGroups)
+		}
+		rg[strings.TrimSpace(group)] = r
+	}
+	return rg, nil
+}
+
+func (o *OIDC) Verify(token string) (Identity, error) {
+	cl := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, &cl, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return o.key(kid)
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithIssuer(o.cfg.Issuer),
+		jwt.WithAudience(o.cfg.Audience),
+		jwt.WithExpirationRequired(),
+	)
+
+	if errors.Is(err, jwt.ErrTokenUnverifiable) && !errors.Is(err, errInvalidToken) {
+		return Identity{}, err
+	}
+	if err != nil {
+		return Identity{}, errInvalidToken
+	}
+
+	sub := claimString(cl, "sub")
+	if sub == "" {
+		return Identity{}, errInvalidToken
+	}
+
+	exp, _ := cl["exp"].(float64)
+	return Identity{
+		Username:  "oidc:" + o.cfg.Issuer + "#" + sub,
+		Name:      claimString(cl, "preferred_username", "email", "sub"),
+		Role:      o.role(cl[o.cfg.GroupsClaim]),
+		TokenID:   claimString(cl, "jti"),
+		ExpiresAt: time.Unix(int64(exp), 0),
+	}, nil
+}
+
+func (o *OIDC) role(claim interface{}) Role {
+	var groups []string
+	switch v := claim.(type) {
+	case string:
+		groups = strings.Fields(v)
+	case []interface{}:
+		for _, g := range v {
+			if s, ok := g.(string); ok {
+				groups = append(groups, s)
+			}
+		}
+	}
+
+	granted := map[Role]bool{}
+	for _, g := range groups {
+		if r, ok := o.cfg.RoleGroups[g]; ok {
+			granted[r] = true
+		}
+	}
+
+	for _, r := range roleRank {
+		if granted[r] {
+			return r
+		}
+	}
+	return ""
+}
+
+func (o *OIDC) key(kid string) (*rsa.PublicKey, error) {
+	o.mu.Lock()
+	k, ok := o.keys[kid]
+	fresh := o.keys != nil && time.Since(o.fetchedAt) < jwksRefreshInterval
+	jwksURI := o.jwksURI
+	o.mu.Unlock()
+
+	if ok {
+		return k, nil
+	}
+	if fresh {
+		return nil, errInvalidToken
+	}
+
+	jwksURI, keys, err := o.fetch(jwksURI)
+	if err != nil {
+		return nil, err
+	}
+
+	o.mu.Lock()
+	o.jwksURI = jwksURI
+	o.keys = keys
+	o.fetchedAt = time.Now()
+	o.mu.Unlock()
+
+	if k, ok := keys[kid]; ok {
+		return k, nil
+	}
+	return nil, errInvalidToken
+}
+
+// fetch runs without o.mu so a slow issuer does not block verification with
+// keys that are already cached.
+func (o *OIDC) fetch(jwksURI string) (string, map[string]*rsa.PublicKey, error) {
+	if jwksURI == "" {
+		d := discovery{}
+		if err := o.get(o.cfg.Issuer+"/.well-known/openid-configuration", &d); err != nil {
+			return "", nil, err
+		}
+		if strings.TrimSuffix(d.Issuer, "/") != o.cfg.Issuer {
+			return "", nil, errors.New(ErrIssuerMismatch)
+		}
+		jwksURI = d.JWKSURI
+	}
+
+	set := jwks{}
+	if err := o.get(jwksURI, &set); err != nil {
+		return "", nil, err
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		pk, err := k.publicKey()
+		if err != nil {
+			return "", nil, err
+		}
+		keys[k.Kid] = pk
+	}
+
+	return jwksURI, keys, nil
+}
+
+func (o *OIDC) get(url string, v interface{}) error {
+	resp, err := o.cfg.Client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (k jwk) publicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+}
+
+func claimString(cl jwt.MapClaims, names ...string) string {
+	for _, n := range names {
+		if s, ok := cl[n].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,337 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type issuer struct {
+	*httptest.Server
+	keys     map[string]*rsa.PrivateKey
+	jwksHits int
+	held     chan struct{}
+	hold     chan struct{}
+}
+
+func newIssuer(t *testing.T, kids ...string) *issuer {
+	t.Helper()
+
+	is := &issuer{keys: map[string]*rsa.PrivateKey{}}
+	for _, kid := range kids {
+		is.addKey(t, kid)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(discovery{Issuer: is.URL, JWKSURI: is.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		is.jwksHits++
+		if is.hold != nil {
+			is.held <- struct{}{}
+			<-is.hold
+		}
+		set := jwks{}
+		for kid, k := range is.keys {
+			set.Keys = append(set.Keys, jwk{
+				Kid: kid,
+				Kty: "RSA",
+				Use: "sig",
+				N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(set)
+	})
+
+	is.Server = httptest.NewServer(mux)
+	t.Cleanup(is.Close)
+	return is
+}
+
+func (is *issuer) addKey(t *testing.T, kid string) {
+	t.Helper()
+
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	is.keys[kid] = k
+}
+
+func (is *issuer) sign(t *testing.T, kid string, cl jwt.MapClaims) string {
+	t.Helper()
+
+	tk := jwt.NewWithClaims(jwt.SigningMethodRS256, cl)
+	tk.Header["kid"] = kid
+	s, err := tk.SignedString(is.keys[kid])
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return s
+}
+
+func (is *issuer) claims(mutate func(jwt.MapClaims)) jwt.MapClaims {
+	cl := jwt.MapClaims{
+		"iss":                is.URL,
+		"aud":                "assessment-tax",
+		"sub":                "00u1",
+		"preferred_username": "alice@example.com",
+		"groups":             []string{"tax-viewers", "tax-approvers"},
+		"jti":                "jti-1",
+		"exp":                time.Now().Add(time.Hour).Unix(),
+	}
+	if mutate != nil {
+		mutate(cl)
+	}
+	return cl
+}
+
+func newVerifier(is *issuer) *OIDC {
+	o, err := NewOIDC(OIDCConfig{
+		Issuer:   is.URL,
+		Audience: "assessment-tax",
+		RoleGroups: map[string]Role{
+			"tax-viewers":   Viewer,
+			"tax-approvers": Approver,
+			"tax-admins":    SuperAdmin,
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return o
+}
+
+func TestNewOIDC(t *testing.T) {
+	if _, err := NewOIDC(OIDCConfig{Issuer: "https://idp.example.com"}); err == nil || err.Error() != ErrMissingAudience {
+		t.Errorf("expected %v but got %v", ErrMissingAudience, err)
+	}
+}
+
+func TestOIDCVerify(t *testing.T) {
+	t.Run("should map a valid token to an identity with the highest role", func(t *testing.T) {
+		is := newIssuer(t, "k1")
+
+		got, err := newVerifier(is).Verify(is.sign(t, "k1", is.claims(nil)))
+
+		if err != nil {
+			t.Fatalf("expected nil but got %v", err)
+		}
+
+		want := "oidc:" + is.URL + "#00u1"
+		if got.Username != want || got.Name != "alice@example.com" || got.Role != Approver || got.TokenID != "jti-1" {
+			t.Errorf("expected %v named alice@example.com as approver but got %v", want, got)
+		}
+	})
+
+	t.Run("should leave role empty if no group is mapped", func(t *testing.T) {
+		is := newIssuer(t, "k1")
+		token := is.sign(t, "k1", is.claims(func(cl jwt.MapClaims) { cl["groups"] = []string{"staff"} }))
+
+		got, err := newVerifier(is).Verify(token)
+
+		if err != nil || got.Role != "" {
+			t.Errorf("expected no role but got %v, %v", got.Role, err)
+		}
+	})
+
+	testCases := []struct {
+		name   string
+		mutate func(jwt.MapClaims)
+	}{
+		{"should reject a token from another issuer", func(cl jwt.MapClaims) { cl["iss"] = "https://evil.example.com" }},
+		{"should reject a token for another audience", func(cl jwt.MapClaims) { cl["aud"] = "other-app" }},
+		{"should reject an expired token", func(cl jwt.MapClaims) { cl["exp"] = time.Now().Add(-time.Minute).Unix() }},
+		{"should reject a token without expiry", func(cl jwt.MapClaims) { delete(cl, "exp") }},
+		{"should reject a token without subject", func(cl jwt.MapClaims) { delete(cl, "sub") }},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := newIssuer(t, "k1")
+
+			_, err := newVerifier(is).Verify(is.sign(t, "k1", is.claims(tc.mutate)))
+
+			if err != errInvalidToken {
+				t.Errorf("expected %v but got %v", errInvalidToken, err)
+			}
+		})
+	}
+
+	t.Run("should reject a token signed by an unknown key", func(t *testing.T) {
+		is := newIssuer(t, "k1")
+		other := newIssuer(t, "k1")
+
+		_, err := newVerifier(is).Verify(other.sign(t, "k1", is.claims(nil)))
+
+		if err != errInvalidToken {
+			t.Errorf("expected %v but got %v", errInvalidToken, err)
+		}
+	})
+
+	t.Run("should reject a token signed with a shared secret", func(t *testing.T) {
+		is := newIssuer(t, "k1")
+		s, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, is.claims(nil)).SignedString([]byte("secret"))
+
+		_, err := newVerifier(is).Verify(s)
+
+		if err != errInvalidToken {
+			t.Errorf("expected %v but got %v", errInvalidToken, err)
+		}
+	})
+
+	t.Run("should fetch keys again when the issuer rotates keys", func(t *testing.T) {
+		is := newIssuer(t, "k1")
+		v := newVerifier(is)
+
+		if _, err := v.Verify(is.sign(t, "k1", is.claims(nil))); err != nil {
+			t.Fatalf("expected nil but got %v", err)
+		}
+
+		is.addKey(t, "k2")
+		v.fetchedAt = time.Time{}
+
+		if _, err := v.Verify(is.sign(t, "k2", is.claims(nil))); err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		if is.jwksHits != 2 {
+			t.Errorf("expected keys to be fetched 2 times but got %v", is.jwksHits)
+		}
+	})
+
+	t.Run("should use the subject as name when no username claim is present", func(t *testing.T) {
+		is := newIssuer(t, "k1")
+		token := is.sign(t, "k1", is.claims(func(cl jwt.MapClaims) { delete(cl, "preferred_username") }))
+
+		got, err := newVerifier(is).Verify(token)
+
+		if err != nil || got.Name != "00u1" {
+			t.Errorf("expected name 00u1 but got %v, %v", got.Name, err)
+		}
+	})
+
+	t.Run("should verify with cached keys while keys are being fetched", func(t *testing.T) {
+		is := newIssuer(t, "k1")
+		v := newVerifier(is)
+		v.Verify(is.sign(t, "k1", is.claims(nil)))
+
+		is.addKey(t, "k2")
+		is.held, is.hold = make(chan struct{}), make(chan struct{})
+		v.fetchedAt = time.Time{}
+		rotated, cached := is.sign(t, "k2", is.claims(nil)), is.sign(t, "k1", is.claims(nil))
+
+		fetched := make(chan error)
+		go func() {
+			_, err := v.Verify(rotated)
+			fetched <- err
+		}()
+		<-is.held
+
+		verified := make(chan error)
+		go func() {
+			_, err := v.Verify(cached)
+			verified <- err
+		}()
+
+		select {
+		case err := <-verified:
+			if err != nil {
+				t.Errorf("expected nil but got %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("verification with a cached key waited for the fetch")
+		}
+
+		close(is.hold)
+		if err := <-fetched; err != nil {
+			t.Errorf("expected nil after the fetch but got %v", err)
+		}
+	})
+
+	t.Run("should not fetch keys again for every unknown key id", func(t *testing.T) {
+		is := newIssuer(t, "k1")
+		v := newVerifier(is)
+		v.Verify(is.sign(t, "k1", is.claims(nil)))
+
+		is.addKey(t, "k2")
+		v.Verify(is.sign(t, "k2", is.claims(nil)))
+
+		if is.jwksHits != 1 {
+			t.Errorf("expected keys to be fetched once but got %v", is.jwksHits)
+		}
+	})
+}
+
+func TestParseRoleGroups(t *testing.T) {
+	t.Run("should parse group to role pairs", func(t *testing.T) {
+		got, err := ParseRoleGroups("tax-admins=super-admin, tax-editors=editor")
+
+		want := map[string]Role{"tax-admins": SuperAdmin, "tax-editors": Editor}
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v but got %v, %v", want, got, err)
+		}
+	})
+
+	t.Run("should return an error if role is unknown", func(t *testing.T) {
+		_, err := ParseRoleGroups("tax-admins=owner")
+
+		if err == nil || err.Error() != ErrInvalidRoleGroups {
+			t.Errorf("expected %v but got %v", ErrInvalidRoleGroups, err)
+		}
+	})
+}
+
+func TestAuthenticateOIDC(t *testing.T) {
+	is := newIssuer(t, "k1")
+	h := New(newStub(t, mockCredentials), Config{Secret: mockConfig.Secret, TTL: time.Hour, OIDC: newVerifier(is)})
+
+	t.Run("should accept a token from the configured issuer", func(t *testing.T) {
+		rec, got := authenticate(h, "Bearer "+is.sign(t, "k1", is.claims(nil)))
+
+		if rec.Code != http.StatusOK || got.Role != Approver {
+			t.Errorf("expected status code %v as approver but got %v as %v", http.StatusOK, rec.Code, got.Role)
+		}
+	})
+
+	t.Run("should still accept locally issued tokens", func(t *testing.T) {
+		token, _ := h.issue(Admin{Username: "adminTax", Role: SuperAdmin})
+
+		rec, got := authenticate(h, "Bearer "+token.AccessToken)
+
+		if rec.Code != http.StatusOK || got.Username != "adminTax" {
+			t.Errorf("expected status code %v for adminTax but got %v for %v", http.StatusOK, rec.Code, got)
+		}
+	})
+
+	t.Run("should return 401 if token was revoked at logout", func(t *testing.T) {
+		st := newStub(t)
+		st.revoked["jti-1"] = time.Now().Add(time.Hour)
+		h := New(st, Config{Secret: mockConfig.Secret, TTL: time.Hour, OIDC: newVerifier(is)})
+
+		rec, _ := authenticate(h, "Bearer "+is.sign(t, "k1", is.claims(nil)))
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status code %v but got %v", http.StatusUnauthorized, rec.Code)
+		}
+	})
+
+	t.Run("should return 401 if token is invalid for both", func(t *testing.T) {
+		rec, _ := authenticate(h, "Bearer "+is.sign(t, "k1", is.claims(func(cl jwt.MapClaims) { cl["aud"] = "other-app" })))
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status code %v but got %v", http.StatusUnauthorized, rec.Code)
+		}
+	})
+}
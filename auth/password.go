@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"errors"
+
+	"github.com/varissara-wo/assessment-tax/problem"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+func HashPassword(password string) (string, error) {
+	h, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(h), err
+}
+
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+func (cr Credentials) Validate() error {
+	var es problem.Errors
+
+	if len(cr.Username) < 3 || len(cr.Username) > 100 {
+		es.Add(errInvalidUsername)
+	}
+
+	if len(cr.Password) < 8 || len(cr.Password) > 72 {
+		es.Add(errInvalidPassword)
+	}
+
+	return es.Err()
+}
+
+func (na NewAdmin) Validate() error {
+	var es problem.Errors
+
+	es.Add(na.Credentials.Validate())
+	es.Add(na.Role.Validate())
+
+	return es.Err()
+}
+
+func (h *Handler) verify(cr Credentials) (Admin, bool) {
+	a, err := h.store.AdminByUsername(cr.Username)
+	if err != nil {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(cr.Password))
+		return Admin{}, false
+	}
+
+	return a, CheckPassword(a.PasswordHash, cr.Password)
+}
+
+// Bootstrap creates the configured admin, or updates its password hash when
+// the configured password has changed.
+func Bootstrap(store Storer, username, password string) error {
+	if username == "" || password == "" {
+		return nil
+	}
+
+	a, err := store.AdminByUsername(username)
+	if err == nil && CheckPassword(a.PasswordHash, password) {
+		return nil
+	}
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	if a.Username != "" {
+		return store.SetAdminPassword(username, hash)
+	}
+
+	_, err = store.CreateAdmin(Admin{Username: username, Role: SuperAdmin, PasswordHash: hash})
+	return err
+}
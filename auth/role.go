@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+type Role string
+
+const (
+	Viewer     Role = "viewer"
+	Editor     Role = "editor"
+	Approver   Role = "approver"
+	Auditor    Role = "auditor"
+	SuperAdmin Role = "super-admin"
+)
+
+type Permission string
+
+const (
+	ReadDeductions    Permission = "deductions:read"
+	ProposeDeductions Permission = "deductions:propose"
+	ApproveDeductions Permission = "deductions:approve"
+	ManageUsers       Permission = "users:manage"
+	ManageAPIKeys     Permission = "api-keys:manage"
+	ReadAudit         Permission = "audit:read"
+	ManageWebhooks    Permission = "webhooks:manage"
+)
+
+var RolePermissions = map[Role][]Permission{
+	Viewer:     {ReadDeductions},
+	Editor:     {ReadDeductions, ProposeDeductions},
+	Approver:   {ReadDeductions, ApproveDeductions},
+	Auditor:    {ReadDeductions, ReadAudit},
+	SuperAdmin: {ReadDeductions, ProposeDeductions, ApproveDeductions, ManageUsers, ManageAPIKeys, ReadAudit, ManageWebhooks},
+}
+
+func (r Role) Validate() error {
+	if _, ok := RolePermissions[r]; !ok {
+		return errInvalidRole
+	}
+	return nil
+}
+
+func (r Role) Can(p Permission) bool {
+	for _, rp := range RolePermissions[r] {
+		if rp == p {
+			return true
+		}
+	}
+	return false
+}
+
+func Require(p Permission) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if status, err := Authorize(c, p); err != nil {
+				return problem.Respond(c, status, err)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+func Authorize(c echo.Context, p Permission) (int, error) {
+	id, ok := IdentityFrom(c)
+	if !ok {
+		return http.StatusUnauthorized, errMissingToken
+	}
+
+	if !id.Role.Can(p) {
+		return http.StatusForbidden, problem.Newf("AUTH_PERMISSION_DENIED", "", ErrPermissionDenied, id.Role, p)
+	}
+
+	return http.StatusOK, nil
+}
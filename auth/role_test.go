@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+func TestRequire(t *testing.T) {
+	testCases := []struct {
+		role       Role
+		permission Permission
+		wantStatus int
+	}{
+		{Viewer, ReadDeductions, http.StatusOK},
+		{Viewer, ProposeDeductions, http.StatusForbidden},
+		{Viewer, ApproveDeductions, http.StatusForbidden},
+		{Editor, ProposeDeductions, http.StatusOK},
+		{Editor, ApproveDeductions, http.StatusForbidden},
+		{Approver, ApproveDeductions, http.StatusOK},
+		{Approver, ProposeDeductions, http.StatusForbidden},
+		{Approver, ManageUsers, http.StatusForbidden},
+		{SuperAdmin, ManageUsers, http.StatusOK},
+		{"", ReadDeductions, http.StatusForbidden},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("role %q with permission %q should return %v", tc.role, tc.permission, tc.wantStatus), func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.Set(IdentityContextKey, Identity{Username: "alice", Role: tc.role})
+
+			err := Require(tc.permission)(func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})(c)
+
+			if err != nil {
+				t.Errorf("expected nil but got %v", err)
+			}
+
+			if rec.Code != tc.wantStatus {
+				t.Errorf("expected status code %v but got %v", tc.wantStatus, rec.Code)
+			}
+
+			if tc.wantStatus == http.StatusForbidden {
+				var gotErr problem.Problem
+				json.Unmarshal(rec.Body.Bytes(), &gotErr)
+
+				want := fmt.Sprintf(ErrPermissionDenied, tc.role, tc.permission)
+				if gotErr.Detail != want {
+					t.Errorf("expected error message %v but got %v", want, gotErr.Detail)
+				}
+			}
+		})
+	}
+
+	t.Run("should return 401 if request is not authenticated", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		Require(ReadDeductions)(func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		})(c)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status code %v but got %v", http.StatusUnauthorized, rec.Code)
+		}
+	})
+}
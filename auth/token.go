@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+var errInvalidToken = problem.New("AUTH_TOKEN_INVALID", "", ErrInvalidToken)
+
+type claims struct {
+	Role Role `json:"role"`
+	jwt.RegisteredClaims
+}
+
+func (h *Handler) issue(a Admin) (Token, error) {
+	id, err := tokenID()
+	if err != nil {
+		return Token{}, err
+	}
+
+	now := time.Now()
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{a.Role, jwt.RegisteredClaims{
+		ID:        id,
+		Subject:   a.Username,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(h.cfg.TTL)),
+	}})
+
+	s, err := t.SignedString(h.cfg.Secret)
+	if err != nil {
+		return Token{}, err
+	}
+
+	return Token{AccessToken: s, TokenType: "Bearer", ExpiresIn: int(h.cfg.TTL.Seconds())}, nil
+}
+
+func (h *Handler) parse(token string) (Identity, error) {
+	cl := claims{}
+	_, err := jwt.ParseWithClaims(token, &cl, func(t *jwt.Token) (interface{}, error) {
+		return h.cfg.Secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || cl.ID == "" || cl.Subject == "" {
+		return Identity{}, errInvalidToken
+	}
+
+	revoked, err := h.store.IsTokenRevoked(cl.ID)
+	if err != nil {
+		return Identity{}, err
+	}
+	if revoked {
+		return Identity{}, errInvalidToken
+	}
+
+	// The role in the token is only what it was at login. The stored role
+	// applies, so a demoted admin loses rights on the next request.
+	a, err := h.store.AdminByUsername(cl.Subject)
+	if errors.Is(err, ErrNotFound) {
+		return Identity{}, errInvalidToken
+	}
+	if err != nil {
+		return Identity{}, err
+	}
+
+	return Identity{Username: a.Username, Name: a.Username, Role: a.Role, TokenID: cl.ID, ExpiresAt: cl.ExpiresAt.Time}, nil
+}
+
+func tokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func NewSecret() ([]byte, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	return b, err
+}
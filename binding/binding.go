@@ -0,0 +1,120 @@
+package binding
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+type Mode string
+
+const (
+	Lenient Mode = "lenient"
+	Strict  Mode = "strict"
+)
+
+const ModeContextKey = "binding.mode"
+
+const (
+	ErrInvalidMode     = "binding mode must be lenient or strict"
+	ErrTrailingData    = "request body must contain a single JSON value"
+	ErrUnknownField    = "%s is not a known field"
+	ErrDuplicateField  = "%s must not appear more than once"
+	ErrFieldType       = "%s must be of type %s"
+	ErrNumberNotFinite = "%s must be a finite number"
+)
+
+const (
+	CodeTrailingData    = "REQUEST_BODY_TRAILING_DATA"
+	CodeUnknownField    = "REQUEST_FIELD_UNKNOWN"
+	CodeDuplicateField  = "REQUEST_FIELD_DUPLICATE"
+	CodeFieldType       = "REQUEST_FIELD_TYPE"
+	CodeNumberNotFinite = "REQUEST_NUMBER_NOT_FINITE"
+)
+
+type Binder struct {
+	echo.DefaultBinder
+	mode Mode
+}
+
+func New(mode Mode) *Binder {
+	return &Binder{mode: mode}
+}
+
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(strings.ToLower(strings.TrimSpace(s))); m {
+	case Lenient, Strict:
+		return m, nil
+	}
+	return "", errors.New(ErrInvalidMode)
+}
+
+func WithMode(mode Mode) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(ModeContextKey, mode)
+			return next(c)
+		}
+	}
+}
+
+func (b *Binder) Mode(c echo.Context) Mode {
+	if m, ok := c.Get(ModeContextKey).(Mode); ok {
+		return m
+	}
+	return b.mode
+}
+
+func (b *Binder) Bind(i interface{}, c echo.Context) error {
+	ctype := c.Request().Header.Get(echo.HeaderContentType)
+	if b.Mode(c) != Strict || !strings.HasPrefix(ctype, echo.MIMEApplicationJSON) {
+		return b.DefaultBinder.Bind(i, c)
+	}
+
+	if err := b.BindPathParams(c, i); err != nil {
+		return err
+	}
+
+	return b.BindStrict(c, i)
+}
+
+func (b *Binder) BindStrict(c echo.Context, i interface{}) error {
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+
+	if err := Check(body, i); err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(body, i); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+	return nil
+}
+
+func Check(body []byte, i interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	s := scanner{dec: dec}
+	if err := s.value(typeOf(i), ""); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return problem.New(CodeTrailingData, "", ErrTrailingData)
+	}
+
+	return s.errs.Err()
+}
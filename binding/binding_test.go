@@ -0,0 +1,154 @@
+package binding
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/problem"
+	"github.com/varissara-wo/assessment-tax/tax"
+)
+
+func bind(b *Binder, body string, i interface{}, mws ...echo.MiddlewareFunc) error {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	h := func(c echo.Context) error {
+		return b.Bind(i, c)
+	}
+	for _, mw := range mws {
+		h = mw(h)
+	}
+	return h(c)
+}
+
+func details(err error) problem.Errors {
+	var es problem.Errors
+	if errors.As(err, &es) {
+		return es
+	}
+	var e *problem.Error
+	if errors.As(err, &e) {
+		return problem.Errors{e}
+	}
+	return nil
+}
+
+func TestStrict(t *testing.T) {
+	b := New(Strict)
+
+	tests := []struct {
+		name  string
+		body  string
+		code  string
+		field string
+	}{
+		{"should reject unknown fields", `{"totalincome": 500000}`, CodeUnknownField, "totalincome"},
+		{"should reject fields in another casing", `{"totalIncome": 1, "allowances": [{"AllowanceType": "donation", "amount": 1}]}`, CodeUnknownField, "allowances[0].AllowanceType"},
+		{"should reject duplicate keys", `{"totalIncome": 1, "totalIncome": 2}`, CodeDuplicateField, "totalIncome"},
+		{"should reject wrong types", `{"totalIncome": "500000"}`, CodeFieldType, "totalIncome"},
+		{"should reject null for a number", `{"totalIncome": null}`, CodeFieldType, "totalIncome"},
+		{"should reject null for a string", `{"totalIncome": 1, "allowances": [{"allowanceType": null, "amount": 1}]}`, CodeFieldType, "allowances[0].allowanceType"},
+		{"should reject null for an object", `{"totalIncome": 1, "allowances": [null]}`, CodeFieldType, "allowances[0]"},
+		{"should reject numbers that are not finite", `{"totalIncome": 1e999}`, CodeNumberNotFinite, "totalIncome"},
+		{"should reject a body that is not an object", `[1]`, CodeFieldType, ""},
+		{"should reject trailing data", `{"totalIncome": 1} {}`, CodeTrailingData, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var td tax.TaxDetails
+			es := details(bind(b, tt.body, &td))
+
+			if len(es) != 1 || es[0].Code != tt.code || es[0].Field != tt.field {
+				t.Errorf("expected %v on %q but got %v", tt.code, tt.field, es)
+			}
+		})
+	}
+
+	t.Run("should report every rejected field", func(t *testing.T) {
+		var cr tax.CalculationRequest
+		es := details(bind(b, `{"filingDate": 1, "taxYear": 2567.5, "incomes": [{"category": "salary", "amount": 1, "bogus": true}]}`, &cr))
+
+		want := []string{"filingDate", "taxYear", "incomes[0].bogus"}
+		if len(es) != len(want) {
+			t.Fatalf("expected errors on %v but got %v", want, es)
+		}
+		for i, e := range es {
+			if e.Field != want[i] {
+				t.Errorf("expected error on %v but got %v", want[i], e.Field)
+			}
+		}
+	})
+
+	t.Run("should bind a valid body", func(t *testing.T) {
+		var td tax.TaxDetails
+		err := bind(b, `{"totalIncome": 500000, "wht": 0, "allowances": [{"allowanceType": "donation", "amount": 200000}]}`, &td)
+
+		if err != nil || td.TotalIncome != 500000 || td.Allowances[0].Amount != 200000 {
+			t.Errorf("expected body to be bound but got %+v %v", td, err)
+		}
+	})
+
+	t.Run("should accept null for a list", func(t *testing.T) {
+		var td tax.TaxDetails
+		err := bind(b, `{"totalIncome": 500000, "allowances": null}`, &td)
+
+		if err != nil || td.Allowances != nil {
+			t.Errorf("expected no allowances but got %+v %v", td, err)
+		}
+	})
+
+	t.Run("should bind embedded fields", func(t *testing.T) {
+		var br tax.BatchRequest
+		err := bind(b, `{"items": [{"referenceId": "a", "totalIncome": 500000}]}`, &br)
+
+		if err != nil || br.Items[0].ReferenceID != "a" || br.Items[0].TotalIncome != 500000 {
+			t.Errorf("expected body to be bound but got %+v %v", br, err)
+		}
+	})
+
+	t.Run("should report a body that is not json", func(t *testing.T) {
+		var td tax.TaxDetails
+		err := bind(b, `{"totalIncome": NaN}`, &td)
+
+		if p := problem.From(http.StatusBadRequest, err); p.Code != problem.CodeInvalidBody {
+			t.Errorf("expected %v but got %+v", problem.CodeInvalidBody, p)
+		}
+	})
+}
+
+func TestLenient(t *testing.T) {
+	t.Run("should ignore unknown fields", func(t *testing.T) {
+		var td tax.TaxDetails
+		err := bind(New(Lenient), `{"totalincome": 500000, "bogus": 1}`, &td)
+
+		if err != nil || td.TotalIncome != 500000 {
+			t.Errorf("expected lenient binding but got %+v %v", td, err)
+		}
+	})
+
+	t.Run("should be overridden by the route mode", func(t *testing.T) {
+		var td tax.TaxDetails
+		err := bind(New(Lenient), `{"bogus": 1}`, &td, WithMode(Strict))
+
+		if es := details(err); len(es) != 1 || es[0].Code != CodeUnknownField {
+			t.Errorf("expected %v but got %v", CodeUnknownField, err)
+		}
+	})
+}
+
+func TestParseMode(t *testing.T) {
+	if m, err := ParseMode("Strict"); err != nil || m != Strict {
+		t.Errorf("expected strict but got %v %v", m, err)
+	}
+
+	if _, err := ParseMode("loose"); err == nil || err.Error() != ErrInvalidMode {
+		t.Errorf("expected %v but got %v", ErrInvalidMode, err)
+	}
+}
@@ -0,0 +1,238 @@
+package binding
+
+import (
+	"encoding"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+// Only these kinds have a nil value, so null would silently bind the zero
+// value into any other field.
+var nullableKinds = map[reflect.Kind]bool{
+	reflect.Ptr:       true,
+	reflect.Interface: true,
+	reflect.Map:       true,
+	reflect.Slice:     true,
+}
+
+var (
+	jsonUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+type scanner struct {
+	dec  *json.Decoder
+	errs problem.Errors
+}
+
+func typeOf(i interface{}) reflect.Type {
+	t := reflect.TypeOf(i)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func (s *scanner) value(t reflect.Type, path string) error {
+	nullable := t == nil || nullableKinds[t.Kind()]
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() == reflect.Interface || unmarshals(t) {
+		var raw json.RawMessage
+		return s.dec.Decode(&raw)
+	}
+
+	tok, err := s.dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '[' {
+			return s.array(t, path)
+		}
+		return s.object(t, path)
+	case json.Number:
+		s.number(t, path, tok)
+	case string:
+		if t.Kind() != reflect.String {
+			s.typeError(t, path)
+		}
+	case bool:
+		if t.Kind() != reflect.Bool {
+			s.typeError(t, path)
+		}
+	case nil:
+		if !nullable {
+			s.typeError(t, path)
+		}
+	}
+
+	return nil
+}
+
+func (s *scanner) object(t reflect.Type, path string) error {
+	var fields map[string]reflect.Type
+	switch t.Kind() {
+	case reflect.Struct:
+		fields = jsonFields(t)
+	case reflect.Map:
+	default:
+		s.typeError(t, path)
+		t = nil
+	}
+
+	seen := map[string]bool{}
+	for s.dec.More() {
+		tok, err := s.dec.Token()
+		if err != nil {
+			return err
+		}
+
+		key, _ := tok.(string)
+		field := join(path, key)
+
+		if seen[key] {
+			s.errs.Add(problem.Newf(CodeDuplicateField, field, ErrDuplicateField, field))
+		}
+		seen[key] = true
+
+		var ft reflect.Type
+		switch {
+		case t == nil:
+		case t.Kind() == reflect.Map:
+			ft = t.Elem()
+		default:
+			var ok bool
+			if ft, ok = fields[key]; !ok {
+				s.errs.Add(problem.Newf(CodeUnknownField, field, ErrUnknownField, field))
+			}
+		}
+
+		if err := s.value(ft, field); err != nil {
+			return err
+		}
+	}
+
+	_, err := s.dec.Token()
+	return err
+}
+
+func (s *scanner) array(t reflect.Type, path string) error {
+	var elem reflect.Type
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		elem = t.Elem()
+	default:
+		s.typeError(t, path)
+	}
+
+	for i := 0; s.dec.More(); i++ {
+		if err := s.value(elem, path+"["+strconv.Itoa(i)+"]"); err != nil {
+			return err
+		}
+	}
+
+	_, err := s.dec.Token()
+	return err
+}
+
+func (s *scanner) number(t reflect.Type, path string, n json.Number) {
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(string(n), t.Bits())
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			s.errs.Add(problem.Newf(CodeNumberNotFinite, path, ErrNumberNotFinite, path))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if _, err := strconv.ParseInt(string(n), 10, t.Bits()); err != nil {
+			s.typeError(t, path)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if _, err := strconv.ParseUint(string(n), 10, t.Bits()); err != nil {
+			s.typeError(t, path)
+		}
+	default:
+		s.typeError(t, path)
+	}
+}
+
+func (s *scanner) typeError(t reflect.Type, path string) {
+	label := path
+	if label == "" {
+		label = "request body"
+	}
+	s.errs.Add(problem.Newf(CodeFieldType, path, ErrFieldType, label, kindName(t)))
+}
+
+func kindName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return "object"
+}
+
+func unmarshals(t reflect.Type) bool {
+	p := reflect.PointerTo(t)
+	return p.Implements(jsonUnmarshaler) || p.Implements(textUnmarshaler)
+}
+
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for n, t := range jsonFields(ft) {
+					if _, ok := fields[n]; !ok {
+						fields[n] = t
+					}
+				}
+				continue
+			}
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,133 @@
+package gql
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/allowance"
+	"github.com/varissara-wo/assessment-tax/i18n"
+	"github.com/varissara-wo/assessment-tax/problem"
+	"github.com/varissara-wo/assessment-tax/tax"
+)
+
+type contextKey struct{}
+
+const (
+	ErrEmptyQuery       = "query must not be empty"
+	ErrInvalidScenarios = "scenarios must contain between 1 and %d entries"
+)
+
+const maxScenarios = 10
+
+var errEmptyQuery = problem.New("GRAPHQL_QUERY_EMPTY", "query", ErrEmptyQuery)
+
+type Storer interface {
+	tax.Storer
+	GetAllowances(at time.Time) (allowance.MaxAllowance, error)
+}
+
+type Handler struct {
+	store     Storer
+	proposals *allowance.Handler
+	public    graphql.Schema
+	admin     graphql.Schema
+}
+
+type Option func(*Handler)
+
+type Request struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+type resolverError struct {
+	p problem.Problem
+}
+
+func (e resolverError) Error() string {
+	return e.p.Detail
+}
+
+func (e resolverError) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{"code": e.p.Code, "status": e.p.Status}
+	if len(e.p.Details) > 0 {
+		ext["details"] = e.p.Details
+	}
+	return ext
+}
+
+func New(store Storer, opts ...Option) (*Handler, error) {
+	h := &Handler{store: store}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	var err error
+	if h.public, err = h.schema(false); err != nil {
+		return nil, err
+	}
+	if h.admin, err = h.schema(true); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func WithProposals(ah *allowance.Handler) Option {
+	return func(h *Handler) {
+		h.proposals = ah
+	}
+}
+
+func (h *Handler) QueryHandler(c echo.Context) error {
+	return h.serve(c, h.public)
+}
+
+func (h *Handler) AdminHandler(c echo.Context) error {
+	return h.serve(c, h.admin)
+}
+
+func (h *Handler) serve(c echo.Context, schema graphql.Schema) error {
+	req := Request{}
+
+	if err := c.Bind(&req); err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	if req.Query == "" {
+		return problem.Respond(c, http.StatusBadRequest, errEmptyQuery)
+	}
+
+	if err := checkQuery(req.Query); err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	res := graphql.Do(graphql.Params{
+		Schema:         schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        context.WithValue(c.Request().Context(), contextKey{}, c),
+	})
+
+	return c.JSON(http.StatusOK, res)
+}
+
+func echoContext(p graphql.ResolveParams) echo.Context {
+	c, _ := p.Context.Value(contextKey{}).(echo.Context)
+	return c
+}
+
+func lang(p graphql.ResolveParams) i18n.Lang {
+	if c := echoContext(p); c != nil {
+		return i18n.From(c)
+	}
+	return i18n.Default
+}
+
+func fail(p graphql.ResolveParams, status int, err error) error {
+	return resolverError{p: problem.Localized(lang(p), status, err)}
+}
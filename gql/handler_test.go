@@ -0,0 +1,452 @@
+package gql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql/testutil"
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/allowance"
+	"github.com/varissara-wo/assessment-tax/auth"
+	"github.com/varissara-wo/assessment-tax/i18n"
+	"github.com/varissara-wo/assessment-tax/problem"
+	"github.com/varissara-wo/assessment-tax/tax"
+)
+
+var caps = allowance.MaxAllowance{Donation: 100000.0, KReceipt: 50000.0, Personal: 60000.0, RMF: 500000.0, SSF: 200000.0}
+
+type stub struct {
+	err error
+	at  time.Time
+}
+
+func (s *stub) TaxCalculation(td tax.TaxDetails) (tax.TaxResponse, error) {
+	return tax.CalculateTax(td.CalculateNetIncome(caps), td.WHT), s.err
+}
+
+func (s *stub) TaxesCalculation(tds []tax.TaxDetails, p tax.Progress) ([]tax.Taxes, error) {
+	return nil, s.err
+}
+
+func (s *stub) TaxRecommendation(td tax.TaxDetails) (tax.RecommendationResponse, error) {
+	return td.Recommend(caps), s.err
+}
+
+func (s *stub) TaxCurve(cr tax.CurveRequest) (tax.CurveResponse, error) {
+	return tax.CurveResponse{}, s.err
+}
+
+func (s *stub) TaxSummary(td tax.TaxDetails) (tax.TaxSummary, error) {
+	return td.Summarize(caps), s.err
+}
+
+func (s *stub) TaxSummaries(tds []tax.TaxDetails) ([]tax.TaxSummary, error) {
+	ts := make([]tax.TaxSummary, len(tds))
+	for i, td := range tds {
+		ts[i] = td.Summarize(caps)
+	}
+	return ts, s.err
+}
+
+func (s *stub) TaxBatch(tds []tax.TaxDetails, workers int) ([]tax.TaxOutcome, error) {
+	return tax.CalculateTaxes(tds, repeatCaps(len(tds)), workers), s.err
+}
+
+func repeatCaps(n int) []allowance.MaxAllowance {
+	mas := make([]allowance.MaxAllowance, n)
+	for i := range mas {
+		mas[i] = caps
+	}
+	return mas
+}
+
+func (s *stub) GetAllowances(at time.Time) (allowance.MaxAllowance, error) {
+	s.at = at
+	return caps, s.err
+}
+
+type proposalStub struct {
+	created  allowance.Proposal
+	reviewed allowance.Proposal
+	reviewer string
+}
+
+func (s *proposalStub) CreateProposal(p allowance.Proposal, a allowance.Audit) (allowance.Proposal, error) {
+	p.ID = 1
+	s.created = p
+	return p, nil
+}
+
+func (s *proposalStub) Proposal(id int) (allowance.Proposal, error) {
+	return s.created, nil
+}
+
+func (s *proposalStub) Proposals(status allowance.ProposalStatus) ([]allowance.Proposal, error) {
+	return nil, nil
+}
+
+func (s *proposalStub) ApproveProposal(id int, reviewer string, a allowance.Audit) (allowance.Proposal, error) {
+	s.reviewer = reviewer
+	return s.reviewed, nil
+}
+
+func (s *proposalStub) RejectProposal(id int, reviewer string, a allowance.Audit) (allowance.Proposal, error) {
+	s.reviewer = reviewer
+	return s.reviewed, nil
+}
+
+func (s *proposalStub) AllowanceHistory(t allowance.AllowanceType) ([]allowance.CapRecord, error) {
+	return nil, nil
+}
+
+func (s *proposalStub) GetAllowances(at time.Time) (allowance.MaxAllowance, error) {
+	return caps, nil
+}
+
+type gqlError struct {
+	Message    string `json:"message"`
+	Extensions struct {
+		Code    string          `json:"code"`
+		Status  int             `json:"status"`
+		Details []problem.Error `json:"details"`
+	} `json:"extensions"`
+}
+
+type response struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []gqlError                 `json:"errors"`
+}
+
+type call struct {
+	body     string
+	language string
+	identity *auth.Identity
+}
+
+func serve(t *testing.T, handler func(*Handler) echo.HandlerFunc, h *Handler, cl call) (*httptest.ResponseRecorder, response) {
+	t.Helper()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(cl.body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if cl.language != "" {
+		req.Header.Set(i18n.HeaderAcceptLanguage, cl.language)
+	}
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	if cl.identity != nil {
+		c.Set(auth.IdentityContextKey, *cl.identity)
+	}
+
+	if err := handler(h)(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var res response
+	json.Unmarshal(rec.Body.Bytes(), &res)
+	return rec, res
+}
+
+func query(q string) string {
+	b, _ := json.Marshal(Request{Query: q})
+	return string(b)
+}
+
+func public(h *Handler) echo.HandlerFunc { return h.QueryHandler }
+func admin(h *Handler) echo.HandlerFunc  { return h.AdminHandler }
+
+func newHandler(t *testing.T, st *stub, ps *proposalStub) *Handler {
+	t.Helper()
+
+	h, err := New(st, WithProposals(allowance.New(ps)))
+	if err != nil {
+		t.Fatalf("unexpected schema error: %v", err)
+	}
+	return h
+}
+
+func TestQueryHandler(t *testing.T) {
+	t.Run("should return 400 when query is empty", func(t *testing.T) {
+		h := newHandler(t, &stub{}, &proposalStub{})
+
+		rec, _ := serve(t, public, h, call{body: `{"query":""}`})
+
+		var got problem.Problem
+		json.Unmarshal(rec.Body.Bytes(), &got)
+
+		if rec.Code != http.StatusBadRequest || got.Code != "GRAPHQL_QUERY_EMPTY" {
+			t.Errorf("expected 400 GRAPHQL_QUERY_EMPTY but got %v %v", rec.Code, got.Code)
+		}
+	})
+
+	t.Run("should return allowance caps", func(t *testing.T) {
+		h := newHandler(t, &stub{}, &proposalStub{})
+
+		rec, res := serve(t, public, h, call{body: query(`{ allowanceCaps { allowanceType maxAmount } }`)})
+
+		var got []struct {
+			AllowanceType string  `json:"allowanceType"`
+			MaxAmount     float64 `json:"maxAmount"`
+		}
+		json.Unmarshal(res.Data["allowanceCaps"], &got)
+
+		if rec.Code != http.StatusOK || len(res.Errors) != 0 {
+			t.Fatalf("expected no errors but got %v %v", rec.Code, res.Errors)
+		}
+		if len(got) != len(allowance.AllowanceTypes) {
+			t.Fatalf("expected %d caps but got %d", len(allowance.AllowanceTypes), len(got))
+		}
+		for _, c := range got {
+			if c.AllowanceType == string(allowance.KReceipt) && c.MaxAmount != caps.KReceipt {
+				t.Errorf("expected k-receipt cap %v but got %v", caps.KReceipt, c.MaxAmount)
+			}
+		}
+	})
+
+	t.Run("should resolve allowance caps for the tax year being filed", func(t *testing.T) {
+		st := &stub{}
+		h := newHandler(t, st, &proposalStub{})
+
+		serve(t, public, h, call{body: query(`{ allowanceCaps { maxAmount } }`)})
+
+		if want := (tax.TaxDetails{}).CapsAt(time.Now()); !st.at.Equal(want) {
+			t.Errorf("expected caps at %v but got %v", want, st.at)
+		}
+	})
+
+	t.Run("should return 400 when query is nested too deeply", func(t *testing.T) {
+		h := newHandler(t, &stub{}, &proposalStub{})
+		q := "{ __schema { types { name } } }"
+		for i := 0; i < maxDepth; i++ {
+			q = strings.Replace(q, "{ name }", "{ fields { type { name } } }", 1)
+		}
+
+		rec, res := serve(t, public, h, call{body: query(q)})
+
+		var got problem.Problem
+		json.Unmarshal(rec.Body.Bytes(), &got)
+
+		if rec.Code != http.StatusBadRequest || got.Code != "GRAPHQL_QUERY_TOO_COMPLEX" || res.Data != nil {
+			t.Errorf("expected 400 GRAPHQL_QUERY_TOO_COMPLEX but got %v %v", rec.Code, got.Code)
+		}
+	})
+
+	t.Run("should allow the introspection query", func(t *testing.T) {
+		h := newHandler(t, &stub{}, &proposalStub{})
+
+		rec, res := serve(t, public, h, call{body: query(testutil.IntrospectionQuery)})
+
+		if rec.Code != http.StatusOK || len(res.Errors) != 0 || res.Data["__schema"] == nil {
+			t.Errorf("expected the schema but got %v %v", rec.Code, res.Errors)
+		}
+	})
+
+	t.Run("should return the bracket table in the requested language", func(t *testing.T) {
+		h := newHandler(t, &stub{}, &proposalStub{})
+
+		_, res := serve(t, public, h, call{body: query(`{ taxBrackets { level from to rate } }`), language: "th"})
+
+		var got []struct {
+			Level string   `json:"level"`
+			From  float64  `json:"from"`
+			To    *float64 `json:"to"`
+			Rate  float64  `json:"rate"`
+		}
+		json.Unmarshal(res.Data["taxBrackets"], &got)
+
+		want := tax.BracketTable(i18n.Thai)
+		if len(got) != len(want) {
+			t.Fatalf("expected %d brackets but got %d", len(want), len(got))
+		}
+		if got[0].Level != want[0].Level {
+			t.Errorf("expected level %q but got %q", want[0].Level, got[0].Level)
+		}
+		if got[len(got)-1].To != nil {
+			t.Errorf("expected the last bracket to be open ended but got %v", *got[len(got)-1].To)
+		}
+	})
+
+	t.Run("should return only the selected calculation fields", func(t *testing.T) {
+		h := newHandler(t, &stub{}, &proposalStub{})
+
+		_, res := serve(t, public, h, call{body: query(`{ calculation(input: {totalIncome: 500000, wht: 0}) { tax netIncome } }`)})
+
+		var got map[string]float64
+		json.Unmarshal(res.Data["calculation"], &got)
+
+		want := map[string]float64{"tax": 29000.0, "netIncome": 440000.0}
+		if len(got) != len(want) || got["tax"] != want["tax"] || got["netIncome"] != want["netIncome"] {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+
+	t.Run("should return field errors in extensions when input is invalid", func(t *testing.T) {
+		h := newHandler(t, &stub{}, &proposalStub{})
+
+		_, res := serve(t, public, h, call{body: query(`{ calculation(input: {totalIncome: -1}) { tax } }`)})
+
+		if len(res.Errors) != 1 {
+			t.Fatalf("expected one error but got %v", res.Errors)
+		}
+		ext := res.Errors[0].Extensions
+		if ext.Status != http.StatusBadRequest || len(ext.Details) == 0 || !strings.HasPrefix(ext.Details[0].Field, "input.") {
+			t.Errorf("expected 400 with input field details but got %+v", ext)
+		}
+	})
+
+	t.Run("should compare scenarios against the first one", func(t *testing.T) {
+		h := newHandler(t, &stub{}, &proposalStub{})
+
+		q := `{ compareScenarios(scenarios: [
+			{name: "base", input: {totalIncome: 500000}},
+			{name: "donate", input: {totalIncome: 500000, allowances: [{allowanceType: "donation", amount: 100000}]}}
+		]) { name taxDifference calculation { tax } } }`
+		_, res := serve(t, public, h, call{body: query(q)})
+
+		var got []struct {
+			Name          string  `json:"name"`
+			TaxDifference float64 `json:"taxDifference"`
+		}
+		json.Unmarshal(res.Data["compareScenarios"], &got)
+
+		if len(got) != 2 || got[0].TaxDifference != 0 || got[1].TaxDifference != -10000.0 {
+			t.Errorf("expected differences 0 and -10000 but got %+v %v", got, res.Errors)
+		}
+	})
+
+	t.Run("should reject too many scenarios", func(t *testing.T) {
+		h := newHandler(t, &stub{}, &proposalStub{})
+
+		items := strings.Repeat(`{name: "s", input: {totalIncome: 1}},`, maxScenarios+1)
+		_, res := serve(t, public, h, call{body: query(`{ compareScenarios(scenarios: [` + items + `]) { name } }`)})
+
+		if len(res.Errors) != 1 || res.Errors[0].Extensions.Code != "GRAPHQL_SCENARIOS_INVALID" {
+			t.Errorf("expected GRAPHQL_SCENARIOS_INVALID but got %+v", res.Errors)
+		}
+	})
+
+	t.Run("should return 500 extensions when store fails", func(t *testing.T) {
+		h := newHandler(t, &stub{err: errors.New("boom")}, &proposalStub{})
+
+		_, res := serve(t, public, h, call{body: query(`{ allowanceCaps { maxAmount } }`)})
+
+		if len(res.Errors) != 1 || res.Errors[0].Extensions.Status != http.StatusInternalServerError {
+			t.Errorf("expected a 500 error but got %+v", res.Errors)
+		}
+	})
+
+	t.Run("should not expose mutations", func(t *testing.T) {
+		h := newHandler(t, &stub{}, &proposalStub{})
+
+		_, res := serve(t, public, h, call{body: query(`mutation { approveProposal(id: 1) { id } }`)})
+
+		if len(res.Errors) == 0 {
+			t.Errorf("expected mutation to be rejected")
+		}
+	})
+}
+
+func TestAdminHandler(t *testing.T) {
+	editor := &auth.Identity{Username: "editor", Role: auth.Editor}
+	approver := &auth.Identity{Username: "approver", Role: auth.Approver}
+
+	t.Run("should propose a cap for an editor", func(t *testing.T) {
+		ps := &proposalStub{}
+		h := newHandler(t, &stub{}, ps)
+
+		_, res := serve(t, admin, h, call{
+			body:     query(`mutation { proposeCap(allowanceType: "personal", amount: 70000, reason: "inflation") { id allowanceType amount status proposedBy } }`),
+			identity: editor,
+		})
+
+		var got allowance.Proposal
+		json.Unmarshal(res.Data["proposeCap"], &got)
+
+		if len(res.Errors) != 0 {
+			t.Fatalf("expected no errors but got %+v", res.Errors)
+		}
+		if got.ID != 1 || got.Amount != 70000.0 || got.ProposedBy != "editor" || got.Status != allowance.Pending {
+			t.Errorf("unexpected proposal %+v", got)
+		}
+		if ps.created.Reason != "inflation" {
+			t.Errorf("expected reason to be stored but got %q", ps.created.Reason)
+		}
+	})
+
+	t.Run("should return validation errors from the proposal", func(t *testing.T) {
+		h := newHandler(t, &stub{}, &proposalStub{})
+
+		_, res := serve(t, admin, h, call{
+			body:     query(`mutation { proposeCap(allowanceType: "personal", amount: 1) { id } }`),
+			identity: editor,
+		})
+
+		if len(res.Errors) != 1 || res.Errors[0].Extensions.Status != http.StatusBadRequest {
+			t.Errorf("expected a 400 error but got %+v", res.Errors)
+		}
+	})
+
+	t.Run("should deny approval without permission", func(t *testing.T) {
+		ps := &proposalStub{}
+		h := newHandler(t, &stub{}, ps)
+
+		_, res := serve(t, admin, h, call{body: query(`mutation { approveProposal(id: 1) { id } }`), identity: editor})
+
+		if len(res.Errors) != 1 || res.Errors[0].Extensions.Status != http.StatusForbidden {
+			t.Errorf("expected a 403 error but got %+v", res.Errors)
+		}
+		if ps.reviewer != "" {
+			t.Errorf("expected store not to be called but got reviewer %q", ps.reviewer)
+		}
+	})
+
+	t.Run("should approve a proposal for an approver", func(t *testing.T) {
+		ps := &proposalStub{
+			created:  allowance.Proposal{ID: 1, AllowanceType: allowance.Personal, Amount: 70000.0, Status: allowance.Pending, ProposedBy: "editor"},
+			reviewed: allowance.Proposal{ID: 1, AllowanceType: allowance.Personal, Amount: 70000.0, Status: allowance.Approved, ProposedBy: "editor", ReviewedBy: "approver"},
+		}
+		h := newHandler(t, &stub{}, ps)
+
+		_, res := serve(t, admin, h, call{body: query(`mutation { approveProposal(id: 1) { id status reviewedBy } }`), identity: approver})
+
+		var got allowance.Proposal
+		json.Unmarshal(res.Data["approveProposal"], &got)
+
+		if len(res.Errors) != 0 || got.Status != allowance.Approved || ps.reviewer != "approver" {
+			t.Errorf("expected approved proposal but got %+v %+v", got, res.Errors)
+		}
+	})
+
+	t.Run("should return 400 when query uses too many aliases", func(t *testing.T) {
+		h := newHandler(t, &stub{}, &proposalStub{})
+		var b strings.Builder
+		for i := 0; i <= maxAliases; i++ {
+			fmt.Fprintf(&b, "c%d: allowanceCaps { maxAmount } ", i)
+		}
+
+		rec, _ := serve(t, admin, h, call{body: query("{ " + b.String() + "}"), identity: approver})
+
+		var got problem.Problem
+		json.Unmarshal(rec.Body.Bytes(), &got)
+
+		if rec.Code != http.StatusBadRequest || got.Code != "GRAPHQL_QUERY_TOO_COMPLEX" {
+			t.Errorf("expected 400 GRAPHQL_QUERY_TOO_COMPLEX but got %v %v", rec.Code, got.Code)
+		}
+	})
+
+	t.Run("should require an identity", func(t *testing.T) {
+		h := newHandler(t, &stub{}, &proposalStub{})
+
+		_, res := serve(t, admin, h, call{body: query(`mutation { rejectProposal(id: 1) { id } }`)})
+
+		if len(res.Errors) != 1 || res.Errors[0].Extensions.Status != http.StatusUnauthorized {
+			t.Errorf("expected a 401 error but got %+v", res.Errors)
+		}
+	})
+}
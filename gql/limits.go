@@ -0,0 +1,102 @@
+package gql
+
+import (
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+const ErrQueryTooComplex = "query must not nest deeper than %d levels, select more than %d fields or use more than %d aliases"
+
+// The limits leave room for the standard introspection query.
+const (
+	maxDepth   = 15
+	maxFields  = 500
+	maxAliases = 20
+)
+
+var errQueryTooComplex = problem.Newf("GRAPHQL_QUERY_TOO_COMPLEX", "query", ErrQueryTooComplex, maxDepth, maxFields, maxAliases)
+
+type complexity struct {
+	fragments map[string]*ast.FragmentDefinition
+	visiting  map[string]bool
+	depth     int
+	fields    int
+	aliases   int
+}
+
+// checkQuery rejects queries whose depth, field count or alias count exceeds
+// the limits. Fragment spreads count once per use. Syntax errors are left for
+// graphql.Do to report.
+func checkQuery(query string) error {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return nil
+	}
+
+	cx := complexity{fragments: map[string]*ast.FragmentDefinition{}, visiting: map[string]bool{}}
+	for _, def := range doc.Definitions {
+		if fd, ok := def.(*ast.FragmentDefinition); ok && fd.Name != nil {
+			cx.fragments[fd.Name.Value] = fd
+		}
+	}
+
+	for _, def := range doc.Definitions {
+		if od, ok := def.(*ast.OperationDefinition); ok {
+			if !cx.walk(od.SelectionSet, 1) {
+				return errQueryTooComplex
+			}
+		}
+	}
+	return nil
+}
+
+// walk counts the selections under ss at the given depth and reports false
+// as soon as a limit is exceeded.
+func (cx *complexity) walk(ss *ast.SelectionSet, depth int) bool {
+	if ss == nil {
+		return true
+	}
+	if depth > cx.depth {
+		cx.depth = depth
+	}
+	if cx.depth > maxDepth {
+		return false
+	}
+
+	for _, sel := range ss.Selections {
+		switch s := sel.(type) {
+		case *ast.Field:
+			cx.fields++
+			if s.Alias != nil {
+				cx.aliases++
+			}
+			if cx.fields > maxFields || cx.aliases > maxAliases {
+				return false
+			}
+			if !cx.walk(s.SelectionSet, depth+1) {
+				return false
+			}
+		case *ast.InlineFragment:
+			if !cx.walk(s.SelectionSet, depth) {
+				return false
+			}
+		case *ast.FragmentSpread:
+			if s.Name == nil {
+				continue
+			}
+			fd, ok := cx.fragments[s.Name.Value]
+			// Cycles are invalid and graphql.Do reports them.
+			if !ok || cx.visiting[s.Name.Value] {
+				continue
+			}
+			cx.visiting[s.Name.Value] = true
+			ok = cx.walk(fd.SelectionSet, depth)
+			delete(cx.visiting, s.Name.Value)
+			if !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
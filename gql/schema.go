@@ -0,0 +1,345 @@
+package gql
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/varissara-wo/assessment-tax/allowance"
+	"github.com/varissara-wo/assessment-tax/auth"
+	"github.com/varissara-wo/assessment-tax/problem"
+	"github.com/varissara-wo/assessment-tax/tax"
+)
+
+type allowanceCap struct {
+	AllowanceType allowance.AllowanceType
+	Name          string
+	MaxAmount     float64
+}
+
+type scenario struct {
+	Name          string
+	Calculation   map[string]interface{}
+	TaxDifference float64
+}
+
+var allowanceCapType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "AllowanceCap",
+	Fields: graphql.Fields{
+		"allowanceType": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"name":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"maxAmount":     &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+	},
+})
+
+var taxBracketType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "TaxBracket",
+	Fields: graphql.Fields{
+		"level": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"from":  &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"to":    &graphql.Field{Type: graphql.Float},
+		"rate":  &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+	},
+})
+
+var taxLevelType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "TaxLevel",
+	Fields: graphql.Fields{
+		"level": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"tax":   &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+	},
+})
+
+var allowanceClaimType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "AllowanceClaim",
+	Fields: graphql.Fields{
+		"allowanceType": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"name":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"claimed":       &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+	},
+})
+
+var calculationType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Calculation",
+	Fields: graphql.Fields{
+		"totalIncome":    &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"allowances":     &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(allowanceClaimType)))},
+		"totalAllowance": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"netIncome":      &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"taxLevel":       &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(taxLevelType)))},
+		"totalTax":       &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"wht":            &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"tax":            &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"taxRefund":      &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+	},
+})
+
+var scenarioType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Scenario",
+	Fields: graphql.Fields{
+		"name":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"calculation":   &graphql.Field{Type: graphql.NewNonNull(calculationType)},
+		"taxDifference": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+	},
+})
+
+var proposalType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Proposal",
+	Fields: graphql.Fields{
+		"id":            &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"allowanceType": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"amount":        &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"effectiveFrom": &graphql.Field{Type: graphql.DateTime},
+		"reason":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"status":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"proposedBy":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"reviewedBy":    &graphql.Field{Type: graphql.String},
+		"reviewedAt":    &graphql.Field{Type: graphql.DateTime},
+		"createdAt":     &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+	},
+})
+
+var allowanceInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "AllowanceInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"allowanceType": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"amount":        &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Float)},
+	},
+})
+
+var taxInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "TaxInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"totalIncome": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Float)},
+		"wht":         &graphql.InputObjectFieldConfig{Type: graphql.Float, DefaultValue: 0.0},
+		"allowances":  &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(allowanceInputType))},
+	},
+})
+
+var scenarioInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "ScenarioInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"name":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"input": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(taxInputType)},
+	},
+})
+
+func (h *Handler) schema(admin bool) (graphql.Schema, error) {
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"allowanceCaps": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(allowanceCapType))),
+				Resolve: h.allowanceCaps,
+			},
+			"taxBrackets": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(taxBracketType))),
+				Resolve: h.taxBrackets,
+			},
+			"calculation": &graphql.Field{
+				Type:    graphql.NewNonNull(calculationType),
+				Args:    graphql.FieldConfigArgument{"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(taxInputType)}},
+				Resolve: h.calculation,
+			},
+			"compareScenarios": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(scenarioType))),
+				Args:    graphql.FieldConfigArgument{"scenarios": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(scenarioInputType)))}},
+				Resolve: h.compareScenarios,
+			},
+		},
+	})
+
+	cfg := graphql.SchemaConfig{Query: query}
+	if admin && h.proposals != nil {
+		cfg.Mutation = graphql.NewObject(graphql.ObjectConfig{
+			Name: "Mutation",
+			Fields: graphql.Fields{
+				"proposeCap": &graphql.Field{
+					Type: graphql.NewNonNull(proposalType),
+					Args: graphql.FieldConfigArgument{
+						"allowanceType": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+						"amount":        &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Float)},
+						"effectiveFrom": &graphql.ArgumentConfig{Type: graphql.String},
+						"reason":        &graphql.ArgumentConfig{Type: graphql.String},
+					},
+					Resolve: h.proposeCap,
+				},
+				"approveProposal": &graphql.Field{
+					Type:    graphql.NewNonNull(proposalType),
+					Args:    graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)}},
+					Resolve: h.review(true),
+				},
+				"rejectProposal": &graphql.Field{
+					Type:    graphql.NewNonNull(proposalType),
+					Args:    graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)}},
+					Resolve: h.review(false),
+				},
+			},
+		})
+	}
+
+	return graphql.NewSchema(cfg)
+}
+
+func (h *Handler) allowanceCaps(p graphql.ResolveParams) (interface{}, error) {
+	ma, err := h.store.GetAllowances(tax.TaxDetails{}.CapsAt(time.Now()))
+	if err != nil {
+		return nil, fail(p, http.StatusInternalServerError, err)
+	}
+
+	l := lang(p)
+	caps := make([]allowanceCap, len(allowance.AllowanceTypes))
+	for i, t := range allowance.AllowanceTypes {
+		caps[i] = allowanceCap{AllowanceType: t, Name: t.Name(l), MaxAmount: allowance.AllowanceAmount(ma).Get(t)}
+	}
+	return caps, nil
+}
+
+func (h *Handler) taxBrackets(p graphql.ResolveParams) (interface{}, error) {
+	bs := tax.BracketTable(lang(p))
+
+	brackets := make([]map[string]interface{}, len(bs))
+	for i, b := range bs {
+		brackets[i] = map[string]interface{}{"level": b.Level, "from": float64(b.From), "rate": float64(b.Rate)}
+		if b.To != nil {
+			brackets[i]["to"] = float64(*b.To)
+		}
+	}
+	return brackets, nil
+}
+
+func (h *Handler) calculation(p graphql.ResolveParams) (interface{}, error) {
+	td := taxDetails(p.Args["input"])
+
+	if err := td.ValidateTaxDetails(); err != nil {
+		return nil, fail(p, http.StatusBadRequest, problem.Nest(err, "input", nil))
+	}
+
+	ts, err := h.store.TaxSummary(td)
+	if err != nil {
+		return nil, fail(p, http.StatusInternalServerError, err)
+	}
+
+	return localize(ts, p), nil
+}
+
+func (h *Handler) compareScenarios(p graphql.ResolveParams) (interface{}, error) {
+	inputs, _ := p.Args["scenarios"].([]interface{})
+
+	if len(inputs) == 0 || len(inputs) > maxScenarios {
+		return nil, fail(p, http.StatusBadRequest, problem.Newf("GRAPHQL_SCENARIOS_INVALID", "scenarios", ErrInvalidScenarios, maxScenarios))
+	}
+
+	var es problem.Errors
+	names := make([]string, len(inputs))
+	tds := make([]tax.TaxDetails, len(inputs))
+	for i, in := range inputs {
+		m, _ := in.(map[string]interface{})
+		names[i], _ = m["name"].(string)
+		tds[i] = taxDetails(m["input"])
+		es.Add(problem.Nest(tds[i].ValidateTaxDetails(), fmt.Sprintf("scenarios[%d].input", i), nil))
+	}
+
+	if err := es.Err(); err != nil {
+		return nil, fail(p, http.StatusBadRequest, err)
+	}
+
+	ts, err := h.store.TaxSummaries(tds)
+	if err != nil {
+		return nil, fail(p, http.StatusInternalServerError, err)
+	}
+
+	scenarios := make([]scenario, len(ts))
+	for i, s := range ts {
+		scenarios[i] = scenario{
+			Name:          names[i],
+			Calculation:   localize(s, p),
+			TaxDifference: balance(s) - balance(ts[0]),
+		}
+	}
+	return scenarios, nil
+}
+
+func (h *Handler) proposeCap(p graphql.ResolveParams) (interface{}, error) {
+	c := echoContext(p)
+	if status, err := auth.Authorize(c, auth.ProposeDeductions); err != nil {
+		return nil, fail(p, status, err)
+	}
+
+	a := allowance.Amount{}
+	a.Amount, _ = p.Args["amount"].(float64)
+	a.EffectiveFrom, _ = p.Args["effectiveFrom"].(string)
+	a.Reason, _ = p.Args["reason"].(string)
+	t, _ := p.Args["allowanceType"].(string)
+
+	proposal, status, err := h.proposals.Propose(c, allowance.AllowanceType(t), a)
+	if err != nil {
+		return nil, fail(p, status, err)
+	}
+	return proposal, nil
+}
+
+func (h *Handler) review(approve bool) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		c := echoContext(p)
+		if status, err := auth.Authorize(c, auth.ApproveDeductions); err != nil {
+			return nil, fail(p, status, err)
+		}
+
+		id, _ := p.Args["id"].(int)
+		decide := h.proposals.Reject
+		if approve {
+			decide = h.proposals.Approve
+		}
+
+		proposal, status, err := decide(c, id)
+		if err != nil {
+			return nil, fail(p, status, err)
+		}
+		return proposal, nil
+	}
+}
+
+func taxDetails(v interface{}) tax.TaxDetails {
+	m, _ := v.(map[string]interface{})
+
+	td := tax.TaxDetails{}
+	td.TotalIncome, _ = m["totalIncome"].(float64)
+	td.WHT, _ = m["wht"].(float64)
+
+	as, _ := m["allowances"].([]interface{})
+	for _, a := range as {
+		am, _ := a.(map[string]interface{})
+		t, _ := am["allowanceType"].(string)
+		amount, _ := am["amount"].(float64)
+		td.Allowances = append(td.Allowances, allowance.Allowance{AllowanceType: allowance.AllowanceType(t), Amount: amount})
+	}
+
+	return td
+}
+
+func localize(ts tax.TaxSummary, p graphql.ResolveParams) map[string]interface{} {
+	l := lang(p)
+
+	claims := make([]map[string]interface{}, len(ts.Allowances))
+	for i, a := range ts.Allowances {
+		claims[i] = map[string]interface{}{"allowanceType": a.AllowanceType, "name": a.AllowanceType.Name(l), "claimed": a.Amount}
+	}
+
+	return map[string]interface{}{
+		"totalIncome":    ts.TotalIncome,
+		"allowances":     claims,
+		"totalAllowance": ts.TotalAllowance,
+		"netIncome":      ts.NetIncome,
+		"taxLevel":       ts.TaxResponse().Localize(l).TaxLevel,
+		"totalTax":       ts.TotalTax,
+		"wht":            ts.WHT,
+		"tax":            ts.Tax,
+		"taxRefund":      ts.TaxRefund,
+	}
+}
+
+func balance(ts tax.TaxSummary) float64 {
+	return ts.Tax - ts.TaxRefund
+}
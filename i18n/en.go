@@ -0,0 +1,110 @@
+package i18n
+
+var en = map[string]string{
+	"REQUEST_BODY_INVALID":       "request body is not valid JSON",
+	"REQUEST_BODY_TYPE_MISMATCH": "%s must be of type %s",
+	"VALIDATION_FAILED":          "request has %d validation errors",
+	"INTERNAL_ERROR":             "internal server error",
+	"RATE_LIMITED":               "too many requests, retry after %d seconds",
+	"REQUEST_BODY_TRAILING_DATA": "request body must contain a single JSON value",
+	"REQUEST_FIELD_DUPLICATE":    "%s must not appear more than once",
+	"REQUEST_FIELD_TYPE":         "%s must be of type %s",
+	"REQUEST_FIELD_UNKNOWN":      "%s is not a known field",
+	"REQUEST_NUMBER_NOT_FINITE":  "%s must be a finite number",
+
+	"ADMIN_EXISTS":             "admin already exists",
+	"ADMIN_NOT_FOUND":          "admin not found",
+	"ADMIN_PASSWORD_INVALID":   "password must be between 8 and 72 characters",
+	"ADMIN_ROLE_INVALID":       "role must be viewer, editor, approver, auditor or super-admin",
+	"ADMIN_USERNAME_INVALID":   "username must be between 3 and 100 characters",
+	"AUTH_CREDENTIALS_INVALID": "invalid username or password",
+	"AUTH_PERMISSION_DENIED":   "role %q does not have permission %q",
+	"AUTH_TOKEN_INVALID":       "invalid or expired token",
+	"AUTH_TOKEN_MISSING":       "missing or malformed authorization header",
+
+	"API_KEY_ID_INVALID":     "api key id must be a positive integer",
+	"API_KEY_INVALID":        "invalid or revoked api key",
+	"API_KEY_MISSING":        "missing api key",
+	"API_KEY_NAME_INVALID":   "name must be between 1 and 100 characters",
+	"API_KEY_NOT_FOUND":      "api key not found",
+	"API_KEY_SCOPES_EMPTY":   "at least one scope is required",
+	"API_KEY_SCOPE_INVALID":  "scopes must be calc or batch only",
+	"API_KEY_SCOPE_REQUIRED": "api key does not have scope %q",
+
+	"GRAPHQL_QUERY_EMPTY":       "query must not be empty",
+	"GRAPHQL_QUERY_TOO_COMPLEX": "query must not nest deeper than %d levels, select more than %d fields or use more than %d aliases",
+	"GRAPHQL_SCENARIOS_INVALID": "scenarios must contain between 1 and %d entries",
+
+	"IDEMPOTENCY_KEY_INVALID":         "idempotency key must be between 1 and 255 printable characters",
+	"IDEMPOTENCY_KEY_REUSED":          "idempotency key was already used for a different request",
+	"IDEMPOTENCY_REQUEST_IN_PROGRESS": "a request with this idempotency key is still in progress",
+
+	"WEBHOOK_EVENT_INVALID": "events must be deduction.proposed, deduction.approved or deduction.rejected",
+	"WEBHOOK_ID_INVALID":    "webhook id must be a positive integer",
+	"WEBHOOK_NOT_FOUND":     "webhook not found",
+	"WEBHOOK_URL_INVALID":   "url must be an absolute http or https url of at most 2048 characters",
+
+	"AUDIT_AFTER_INVALID": "after must be a positive entry id",
+	"AUDIT_FROM_INVALID":  "from must be a date (YYYY-MM-DD) or an RFC 3339 timestamp",
+	"AUDIT_LIMIT_INVALID": "limit must be between 1 and 1000",
+	"AUDIT_TO_INVALID":    "to must be a date (YYYY-MM-DD) or an RFC 3339 timestamp",
+	"AUDIT_WRITE_FAILED":  "the change could not be recorded in the audit log",
+
+	"ALLOWANCE_AMOUNT_NEGATIVE":             "allowance amount must be greater than or equal to 0",
+	"ALLOWANCE_EFFECTIVE_FROM_INVALID":      "effective from must be a date in YYYY-MM-DD format and not before the start of the tax year being filed",
+	"ALLOWANCE_HISTORY_TYPE_INVALID":        "type must be personal, donation, k-receipt, rmf or ssf",
+	"ALLOWANCE_K_RECEIPT_TOO_HIGH":          "amount must be less than 100000.0",
+	"ALLOWANCE_K_RECEIPT_TOO_LOW":           "amount must be greater than 0.0",
+	"ALLOWANCE_NOT_MANAGED":                 "allowance type can't be changed by admins",
+	"ALLOWANCE_PERSONAL_TOO_HIGH":           "amount must be less than 100000.0",
+	"ALLOWANCE_PERSONAL_TOO_LOW":            "amount must be greater than 10000.0",
+	"ALLOWANCE_RECOMMENDATION_TYPE_INVALID": "allowances must be donation, k-receipt, rmf or ssf only",
+	"ALLOWANCE_TYPE_INVALID":                "allowances must be donation and k-receipt only",
+
+	"PROPOSAL_ID_INVALID":     "proposal id must be a positive integer",
+	"PROPOSAL_NOT_FOUND":      "proposal not found",
+	"PROPOSAL_NOT_PENDING":    "proposal has already been reviewed",
+	"PROPOSAL_SELF_REVIEW":    "proposal must be reviewed by a different admin",
+	"PROPOSAL_STATUS_INVALID": "status must be pending, approved or rejected",
+
+	"TAX_BATCH_EMPTY":                  "items must not be empty",
+	"TAX_BATCH_REFERENCE_INVALID":      "reference id must not be longer than 100 characters",
+	"TAX_BATCH_TOO_LARGE":              "items must not exceed %d",
+	"TAX_CSV_FILE_MISSING":             "file must be uploaded as a multipart form field named file",
+	"TAX_CSV_HEADER_INVALID":           "invalid CSV header, expected totalIncome, wht, donation",
+	"TAX_CSV_INVALID":                  "file must be a valid CSV with three values per row",
+	"TAX_CSV_ROW":                      "row %d: ",
+	"TAX_CSV_VALUE_EMPTY":              "invalid CSV data value cannot be empty",
+	"TAX_CSV_VALUE_INVALID":            "invalid CSV data value must be a number",
+	"TAX_CURVE_RANGE_INVALID":          "from must be greater than or equal to 0 and less than or equal to to",
+	"TAX_CURVE_STEP_INVALID":           "step must be greater than 0",
+	"TAX_CURVE_TOO_MANY_POINTS":        "curve must not exceed 1000 points",
+	"TAX_DUE_DATE_INVALID":             "due date must be in YYYY-MM-DD format",
+	"TAX_INCOMES_EMPTY":                "incomes must not be empty",
+	"TAX_INCOME_AMOUNT_NEGATIVE":       "income amount must be greater than or equal to 0",
+	"TAX_INCOME_CATEGORY_INVALID":      "category must be salary, service, royalty, investment, rental, professional, contract or business",
+	"TAX_FILING_DATE_INVALID":          "filing date must be in YYYY-MM-DD format",
+	"TAX_INSTALLMENTS_BELOW_THRESHOLD": "installments are only available when tax due exceeds 3,000",
+	"TAX_ROW_QUOTA_EXCEEDED":           "daily row quota exceeded",
+	"TAX_RUN_EXISTS":                   "a batch run with this id has already started",
+	"TAX_RUN_ID_INVALID":               "run id must be 1 to 64 letters, digits, '-' or '_'",
+	"TAX_RUN_KEY_REQUIRED":             "batch run events require an API key",
+	"TAX_RUN_LAST_EVENT_ID_INVALID":    "Last-Event-ID must be a non-negative event id",
+	"TAX_RUN_LIMIT_EXCEEDED":           "too many batch runs are pending, wait for one to complete",
+	"TAX_TOTAL_INCOME_NEGATIVE":        "total income must be greater than or equals 0",
+	"TAX_WHT_EXCEEDS_INCOME":           "wht must be greater than or equal to 0 and less than total income",
+	"TAX_WHT_NEGATIVE":                 "wht must be greater than or equal to 0 and less than total income",
+	"TAX_YEAR_INVALID":                 "tax year must be a Buddhist era year between 2500 and 2999",
+
+	"TAX_BRACKET_1": "0-150,000",
+	"TAX_BRACKET_2": "150,001-500,000",
+	"TAX_BRACKET_3": "500,001-1,000,000",
+	"TAX_BRACKET_4": "1,000,001-2,000,000",
+	"TAX_BRACKET_5": "2,000,001 and above",
+
+	"ALLOWANCE_DONATION":  "Donations",
+	"ALLOWANCE_K_RECEIPT": "Goods and services purchases (Easy e-Receipt)",
+	"ALLOWANCE_PERSONAL":  "Personal allowance",
+	"ALLOWANCE_RMF":       "Retirement Mutual Fund (RMF) units",
+	"ALLOWANCE_SSF":       "Super Savings Fund (SSF) units",
+}
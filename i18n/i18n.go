@@ -0,0 +1,111 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+type Lang string
+
+const (
+	Thai    Lang = "th"
+	English Lang = "en"
+)
+
+const (
+	HeaderAcceptLanguage  = "Accept-Language"
+	HeaderContentLanguage = "Content-Language"
+)
+
+const ErrUnsupportedLanguage = "language must be th or en"
+
+var Languages = []Lang{Thai, English}
+
+var Default = English
+
+var catalogues = map[Lang]map[string]string{
+	Thai:    th,
+	English: en,
+}
+
+func Parse(s string) (Lang, error) {
+	l := Lang(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := catalogues[l]; !ok {
+		return "", errors.New(ErrUnsupportedLanguage)
+	}
+	return l, nil
+}
+
+func Negotiate(header string) Lang {
+	type candidate struct {
+		lang Lang
+		q    float64
+	}
+
+	var cs []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+		if q <= 0 {
+			continue
+		}
+
+		primary, _, _ := strings.Cut(tag, "-")
+		l := Lang(primary)
+		if primary == "*" {
+			l = Default
+		}
+		if _, ok := catalogues[l]; ok {
+			cs = append(cs, candidate{lang: l, q: q})
+		}
+	}
+
+	if len(cs) == 0 {
+		return Default
+	}
+
+	sort.SliceStable(cs, func(i, j int) bool {
+		return cs[i].q > cs[j].q
+	})
+	return cs[0].lang
+}
+
+func From(c echo.Context) Lang {
+	return Negotiate(c.Request().Header.Get(HeaderAcceptLanguage))
+}
+
+func (l Lang) Lookup(key string) (string, bool) {
+	if s, ok := catalogues[l][key]; ok {
+		return s, true
+	}
+	s, ok := catalogues[Default][key]
+	return s, ok
+}
+
+func (l Lang) T(key string, args ...interface{}) string {
+	s, ok := l.Lookup(key)
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return s
+	}
+	return fmt.Sprintf(s, args...)
+}
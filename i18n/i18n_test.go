@@ -0,0 +1,96 @@
+package i18n
+
+import (
+	"regexp"
+	"testing"
+)
+
+var verb = regexp.MustCompile(`%[a-z]`)
+
+func TestCatalogues(t *testing.T) {
+	for _, l := range Languages {
+		for _, other := range Languages {
+			for key := range catalogues[other] {
+				if _, ok := catalogues[l][key]; !ok {
+					t.Errorf("expected %v to have key %v", l, key)
+				}
+			}
+		}
+	}
+
+	t.Run("should use the same format verbs in every language", func(t *testing.T) {
+		for key, s := range catalogues[English] {
+			want := verb.FindAllString(s, -1)
+			for _, l := range Languages {
+				got := verb.FindAllString(catalogues[l][key], -1)
+				if len(got) != len(want) {
+					t.Errorf("expected %v %v to have verbs %v but got %v", l, key, want, got)
+					continue
+				}
+				for i := range got {
+					if got[i] != want[i] {
+						t.Errorf("expected %v %v to have verbs %v but got %v", l, key, want, got)
+					}
+				}
+			}
+		}
+	})
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   Lang
+	}{
+		{"", Default},
+		{"th", Thai},
+		{"en-US", English},
+		{"th-TH,th;q=0.9,en;q=0.8", Thai},
+		{"th;q=0.5,en;q=0.8", English},
+		{"fr,en;q=0.1", English},
+		{"fr,de", Default},
+		{"th;q=0,en", English},
+		{"*", Default},
+	}
+
+	for _, tt := range tests {
+		if got := Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	t.Run("should fall back to the default language", func(t *testing.T) {
+		catalogues[Default]["TEST_ONLY_DEFAULT"] = "only in default"
+		defer delete(catalogues[Default], "TEST_ONLY_DEFAULT")
+
+		for _, l := range Languages {
+			if got := l.T("TEST_ONLY_DEFAULT"); got != "only in default" {
+				t.Errorf("expected %v to fall back to default but got %v", l, got)
+			}
+		}
+	})
+
+	t.Run("should return the key if no language has it", func(t *testing.T) {
+		if got := Thai.T("MISSING_KEY"); got != "MISSING_KEY" {
+			t.Errorf("expected MISSING_KEY but got %v", got)
+		}
+	})
+
+	t.Run("should format arguments", func(t *testing.T) {
+		if got := English.T("TAX_CSV_ROW", 2); got != "row 2: " {
+			t.Errorf("expected row 2 but got %v", got)
+		}
+	})
+}
+
+func TestParse(t *testing.T) {
+	if l, err := Parse("TH"); err != nil || l != Thai {
+		t.Errorf("expected th but got %v %v", l, err)
+	}
+
+	if _, err := Parse("fr"); err == nil || err.Error() != ErrUnsupportedLanguage {
+		t.Errorf("expected %v but got %v", ErrUnsupportedLanguage, err)
+	}
+}
@@ -0,0 +1,110 @@
+package i18n
+
+var th = map[string]string{
+	"REQUEST_BODY_INVALID":       "ข้อมูลที่ส่งมาไม่ใช่ JSON ที่ถูกต้อง",
+	"REQUEST_BODY_TYPE_MISMATCH": "%s ต้องเป็นชนิด %s",
+	"VALIDATION_FAILED":          "คำขอมีข้อผิดพลาดในการตรวจสอบ %d รายการ",
+	"INTERNAL_ERROR":             "เกิดข้อผิดพลาดภายในระบบ",
+	"RATE_LIMITED":               "มีคำขอมากเกินไป กรุณาลองใหม่ในอีก %d วินาที",
+	"REQUEST_BODY_TRAILING_DATA": "ข้อมูลที่ส่งมาต้องมีค่า JSON เพียงค่าเดียว",
+	"REQUEST_FIELD_DUPLICATE":    "%s ต้องไม่ปรากฏซ้ำ",
+	"REQUEST_FIELD_TYPE":         "%s ต้องเป็นชนิด %s",
+	"REQUEST_FIELD_UNKNOWN":      "%s ไม่ใช่ฟิลด์ที่รู้จัก",
+	"REQUEST_NUMBER_NOT_FINITE":  "%s ต้องเป็นตัวเลขที่มีค่าจำกัด",
+
+	"ADMIN_EXISTS":             "มีผู้ดูแลระบบนี้อยู่แล้ว",
+	"ADMIN_NOT_FOUND":          "ไม่พบผู้ดูแลระบบ",
+	"ADMIN_PASSWORD_INVALID":   "รหัสผ่านต้องมีความยาว 8 ถึง 72 ตัวอักษร",
+	"ADMIN_ROLE_INVALID":       "บทบาทต้องเป็น viewer, editor, approver, auditor หรือ super-admin",
+	"ADMIN_USERNAME_INVALID":   "ชื่อผู้ใช้ต้องมีความยาว 3 ถึง 100 ตัวอักษร",
+	"AUTH_CREDENTIALS_INVALID": "ชื่อผู้ใช้หรือรหัสผ่านไม่ถูกต้อง",
+	"AUTH_PERMISSION_DENIED":   "บทบาท %q ไม่มีสิทธิ์ %q",
+	"AUTH_TOKEN_INVALID":       "โทเคนไม่ถูกต้องหรือหมดอายุแล้ว",
+	"AUTH_TOKEN_MISSING":       "ไม่พบ authorization header หรือรูปแบบไม่ถูกต้อง",
+
+	"API_KEY_ID_INVALID":     "รหัส api key ต้องเป็นจำนวนเต็มบวก",
+	"API_KEY_INVALID":        "api key ไม่ถูกต้องหรือถูกเพิกถอนแล้ว",
+	"API_KEY_MISSING":        "ไม่พบ api key",
+	"API_KEY_NAME_INVALID":   "ชื่อต้องมีความยาว 1 ถึง 100 ตัวอักษร",
+	"API_KEY_NOT_FOUND":      "ไม่พบ api key",
+	"API_KEY_SCOPES_EMPTY":   "ต้องระบุ scope อย่างน้อยหนึ่งรายการ",
+	"API_KEY_SCOPE_INVALID":  "scope ต้องเป็น calc หรือ batch เท่านั้น",
+	"API_KEY_SCOPE_REQUIRED": "api key ไม่มี scope %q",
+
+	"GRAPHQL_QUERY_EMPTY":       "query ต้องไม่เป็นค่าว่าง",
+	"GRAPHQL_QUERY_TOO_COMPLEX": "query ต้องซ้อนกันไม่เกิน %d ระดับ เลือกฟิลด์ไม่เกิน %d ฟิลด์ และใช้ alias ไม่เกิน %d รายการ",
+	"GRAPHQL_SCENARIOS_INVALID": "scenarios ต้องมี 1 ถึง %d รายการ",
+
+	"IDEMPOTENCY_KEY_INVALID":         "idempotency key ต้องมีความยาว 1 ถึง 255 ตัวอักษรที่พิมพ์ได้",
+	"IDEMPOTENCY_KEY_REUSED":          "idempotency key นี้ถูกใช้กับคำขออื่นแล้ว",
+	"IDEMPOTENCY_REQUEST_IN_PROGRESS": "คำขอที่ใช้ idempotency key นี้กำลังดำเนินการอยู่",
+
+	"WEBHOOK_EVENT_INVALID": "events ต้องเป็น deduction.proposed, deduction.approved หรือ deduction.rejected",
+	"WEBHOOK_ID_INVALID":    "webhook id ต้องเป็นจำนวนเต็มบวก",
+	"WEBHOOK_NOT_FOUND":     "ไม่พบ webhook",
+	"WEBHOOK_URL_INVALID":   "url ต้องเป็น http หรือ https url แบบเต็มและยาวไม่เกิน 2048 ตัวอักษร",
+
+	"AUDIT_AFTER_INVALID": "after ต้องเป็นรหัสรายการที่เป็นจำนวนเต็มบวก",
+	"AUDIT_FROM_INVALID":  "from ต้องเป็นวันที่ (YYYY-MM-DD) หรือเวลาตามรูปแบบ RFC 3339",
+	"AUDIT_LIMIT_INVALID": "limit ต้องอยู่ระหว่าง 1 ถึง 1000",
+	"AUDIT_TO_INVALID":    "to ต้องเป็นวันที่ (YYYY-MM-DD) หรือเวลาตามรูปแบบ RFC 3339",
+	"AUDIT_WRITE_FAILED":  "ไม่สามารถบันทึกการเปลี่ยนแปลงลงในบันทึกการตรวจสอบได้",
+
+	"ALLOWANCE_AMOUNT_NEGATIVE":             "จำนวนเงินลดหย่อนต้องมากกว่าหรือเท่ากับ 0",
+	"ALLOWANCE_EFFECTIVE_FROM_INVALID":      "วันที่มีผลต้องอยู่ในรูปแบบ YYYY-MM-DD และต้องไม่ก่อนวันเริ่มต้นของปีภาษีที่ยื่นแบบ",
+	"ALLOWANCE_HISTORY_TYPE_INVALID":        "ประเภทต้องเป็น personal, donation, k-receipt, rmf หรือ ssf",
+	"ALLOWANCE_K_RECEIPT_TOO_HIGH":          "จำนวนเงินต้องน้อยกว่า 100,000 บาท",
+	"ALLOWANCE_K_RECEIPT_TOO_LOW":           "จำนวนเงินต้องมากกว่า 0 บาท",
+	"ALLOWANCE_NOT_MANAGED":                 "ผู้ดูแลระบบไม่สามารถเปลี่ยนแปลงค่าลดหย่อนประเภทนี้ได้",
+	"ALLOWANCE_PERSONAL_TOO_HIGH":           "จำนวนเงินต้องน้อยกว่า 100,000 บาท",
+	"ALLOWANCE_PERSONAL_TOO_LOW":            "จำนวนเงินต้องมากกว่า 10,000 บาท",
+	"ALLOWANCE_RECOMMENDATION_TYPE_INVALID": "ค่าลดหย่อนต้องเป็น donation, k-receipt, rmf หรือ ssf เท่านั้น",
+	"ALLOWANCE_TYPE_INVALID":                "ค่าลดหย่อนต้องเป็น donation หรือ k-receipt เท่านั้น",
+
+	"PROPOSAL_ID_INVALID":     "รหัสคำขอต้องเป็นจำนวนเต็มบวก",
+	"PROPOSAL_NOT_FOUND":      "ไม่พบคำขอ",
+	"PROPOSAL_NOT_PENDING":    "คำขอนี้ได้รับการพิจารณาแล้ว",
+	"PROPOSAL_SELF_REVIEW":    "คำขอต้องได้รับการพิจารณาโดยผู้ดูแลระบบคนอื่น",
+	"PROPOSAL_STATUS_INVALID": "สถานะต้องเป็น pending, approved หรือ rejected",
+
+	"TAX_BATCH_EMPTY":                  "ต้องมีรายการคำนวณอย่างน้อยหนึ่งรายการ",
+	"TAX_BATCH_REFERENCE_INVALID":      "รหัสอ้างอิงต้องยาวไม่เกิน 100 ตัวอักษร",
+	"TAX_BATCH_TOO_LARGE":              "รายการคำนวณต้องมีไม่เกิน %d รายการ",
+	"TAX_CSV_FILE_MISSING":             "ต้องอัปโหลดไฟล์ในฟิลด์ file ของ multipart form",
+	"TAX_CSV_HEADER_INVALID":           "หัวตาราง CSV ไม่ถูกต้อง ต้องเป็น totalIncome, wht, donation",
+	"TAX_CSV_INVALID":                  "ไฟล์ต้องเป็น CSV ที่ถูกต้องและมีสามค่าในทุกแถว",
+	"TAX_CSV_ROW":                      "แถวที่ %d: ",
+	"TAX_CSV_VALUE_EMPTY":              "ข้อมูลใน CSV ต้องไม่เป็นค่าว่าง",
+	"TAX_CSV_VALUE_INVALID":            "ข้อมูลใน CSV ต้องเป็นตัวเลข",
+	"TAX_CURVE_RANGE_INVALID":          "from ต้องมากกว่าหรือเท่ากับ 0 และน้อยกว่าหรือเท่ากับ to",
+	"TAX_CURVE_STEP_INVALID":           "step ต้องมากกว่า 0",
+	"TAX_CURVE_TOO_MANY_POINTS":        "กราฟต้องมีไม่เกิน 1000 จุด",
+	"TAX_DUE_DATE_INVALID":             "วันครบกำหนดต้องอยู่ในรูปแบบ YYYY-MM-DD",
+	"TAX_INCOMES_EMPTY":                "ต้องมีรายการเงินได้อย่างน้อยหนึ่งรายการ",
+	"TAX_INCOME_AMOUNT_NEGATIVE":       "จำนวนเงินได้ต้องมากกว่าหรือเท่ากับ 0",
+	"TAX_INCOME_CATEGORY_INVALID":      "ประเภทเงินได้ต้องเป็น salary, service, royalty, investment, rental, professional, contract หรือ business",
+	"TAX_FILING_DATE_INVALID":          "วันที่ยื่นแบบต้องอยู่ในรูปแบบ YYYY-MM-DD",
+	"TAX_INSTALLMENTS_BELOW_THRESHOLD": "ผ่อนชำระได้เฉพาะเมื่อภาษีที่ต้องชำระเกิน 3,000 บาท",
+	"TAX_ROW_QUOTA_EXCEEDED":           "เกินโควตาจำนวนรายการต่อวัน",
+	"TAX_RUN_EXISTS":                   "batch run ที่ใช้ id นี้เริ่มทำงานไปแล้ว",
+	"TAX_RUN_ID_INVALID":               "run id ต้องมี 1 ถึง 64 ตัวอักษร ประกอบด้วยตัวอักษร ตัวเลข '-' หรือ '_'",
+	"TAX_RUN_KEY_REQUIRED":             "การติดตาม batch run ต้องใช้ API key",
+	"TAX_RUN_LAST_EVENT_ID_INVALID":    "Last-Event-ID ต้องเป็นหมายเลข event ที่ไม่ติดลบ",
+	"TAX_RUN_LIMIT_EXCEEDED":           "มี batch run ที่รอดำเนินการมากเกินไป กรุณารอให้ batch run ก่อนหน้าเสร็จสิ้น",
+	"TAX_TOTAL_INCOME_NEGATIVE":        "เงินได้ทั้งหมดต้องมากกว่าหรือเท่ากับ 0",
+	"TAX_WHT_EXCEEDS_INCOME":           "ภาษีหัก ณ ที่จ่ายต้องมากกว่าหรือเท่ากับ 0 และน้อยกว่าเงินได้ทั้งหมด",
+	"TAX_WHT_NEGATIVE":                 "ภาษีหัก ณ ที่จ่ายต้องมากกว่าหรือเท่ากับ 0 และน้อยกว่าเงินได้ทั้งหมด",
+	"TAX_YEAR_INVALID":                 "ปีภาษีต้องเป็นปีพุทธศักราชระหว่าง 2500 ถึง 2999",
+
+	"TAX_BRACKET_1": "0-150,000",
+	"TAX_BRACKET_2": "150,001-500,000",
+	"TAX_BRACKET_3": "500,001-1,000,000",
+	"TAX_BRACKET_4": "1,000,001-2,000,000",
+	"TAX_BRACKET_5": "2,000,001 ขึ้นไป",
+
+	"ALLOWANCE_DONATION":  "เงินบริจาค",
+	"ALLOWANCE_K_RECEIPT": "ค่าซื้อสินค้าหรือบริการ (Easy e-Receipt)",
+	"ALLOWANCE_PERSONAL":  "ค่าลดหย่อนส่วนตัว",
+	"ALLOWANCE_RMF":       "ค่าซื้อหน่วยลงทุนในกองทุนรวมเพื่อการเลี้ยงชีพ (RMF)",
+	"ALLOWANCE_SSF":       "ค่าซื้อหน่วยลงทุนในกองทุนรวมเพื่อการออม (SSF)",
+}
@@ -0,0 +1,117 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+const (
+	HeaderIdempotencyKey = "Idempotency-Key"
+	HeaderReplayed       = "Idempotent-Replayed"
+)
+
+const (
+	DefaultRetention = 24 * time.Hour
+	maxKeyLength     = 255
+)
+
+// A reservation expires unless the request holding it extends it every
+// heartbeat, so keys of a crashed instance free up while long uploads keep
+// theirs until they complete.
+const (
+	reservation = 5 * time.Minute
+	heartbeat   = reservation / 2
+)
+
+const (
+	ErrInvalidKey = "idempotency key must be between 1 and 255 printable characters"
+	ErrKeyReused  = "idempotency key was already used for a different request"
+	ErrInProgress = "a request with this idempotency key is still in progress"
+)
+
+var (
+	errInvalidKey = problem.New("IDEMPOTENCY_KEY_INVALID", "", ErrInvalidKey)
+	errKeyReused  = problem.New("IDEMPOTENCY_KEY_REUSED", "", ErrKeyReused)
+	errInProgress = problem.New("IDEMPOTENCY_REQUEST_IN_PROGRESS", "", ErrInProgress)
+)
+
+type Record struct {
+	Owner       string
+	Key         string
+	Fingerprint string
+	Status      int
+	ContentType string
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+func (r Record) Completed() bool {
+	return r.Status != 0
+}
+
+type Config struct {
+	Retention time.Duration
+	Owner     func(echo.Context) string
+}
+
+func ValidateKey(key string) error {
+	if key == "" || len(key) > maxKeyLength {
+		return errInvalidKey
+	}
+	for _, r := range key {
+		if r < 0x20 || r > 0x7e {
+			return errInvalidKey
+		}
+	}
+	return nil
+}
+
+func Fingerprint(req *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, req.Method+" "+req.URL.Path+"\n")
+
+	mt, params, _ := mime.ParseMediaType(req.Header.Get(echo.HeaderContentType))
+	if mt != echo.MIMEMultipartForm || !writeParts(h, body, params["boundary"]) {
+		h.Write(body)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeParts(w io.Writer, body []byte, boundary string) bool {
+	if boundary == "" {
+		return false
+	}
+
+	var b bytes.Buffer
+	mr := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return false
+		}
+
+		content, err := io.ReadAll(p)
+		if err != nil {
+			return false
+		}
+		fmt.Fprintf(&b, "%q %q %d\n", p.FormName(), p.FileName(), len(content))
+		b.Write(content)
+	}
+
+	w.Write(b.Bytes())
+	return true
+}
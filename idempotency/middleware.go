@@ -0,0 +1,157 @@
+package idempotency
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+type Storer interface {
+	ReserveKey(r Record) (Record, bool, error)
+	ExtendKey(owner, key string, expiresAt time.Time) error
+	CompleteKey(r Record) error
+	ReleaseKey(owner, key string) error
+}
+
+type Handler struct {
+	store     Storer
+	cfg       Config
+	now       func() time.Time
+	heartbeat time.Duration
+}
+
+type recorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func New(store Storer, cfg Config) *Handler {
+	if cfg.Retention <= 0 {
+		cfg.Retention = DefaultRetention
+	}
+	return &Handler{store: store, cfg: cfg, now: time.Now, heartbeat: heartbeat}
+}
+
+func (h *Handler) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		key := c.Request().Header.Get(HeaderIdempotencyKey)
+		if key == "" {
+			return next(c)
+		}
+
+		if err := ValidateKey(key); err != nil {
+			return problem.Respond(c, http.StatusBadRequest, err)
+		}
+
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return err
+		}
+		c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+		r := Record{
+			Owner:       h.owner(c),
+			Key:         key,
+			Fingerprint: Fingerprint(c.Request(), body),
+			ExpiresAt:   h.now().Add(reservation),
+		}
+
+		existing, reserved, err := h.store.ReserveKey(r)
+		if err != nil {
+			return problem.Respond(c, http.StatusInternalServerError, err)
+		}
+
+		if !reserved {
+			return replay(c, r, existing)
+		}
+
+		rec := &recorder{ResponseWriter: c.Response().Writer}
+		c.Response().Writer = rec
+
+		stop := h.hold(c, r)
+		err = next(c)
+		stop()
+
+		res := c.Response()
+		res.Writer = rec.ResponseWriter
+
+		if err != nil || !res.Committed || !final(res.Status) {
+			if rerr := h.store.ReleaseKey(r.Owner, r.Key); rerr != nil {
+				c.Logger().Error(rerr)
+			}
+			return err
+		}
+
+		r.Status = res.Status
+		r.ContentType = res.Header().Get(echo.HeaderContentType)
+		r.Body = rec.body.Bytes()
+		r.ExpiresAt = h.now().Add(h.cfg.Retention)
+
+		if err := h.store.CompleteKey(r); err != nil {
+			c.Logger().Error(err)
+		}
+
+		return nil
+	}
+}
+
+// hold extends the reservation of r until the returned function is called.
+func (h *Handler) hold(c echo.Context, r Record) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		t := time.NewTicker(h.heartbeat)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				if err := h.store.ExtendKey(r.Owner, r.Key, h.now().Add(reservation)); err != nil {
+					c.Logger().Error(err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func (h *Handler) owner(c echo.Context) string {
+	if h.cfg.Owner == nil {
+		return ""
+	}
+	return h.cfg.Owner(c)
+}
+
+func replay(c echo.Context, r, existing Record) error {
+	if existing.Fingerprint != r.Fingerprint {
+		return problem.Respond(c, http.StatusConflict, errKeyReused)
+	}
+
+	if !existing.Completed() {
+		return problem.Respond(c, http.StatusConflict, errInProgress)
+	}
+
+	c.Response().Header().Set(HeaderReplayed, strconv.FormatBool(true))
+	return c.Blob(existing.Status, existing.ContentType, existing.Body)
+}
+
+func final(status int) bool {
+	return status < http.StatusInternalServerError && status != http.StatusTooManyRequests
+}
@@ -0,0 +1,275 @@
+package idempotency
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+type stub struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	records map[string]Record
+}
+
+func newStub() *stub {
+	return &stub{now: time.Now, records: map[string]Record{}}
+}
+
+func (s *stub) ReserveKey(r Record) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.records[r.Owner+"/"+r.Key]
+	if ok && existing.ExpiresAt.After(s.now()) {
+		return existing, false, nil
+	}
+	s.records[r.Owner+"/"+r.Key] = r
+	return r, true, nil
+}
+
+func (s *stub) ExtendKey(owner, key string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r, ok := s.records[owner+"/"+key]; ok && !r.Completed() {
+		r.ExpiresAt = expiresAt
+		s.records[owner+"/"+key] = r
+	}
+	return nil
+}
+
+func (s *stub) held(owner, key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[owner+"/"+key]
+	return ok && r.ExpiresAt.After(s.now())
+}
+
+func (s *stub) CompleteKey(r Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[r.Owner+"/"+r.Key] = r
+	return nil
+}
+
+func (s *stub) ReleaseKey(owner, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, owner+"/"+key)
+	return nil
+}
+
+type counter struct {
+	calls  int
+	status int
+}
+
+func (ct *counter) handle(c echo.Context) error {
+	ct.calls++
+	if ct.status == 0 {
+		ct.status = http.StatusCreated
+	}
+	return c.JSON(ct.status, map[string]int{"call": ct.calls})
+}
+
+func send(h *Handler, next echo.HandlerFunc, key, contentType, body string) *httptest.ResponseRecorder {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/tax/calculations/batch", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, contentType)
+	if key != "" {
+		req.Header.Set(HeaderIdempotencyKey, key)
+	}
+	rec := httptest.NewRecorder()
+	h.Middleware(next)(e.NewContext(req, rec))
+	return rec
+}
+
+func code(rec *httptest.ResponseRecorder) string {
+	var p problem.Problem
+	json.Unmarshal(rec.Body.Bytes(), &p)
+	return p.Code
+}
+
+func form(t *testing.T, csv string) (string, string) {
+	t.Helper()
+
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+	f, _ := w.CreateFormFile("file", "file.csv")
+	f.Write([]byte(csv))
+	w.Close()
+	return w.FormDataContentType(), b.String()
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Run("should pass requests without a key through", func(t *testing.T) {
+		ct := &counter{}
+		h := New(newStub(), Config{})
+
+		send(h, ct.handle, "", echo.MIMEApplicationJSON, `{}`)
+		send(h, ct.handle, "", echo.MIMEApplicationJSON, `{}`)
+
+		if ct.calls != 2 {
+			t.Errorf("expected 2 calls but got %v", ct.calls)
+		}
+	})
+
+	t.Run("should replay the stored response for a repeated request", func(t *testing.T) {
+		ct := &counter{}
+		h := New(newStub(), Config{})
+
+		first := send(h, ct.handle, "payroll-1", echo.MIMEApplicationJSON, `{"items": []}`)
+		second := send(h, ct.handle, "payroll-1", echo.MIMEApplicationJSON, `{"items": []}`)
+
+		if ct.calls != 1 {
+			t.Errorf("expected 1 call but got %v", ct.calls)
+		}
+
+		if second.Code != first.Code || second.Body.String() != first.Body.String() || second.Header().Get(echo.HeaderContentType) != first.Header().Get(echo.HeaderContentType) {
+			t.Errorf("expected %v %v but got %v %v", first.Code, first.Body.String(), second.Code, second.Body.String())
+		}
+
+		if first.Header().Get(HeaderReplayed) != "" || second.Header().Get(HeaderReplayed) != "true" {
+			t.Errorf("expected only the replay to be marked")
+		}
+	})
+
+	t.Run("should return 409 if the key is reused with a different body", func(t *testing.T) {
+		ct := &counter{}
+		h := New(newStub(), Config{})
+
+		send(h, ct.handle, "payroll-1", echo.MIMEApplicationJSON, `{"items": [1]}`)
+		rec := send(h, ct.handle, "payroll-1", echo.MIMEApplicationJSON, `{"items": [2]}`)
+
+		if rec.Code != http.StatusConflict || code(rec) != "IDEMPOTENCY_KEY_REUSED" || ct.calls != 1 {
+			t.Errorf("expected IDEMPOTENCY_KEY_REUSED but got %v %v", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("should return 409 while the first request is in progress", func(t *testing.T) {
+		h := New(newStub(), Config{})
+
+		var inner *httptest.ResponseRecorder
+		send(h, func(c echo.Context) error {
+			inner = send(h, (&counter{}).handle, "payroll-1", echo.MIMEApplicationJSON, `{}`)
+			return c.NoContent(http.StatusNoContent)
+		}, "payroll-1", echo.MIMEApplicationJSON, `{}`)
+
+		if inner.Code != http.StatusConflict || code(inner) != "IDEMPOTENCY_REQUEST_IN_PROGRESS" {
+			t.Errorf("expected IDEMPOTENCY_REQUEST_IN_PROGRESS but got %v %v", inner.Code, inner.Body.String())
+		}
+	})
+
+	t.Run("should keep the reservation while a long request runs", func(t *testing.T) {
+		var offset atomic.Int64
+		now := func() time.Time { return time.Now().Add(time.Duration(offset.Load())) }
+		s := newStub()
+		s.now = now
+		h := New(s, Config{})
+		h.now = now
+		h.heartbeat = time.Millisecond
+		ct := &counter{}
+
+		var inner *httptest.ResponseRecorder
+		send(h, func(c echo.Context) error {
+			offset.Store(int64(reservation + time.Minute))
+			for deadline := time.Now().Add(time.Second); !s.held("", "payroll-1") && time.Now().Before(deadline); {
+				time.Sleep(time.Millisecond)
+			}
+			inner = send(h, ct.handle, "payroll-1", echo.MIMEApplicationJSON, `{}`)
+			return c.NoContent(http.StatusNoContent)
+		}, "payroll-1", echo.MIMEApplicationJSON, `{}`)
+
+		if inner.Code != http.StatusConflict || ct.calls != 0 {
+			t.Errorf("expected the retry to wait for the first request but got %v after %v calls", inner.Code, ct.calls)
+		}
+	})
+
+	t.Run("should not store server errors", func(t *testing.T) {
+		ct := &counter{status: http.StatusInternalServerError}
+		h := New(newStub(), Config{})
+
+		send(h, ct.handle, "payroll-1", echo.MIMEApplicationJSON, `{}`)
+		send(h, ct.handle, "payroll-1", echo.MIMEApplicationJSON, `{}`)
+
+		if ct.calls != 2 {
+			t.Errorf("expected the retry to run again but got %v calls", ct.calls)
+		}
+	})
+
+	t.Run("should return 400 if the key is invalid", func(t *testing.T) {
+		rec := send(New(newStub(), Config{}), (&counter{}).handle, strings.Repeat("k", 256), echo.MIMEApplicationJSON, `{}`)
+
+		if rec.Code != http.StatusBadRequest || code(rec) != "IDEMPOTENCY_KEY_INVALID" {
+			t.Errorf("expected IDEMPOTENCY_KEY_INVALID but got %v %v", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("should replay an upload sent with a new multipart boundary", func(t *testing.T) {
+		ct := &counter{}
+		h := New(newStub(), Config{})
+
+		upload := func(csv string) *httptest.ResponseRecorder {
+			contentType, body := form(t, csv)
+			return send(h, ct.handle, "payroll-1", contentType, body)
+		}
+
+		upload("totalIncome,wht,donation\n500000.0,0.0,0.0\n")
+		rec := upload("totalIncome,wht,donation\n500000.0,0.0,0.0\n")
+		changed := upload("totalIncome,wht,donation\n600000.0,0.0,0.0\n")
+
+		if ct.calls != 1 || rec.Header().Get(HeaderReplayed) != "true" {
+			t.Errorf("expected the upload to be replayed but got %v calls", ct.calls)
+		}
+
+		if changed.Code != http.StatusConflict {
+			t.Errorf("expected status code %v but got %v", http.StatusConflict, changed.Code)
+		}
+	})
+
+	t.Run("should scope keys to the owner", func(t *testing.T) {
+		ct := &counter{}
+		s := newStub()
+		owner := "key:1"
+		h := New(s, Config{Owner: func(echo.Context) string { return owner }})
+
+		send(h, ct.handle, "payroll-1", echo.MIMEApplicationJSON, `{}`)
+		owner = "key:2"
+		send(h, ct.handle, "payroll-1", echo.MIMEApplicationJSON, `{}`)
+
+		if ct.calls != 2 {
+			t.Errorf("expected 2 calls but got %v", ct.calls)
+		}
+	})
+
+	t.Run("should forget keys after the retention window", func(t *testing.T) {
+		ct := &counter{}
+		now := time.Now()
+		s := newStub()
+		s.now = func() time.Time { return now }
+		h := New(s, Config{Retention: time.Hour})
+		h.now = s.now
+
+		send(h, ct.handle, "payroll-1", echo.MIMEApplicationJSON, `{}`)
+		now = now.Add(time.Hour + time.Second)
+		send(h, ct.handle, "payroll-1", echo.MIMEApplicationJSON, `{}`)
+
+		if ct.calls != 2 {
+			t.Errorf("expected 2 calls but got %v", ct.calls)
+		}
+	})
+}
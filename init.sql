@@ -1,10 +1,135 @@
 CREATE TABLE IF NOT EXISTS allowances (
     id SERIAL PRIMARY KEY,
-    type VARCHAR(25) NOT NULL UNIQUE,
-    max_amount FLOAT NOT NULL
+    type VARCHAR(25) NOT NULL UNIQUE
 );
 
-INSERT INTO allowances (type, max_amount) VALUES
-('personal', 60000.0),
-('donation', 100000.0),
-('k-receipt', 50000.0);
\ No newline at end of file
+CREATE TABLE IF NOT EXISTS allowance_caps (
+    id SERIAL PRIMARY KEY,
+    type VARCHAR(25) NOT NULL REFERENCES allowances (type),
+    max_amount FLOAT NOT NULL,
+    effective_from TIMESTAMPTZ NOT NULL,
+    changed_by TEXT NOT NULL,
+    approved_by TEXT,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS allowance_caps_type_effective_from_idx ON allowance_caps (type, effective_from DESC);
+
+CREATE TABLE IF NOT EXISTS allowance_proposals (
+    id SERIAL PRIMARY KEY,
+    type VARCHAR(25) NOT NULL REFERENCES allowances (type),
+    max_amount FLOAT NOT NULL,
+    effective_from TIMESTAMPTZ,
+    reason TEXT NOT NULL DEFAULT '',
+    status VARCHAR(10) NOT NULL DEFAULT 'pending',
+    proposed_by TEXT NOT NULL,
+    reviewed_by TEXT,
+    reviewed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS allowance_proposals_status_idx ON allowance_proposals (status);
+
+INSERT INTO allowances (type) VALUES
+('personal'),
+('donation'),
+('k-receipt'),
+('rmf'),
+('ssf');
+
+INSERT INTO allowance_caps (type, max_amount, effective_from, changed_by, reason) VALUES
+('personal', 60000.0, '2024-01-01T00:00:00+07:00', 'system', 'initial cap'),
+('donation', 100000.0, '2024-01-01T00:00:00+07:00', 'system', 'initial cap'),
+('k-receipt', 50000.0, '2024-01-01T00:00:00+07:00', 'system', 'initial cap'),
+('rmf', 500000.0, '2024-01-01T00:00:00+07:00', 'system', 'initial cap'),
+('ssf', 200000.0, '2024-01-01T00:00:00+07:00', 'system', 'initial cap');
+
+CREATE TABLE IF NOT EXISTS admins (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(100) NOT NULL UNIQUE,
+    role VARCHAR(20) NOT NULL DEFAULT 'viewer' CHECK (role IN ('viewer', 'editor', 'approver', 'auditor', 'super-admin')),
+    password_hash VARCHAR(100) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    id VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(20) NOT NULL,
+    scopes TEXT[] NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    created_by TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    at TIMESTAMPTZ NOT NULL,
+    actor TEXT NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    outcome VARCHAR(10) NOT NULL,
+    ip TEXT NOT NULL,
+    request_id TEXT NOT NULL,
+    target VARCHAR(50) NOT NULL,
+    before_value TEXT NOT NULL,
+    after_value TEXT NOT NULL,
+    detail TEXT NOT NULL,
+    prev_hash VARCHAR(64) NOT NULL,
+    hash VARCHAR(64) NOT NULL UNIQUE
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_actor_at_idx ON audit_log (actor, at);
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER audit_log_append_only
+BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    owner VARCHAR(120) NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    fingerprint CHAR(64) NOT NULL,
+    status INTEGER NOT NULL DEFAULT 0,
+    content_type VARCHAR(100) NOT NULL DEFAULT '',
+    body BYTEA NOT NULL DEFAULT '',
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (owner, key)
+);
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id SERIAL PRIMARY KEY,
+    url VARCHAR(2048) NOT NULL,
+    events TEXT[] NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    created_by TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id INTEGER NOT NULL REFERENCES webhook_subscriptions (id),
+    event_id CHAR(32) NOT NULL,
+    event VARCHAR(50) NOT NULL,
+    attempt INTEGER NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    succeeded BOOLEAN NOT NULL,
+    duration_ms BIGINT NOT NULL,
+    at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_subscription_idx ON webhook_deliveries (subscription_id, id DESC);
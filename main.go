@@ -3,16 +3,31 @@ package main
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/varissara-wo/assessment-tax/allowance"
+	"github.com/varissara-wo/assessment-tax/apikey"
+	"github.com/varissara-wo/assessment-tax/audit"
+	"github.com/varissara-wo/assessment-tax/auth"
+	"github.com/varissara-wo/assessment-tax/binding"
+	"github.com/varissara-wo/assessment-tax/gql"
+	"github.com/varissara-wo/assessment-tax/i18n"
+	"github.com/varissara-wo/assessment-tax/idempotency"
+	"github.com/varissara-wo/assessment-tax/openapi"
 	"github.com/varissara-wo/assessment-tax/postgres"
+	"github.com/varissara-wo/assessment-tax/problem"
+	"github.com/varissara-wo/assessment-tax/ratelimit"
 	"github.com/varissara-wo/assessment-tax/tax"
+	"github.com/varissara-wo/assessment-tax/taxgrpc"
+	"github.com/varissara-wo/assessment-tax/webhook"
+	"google.golang.org/grpc"
 )
 
 func main() {
@@ -21,26 +36,208 @@ func main() {
 		panic(err)
 	}
 
+	var font []byte
+	if path := os.Getenv("PND91_FONT_PATH"); path != "" {
+		font, err = os.ReadFile(path)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	i18n.Default, err = i18n.Parse(getenv("DEFAULT_LANGUAGE", "th"))
+	if err != nil {
+		panic(err)
+	}
+
+	bindingMode, err := binding.ParseMode(getenv("JSON_BINDING", string(binding.Lenient)))
+	if err != nil {
+		panic(err)
+	}
+
+	bindingModeV2, err := binding.ParseMode(getenv("JSON_BINDING_V2", string(binding.Strict)))
+	if err != nil {
+		panic(err)
+	}
+
+	anonymousScopes, err := apikey.ParseScopes(getenv("API_ANONYMOUS_SCOPES", "calc,batch"))
+	if err != nil {
+		panic(err)
+	}
+
+	calcLimit := limiter("RATE_LIMIT_CALC", "60/1m")
+	batchLimit := limiter("RATE_LIMIT_BATCH", "10/1m")
+	adminWriteLimit := limiter("RATE_LIMIT_ADMIN_WRITE", "30/1m", ratelimit.WithSkipper(ratelimit.SafeMethods))
+
+	dailyRows, err := strconv.Atoi(getenv("BATCH_DAILY_ROWS", "10000"))
+	if err != nil {
+		panic(err)
+	}
+	rowQuota := ratelimit.NewQuota(dailyRows)
+	uploadLimit := middleware.BodyLimit(getenv("BATCH_MAX_UPLOAD_SIZE", "2M"))
+
+	batchItems, err := strconv.Atoi(getenv("BATCH_MAX_ITEMS", strconv.Itoa(tax.DefaultBatchLimit)))
+	if err != nil {
+		panic(err)
+	}
+
+	batchWorkers, err := strconv.Atoi(getenv("BATCH_WORKERS", "1"))
+	if err != nil {
+		panic(err)
+	}
+
+	pendingRuns, err := strconv.Atoi(getenv("BATCH_MAX_PENDING_RUNS", strconv.Itoa(tax.DefaultMaxPendingRuns)))
+	if err != nil {
+		panic(err)
+	}
+
+	retention := idempotency.DefaultRetention
+	if v := os.Getenv("IDEMPOTENCY_RETENTION"); v != "" {
+		retention, err = time.ParseDuration(v)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	webhookAttempts, err := strconv.Atoi(getenv("WEBHOOK_MAX_ATTEMPTS", strconv.Itoa(webhook.DefaultMaxAttempts)))
+	if err != nil {
+		panic(err)
+	}
+
+	webhookBackoff, err := time.ParseDuration(getenv("WEBHOOK_BACKOFF", webhook.DefaultBackoff.String()))
+	if err != nil {
+		panic(err)
+	}
+
+	ipExtractor, err := ratelimit.IPExtractor(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		panic(err)
+	}
+
 	e := echo.New()
-	th := tax.New(p)
+	e.IPExtractor = ipExtractor
+	e.HTTPErrorHandler = problem.HTTPErrorHandler
+	e.Binder = binding.New(bindingMode)
+	e.Use(middleware.Recover())
+	e.Use(middleware.RequestID())
+	runs := tax.NewRuns(tax.RunConfig{MaxPending: pendingRuns, Owner: apikey.Owner})
+	th := tax.New(p,
+		tax.WithFormFont(font),
+		tax.WithRowQuota(rowQuota),
+		tax.WithBatchLimit(batchItems),
+		tax.WithBatchWorkers(batchWorkers),
+		tax.WithRuns(runs),
+	)
+	kh := apikey.New(p, apikey.Config{Anonymous: anonymousScopes})
+	idem := idempotency.New(p, idempotency.Config{Retention: retention, Owner: ratelimit.ClientKey})
 	e.GET("/", func(c echo.Context) error {
 		return c.String(http.StatusOK, "Hello, Go Bootcamp!")
 	})
-	e.POST("/tax/calculations", th.TaxHandler)
-	e.POST("/tax/calculations/upload-csv", th.TaxCSVHandler)
+	e.GET("/openapi.json", openapi.SpecHandler)
+	e.GET("/docs", openapi.UIHandler)
+
+	for _, prefix := range []string{"", "/v1"} {
+		v1 := e.Group(prefix + "/tax")
+		v1.POST("/calculations", th.TaxHandler, calcLimit.Middleware, kh.Require(apikey.Calc))
+		v1.POST("/calculations/upload-csv", th.TaxCSVHandler, batchLimit.Middleware, kh.Require(apikey.Batch), uploadLimit, idem.Middleware)
+		v1.POST("/calculations/batch", th.TaxBatchHandler, batchLimit.Middleware, kh.Require(apikey.Batch), uploadLimit, idem.Middleware)
+		v1.GET("/calculations/runs/:id/events", th.TaxRunEventsHandler, kh.Require(apikey.Batch))
+		v1.POST("/calculations/pnd91", th.TaxFormHandler, calcLimit.Middleware, kh.Require(apikey.Calc))
+		v1.POST("/recommendations", th.TaxRecommendationHandler, calcLimit.Middleware, kh.Require(apikey.Calc))
+		v1.POST("/curves", th.TaxCurveHandler, calcLimit.Middleware, kh.Require(apikey.Calc))
+	}
+
+	v2 := e.Group("/v2/tax", binding.WithMode(bindingModeV2))
+	v2.POST("/calculations", th.TaxV2Handler, calcLimit.Middleware, kh.Require(apikey.Calc))
+	v2.POST("/calculations/upload-csv", th.TaxCSVV2Handler, batchLimit.Middleware, kh.Require(apikey.Batch), uploadLimit, idem.Middleware)
+	v2.POST("/calculations/batch", th.TaxBatchHandler, batchLimit.Middleware, kh.Require(apikey.Batch), uploadLimit, idem.Middleware)
+	v2.POST("/calculations/pnd91", th.TaxFormHandler, calcLimit.Middleware, kh.Require(apikey.Calc))
+	v2.POST("/recommendations", th.TaxRecommendationHandler, calcLimit.Middleware, kh.Require(apikey.Calc))
+	v2.POST("/curves", th.TaxCurveHandler, calcLimit.Middleware, kh.Require(apikey.Calc))
+
+	secret := []byte(os.Getenv("JWT_SECRET"))
+	if len(secret) == 0 {
+		log.Println("JWT_SECRET is not set, admin tokens will not survive a restart")
+		secret, err = auth.NewSecret()
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	ttl := time.Hour
+	if v := os.Getenv("ADMIN_TOKEN_TTL"); v != "" {
+		ttl, err = time.ParseDuration(v)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	if err := auth.Bootstrap(p, os.Getenv("ADMIN_USERNAME"), os.Getenv("ADMIN_PASSWORD")); err != nil {
+		panic(err)
+	}
 
-	aw := allowance.New(p)
-	a := e.Group("/admin")
-	a.Use(middleware.BasicAuth(func(username, password string, c echo.Context) (bool, error) {
-		if username == os.Getenv("ADMIN_USERNAME") && password == os.Getenv("ADMIN_PASSWORD") {
-			return true, nil
+	var oidc *auth.OIDC
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		roleGroups, err := auth.ParseRoleGroups(os.Getenv("OIDC_ROLE_GROUPS"))
+		if err != nil {
+			panic(err)
+		}
+		oidc, err = auth.NewOIDC(auth.OIDCConfig{
+			Issuer:      issuer,
+			Audience:    os.Getenv("OIDC_AUDIENCE"),
+			GroupsClaim: os.Getenv("OIDC_GROUPS_CLAIM"),
+			RoleGroups:  roleGroups,
+		})
+		if err != nil {
+			panic(err)
 		}
-		return false, nil
+	}
 
-	}))
+	au := audit.New(p)
+	ah := auth.New(p, auth.Config{
+		Secret:    secret,
+		TTL:       ttl,
+		BasicAuth: os.Getenv("ADMIN_BASIC_AUTH") == "true",
+		OIDC:      oidc,
+		Auditor:   au,
+	})
+	e.POST("/admin/login", ah.LoginHandler, adminWriteLimit.Middleware)
+
+	wh := webhook.New(p, webhook.Config{MaxAttempts: webhookAttempts, Backoff: webhookBackoff})
+	aw := allowance.New(p,
+		allowance.WithAuditor(au),
+		allowance.WithPublisher(wh),
+		allowance.WithSelfReview(os.Getenv("ADMIN_SELF_REVIEW") == "true"),
+	)
+	a := e.Group("/admin", ah.Authenticate, adminWriteLimit.Middleware)
+
+	a.POST("/logout", ah.LogoutHandler)
+	a.GET("/users", ah.AdminsHandler, auth.Require(auth.ManageUsers))
+	a.POST("/users", ah.CreateAdminHandler, auth.Require(auth.ManageUsers))
+	a.PUT("/users/:username/role", ah.SetRoleHandler, auth.Require(auth.ManageUsers))
+	a.GET("/api-keys", kh.KeysHandler, auth.Require(auth.ManageAPIKeys))
+	a.POST("/api-keys", kh.CreateKeyHandler, auth.Require(auth.ManageAPIKeys))
+	a.DELETE("/api-keys/:id", kh.RevokeKeyHandler, auth.Require(auth.ManageAPIKeys))
+	a.GET("/audit", au.EntriesHandler, auth.Require(auth.ReadAudit))
+	a.GET("/audit/export", au.ExportHandler, auth.Require(auth.ReadAudit))
+	a.GET("/audit/verify", au.VerifyHandler, auth.Require(auth.ReadAudit))
+	a.POST("/deductions/personal", aw.SetPersonalHandler, auth.Require(auth.ProposeDeductions), idem.Middleware)
+	a.POST("/deductions/k-receipt", aw.SetKReceiptHandler, auth.Require(auth.ProposeDeductions), idem.Middleware)
+	a.GET("/deductions/history", aw.AllowanceHistoryHandler, auth.Require(auth.ReadDeductions))
+	a.GET("/deductions/proposals", aw.ProposalsHandler, auth.Require(auth.ReadDeductions))
+	a.POST("/deductions/proposals/:id/approve", aw.ApproveProposalHandler, auth.Require(auth.ApproveDeductions), idem.Middleware)
+	a.POST("/deductions/proposals/:id/reject", aw.RejectProposalHandler, auth.Require(auth.ApproveDeductions), idem.Middleware)
+	a.POST("/deductions/:type", aw.ProposeHandler, auth.Require(auth.ProposeDeductions), idem.Middleware)
+	a.GET("/webhooks", wh.SubscriptionsHandler, auth.Require(auth.ManageWebhooks))
+	a.POST("/webhooks", wh.CreateSubscriptionHandler, auth.Require(auth.ManageWebhooks))
+	a.DELETE("/webhooks/:id", wh.DeleteSubscriptionHandler, auth.Require(auth.ManageWebhooks))
+	a.GET("/webhooks/:id/deliveries", wh.DeliveriesHandler, auth.Require(auth.ManageWebhooks))
 
-	a.POST("/deductions/personal", aw.SetPersonalHandler)
-	a.POST("/deductions/k-receipt", aw.SetKReceiptHandler)
+	gh, err := gql.New(p, gql.WithProposals(aw))
+	if err != nil {
+		panic(err)
+	}
+	e.POST("/graphql", gh.QueryHandler, calcLimit.Middleware, kh.Require(apikey.Calc))
+	a.POST("/graphql", gh.AdminHandler)
 
 	go func() {
 		if err := e.Start(":" + os.Getenv("PORT")); err != nil && err != http.ErrServerClosed {
@@ -48,13 +245,62 @@ func main() {
 		}
 	}()
 
+	ts := taxgrpc.New(p, taxgrpc.Config{
+		Keys:         kh,
+		CalcLimiter:  calcLimit,
+		BatchLimiter: batchLimit,
+		RowQuota:     rowQuota,
+		BatchLimit:   batchItems,
+		BatchWorkers: batchWorkers,
+	})
+	gs := grpc.NewServer(grpc.UnaryInterceptor(ts.UnaryInterceptor), grpc.StreamInterceptor(ts.StreamInterceptor))
+	ts.Register(gs)
+
+	lis, err := net.Listen("tcp", ":"+getenv("GRPC_PORT", "50051"))
+	if err != nil {
+		panic(err)
+	}
+
+	go func() {
+		if err := gs.Serve(lis); err != nil {
+			log.Println("grpc start error, shutting down the server")
+		}
+	}()
+
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, os.Interrupt)
 	<-shutdown
 	log.Println("shutting down the server")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
+
+	stopped := make(chan struct{})
+	go func() {
+		taxgrpc.Shutdown(ctx, gs)
+		close(stopped)
+	}()
+
+	runs.Close()
 	if err := e.Shutdown(ctx); err != nil {
 		e.Logger.Fatal(err)
 	}
+	if err := wh.Shutdown(ctx); err != nil {
+		log.Println("webhook deliveries still pending at shutdown")
+	}
+	<-stopped
+}
+
+func getenv(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return fallback
+}
+
+func limiter(key, fallback string, opts ...ratelimit.Option) *ratelimit.Limiter {
+	l, err := ratelimit.ParseLimit(getenv(key, fallback))
+	if err != nil {
+		panic(err)
+	}
+	return ratelimit.New(l, opts...)
 }
@@ -0,0 +1,22 @@
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed openapi.json
+var Spec []byte
+
+//go:embed swagger.html
+var ui []byte
+
+func SpecHandler(c echo.Context) error {
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, Spec)
+}
+
+func UIHandler(c echo.Context) error {
+	return c.HTMLBlob(http.StatusOK, ui)
+}
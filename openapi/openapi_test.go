@@ -0,0 +1,416 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/allowance"
+	"github.com/varissara-wo/assessment-tax/apikey"
+	"github.com/varissara-wo/assessment-tax/audit"
+	"github.com/varissara-wo/assessment-tax/auth"
+	"github.com/varissara-wo/assessment-tax/gql"
+	"github.com/varissara-wo/assessment-tax/problem"
+	"github.com/varissara-wo/assessment-tax/tax"
+	"github.com/varissara-wo/assessment-tax/webhook"
+)
+
+type schema struct {
+	Ref        string             `json:"$ref"`
+	Type       string             `json:"type"`
+	Items      *schema            `json:"items"`
+	Properties map[string]*schema `json:"properties"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+type operation struct {
+	OperationID string `json:"operationId"`
+	RequestBody *struct {
+		Content map[string]mediaType `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]struct {
+		Content map[string]mediaType `json:"content"`
+	} `json:"responses"`
+}
+
+type document struct {
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components struct {
+		Schemas map[string]*schema `json:"schemas"`
+	} `json:"components"`
+}
+
+var schemaTypes = map[string]interface{}{
+	"Allowance":              allowance.Allowance{},
+	"TaxDetails":             tax.TaxDetails{},
+	"TaxBreakdown":           tax.TaxBreakdown{},
+	"MonthlySurcharge":       tax.MonthlySurcharge{},
+	"PaymentSchedule":        tax.PaymentSchedule{},
+	"Installment":            tax.Installment{},
+	"TaxResponse":            tax.TaxResponse{},
+	"Taxes":                  tax.Taxes{},
+	"TaxesResponse":          tax.TaxesResponse{},
+	"RunProgress":            tax.RunProgress{},
+	"BatchItem":              tax.BatchItem{},
+	"BatchRequest":           tax.BatchRequest{},
+	"BatchResult":            tax.BatchResult{},
+	"BatchResponse":          tax.BatchResponse{},
+	"Recommendation":         tax.Recommendation{},
+	"RecommendationResponse": tax.RecommendationResponse{},
+	"CurveRequest":           tax.CurveRequest{},
+	"CurvePoint":             tax.CurvePoint{},
+	"CurveResponse":          tax.CurveResponse{},
+	"Credentials":            auth.Credentials{},
+	"Token":                  auth.Token{},
+	"NewAdmin":               auth.NewAdmin{},
+	"Admin":                  auth.Admin{},
+	"Admins":                 auth.Admins{},
+	"RoleChange":             auth.RoleChange{},
+	"Amount":                 allowance.Amount{},
+	"Proposal":               allowance.Proposal{},
+	"Proposals":              allowance.Proposals{},
+	"CapRecord":              allowance.CapRecord{},
+	"CapHistory":             allowance.CapHistory{},
+	"NewKey":                 apikey.NewKey{},
+	"Key":                    apikey.Key{},
+	"IssuedKey":              apikey.IssuedKey{},
+	"Keys":                   apikey.Keys{},
+	"NewSubscription":        webhook.NewSubscription{},
+	"Subscription":           webhook.Subscription{},
+	"IssuedSubscription":     webhook.IssuedSubscription{},
+	"Subscriptions":          webhook.Subscriptions{},
+	"Delivery":               webhook.Delivery{},
+	"Deliveries":             webhook.Deliveries{},
+	"WebhookEvent":           webhook.Event{},
+	"Entry":                  audit.Entry{},
+	"Entries":                audit.Entries{},
+	"Verification":           audit.Verification{},
+	"Income":                 tax.Income{},
+	"CalculationRequest":     tax.CalculationRequest{},
+	"IncomeLine":             tax.IncomeLine{},
+	"IncomeSummary":          tax.IncomeSummary{},
+	"AllowanceClaim":         tax.AllowanceClaim{},
+	"Bracket":                tax.Bracket{},
+	"Calculation":            tax.Calculation{},
+	"Calculations":           tax.Calculations{},
+	"GraphQLRequest":         gql.Request{},
+	"ProblemDetail":          problem.Error{},
+	"Problem":                problem.Problem{},
+}
+
+var operations = map[string]struct {
+	request  interface{}
+	response interface{}
+}{
+	"calculateTax":        {tax.TaxDetails{}, tax.TaxResponse{}},
+	"calculateTaxCSV":     {nil, tax.TaxesResponse{}},
+	"calculateTaxBatch":   {tax.BatchRequest{}, tax.BatchResponse{}},
+	"streamBatchRun":      {nil, nil},
+	"calculateTaxV2":      {tax.CalculationRequest{}, tax.Calculation{}},
+	"calculateTaxCSVV2":   {nil, tax.Calculations{}},
+	"renderPND91":         {tax.TaxDetails{}, nil},
+	"recommendAllowances": {tax.TaxDetails{}, tax.RecommendationResponse{}},
+	"taxCurve":            {tax.CurveRequest{}, tax.CurveResponse{}},
+	"graphqlQuery":        {gql.Request{}, nil},
+	"graphqlAdmin":        {gql.Request{}, nil},
+	"login":               {auth.Credentials{}, auth.Token{}},
+	"logout":              {nil, nil},
+	"listAdmins":          {nil, auth.Admins{}},
+	"createAdmin":         {auth.NewAdmin{}, auth.Admin{}},
+	"setAdminRole":        {auth.RoleChange{}, auth.Admin{}},
+	"listAPIKeys":         {nil, apikey.Keys{}},
+	"createAPIKey":        {apikey.NewKey{}, apikey.IssuedKey{}},
+	"revokeAPIKey":        {nil, apikey.Key{}},
+	"listWebhooks":        {nil, webhook.Subscriptions{}},
+	"createWebhook":       {webhook.NewSubscription{}, webhook.IssuedSubscription{}},
+	"deleteWebhook":       {nil, webhook.Subscription{}},
+	"webhookDeliveries":   {nil, webhook.Deliveries{}},
+	"listAuditEntries":    {nil, audit.Entries{}},
+	"exportAuditEntries":  {nil, nil},
+	"verifyAuditLog":      {nil, audit.Verification{}},
+	"proposePersonal":     {allowance.Amount{}, allowance.Proposal{}},
+	"proposeKReceipt":     {allowance.Amount{}, allowance.Proposal{}},
+	"proposeDeduction":    {allowance.Amount{}, allowance.Proposal{}},
+	"deductionHistory":    {nil, allowance.CapHistory{}},
+	"listProposals":       {nil, allowance.Proposals{}},
+	"approveProposal":     {nil, allowance.Proposal{}},
+	"rejectProposal":      {nil, allowance.Proposal{}},
+}
+
+func load(t *testing.T) document {
+	t.Helper()
+
+	var d document
+	if err := json.Unmarshal(Spec, &d); err != nil {
+		t.Fatalf("spec is not valid JSON: %v", err)
+	}
+	return d
+}
+
+func refName(ref string) string {
+	return strings.TrimPrefix(ref, "#/components/schemas/")
+}
+
+func schemaName(v interface{}) string {
+	for name, st := range schemaTypes {
+		if reflect.TypeOf(st) == reflect.TypeOf(v) {
+			return name
+		}
+	}
+	return ""
+}
+
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			for n, ft := range jsonFields(f.Type) {
+				fields[n] = ft
+			}
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+func checkType(t *testing.T, path string, s *schema, gt reflect.Type) {
+	t.Helper()
+
+	if gt == reflect.TypeOf(json.RawMessage{}) {
+		if s.Type != "" || s.Ref != "" {
+			t.Errorf("%s: expected any value but spec has %q", path, s.Type+s.Ref)
+		}
+		return
+	}
+
+	for gt.Kind() == reflect.Ptr {
+		gt = gt.Elem()
+	}
+
+	if s.Ref != "" {
+		st, ok := schemaTypes[refName(s.Ref)]
+		if !ok || reflect.TypeOf(st) != gt {
+			t.Errorf("%s: spec refers to %s but the handler uses %v", path, s.Ref, gt)
+		}
+		return
+	}
+
+	want := ""
+	switch {
+	case gt == reflect.TypeOf(tax.Decimal(0)):
+		want = "string"
+	case gt == reflect.TypeOf(time.Time{}):
+		want = "string"
+	default:
+		want = kindType(t, path, gt)
+	}
+	if want == "" {
+		return
+	}
+
+	if s.Type != want {
+		t.Errorf("%s: expected type %q for %v but spec has %q", path, want, gt, s.Type)
+		return
+	}
+
+	if want == "array" {
+		if s.Items == nil {
+			t.Errorf("%s: array without items", path)
+			return
+		}
+		checkType(t, path+"[]", s.Items, gt.Elem())
+	}
+}
+
+func kindType(t *testing.T, path string, gt reflect.Type) string {
+	t.Helper()
+
+	switch gt.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "integer"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice:
+		return "array"
+	case reflect.Map:
+		return "object"
+	}
+
+	t.Errorf("%s: expected a $ref to %v", path, gt)
+	return ""
+}
+
+func keys(m interface{}) []string {
+	var ks []string
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		ks = append(ks, k.String())
+	}
+	sort.Strings(ks)
+	return ks
+}
+
+func TestSchemas(t *testing.T) {
+	d := load(t)
+
+	if got, want := keys(d.Components.Schemas), keys(schemaTypes); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected schemas %v but spec has %v", want, got)
+	}
+
+	for name, st := range schemaTypes {
+		s := d.Components.Schemas[name]
+		fields := jsonFields(reflect.TypeOf(st))
+
+		if got, want := keys(s.Properties), keys(fields); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected properties %v but spec has %v", name, want, got)
+			continue
+		}
+
+		for field, ft := range fields {
+			checkType(t, name+"."+field, s.Properties[field], ft)
+		}
+	}
+}
+
+func TestOperations(t *testing.T) {
+	d := load(t)
+
+	seen := map[string]bool{}
+	for path, methods := range d.Paths {
+		for method, op := range methods {
+			want, ok := operations[op.OperationID]
+			if !ok {
+				t.Errorf("%s %s: unknown operation %q", method, path, op.OperationID)
+				continue
+			}
+			seen[op.OperationID] = true
+
+			var req string
+			if op.RequestBody != nil {
+				if mt, ok := op.RequestBody.Content[echo.MIMEApplicationJSON]; ok {
+					req = refName(mt.Schema.Ref)
+				}
+			}
+			if want.request != nil && req != schemaName(want.request) {
+				t.Errorf("%s: expected request %v but spec has %q", op.OperationID, reflect.TypeOf(want.request), req)
+			}
+			if want.request == nil && req != "" {
+				t.Errorf("%s: expected no JSON request but spec has %q", op.OperationID, req)
+			}
+
+			var res string
+			for status, r := range op.Responses {
+				if !strings.HasPrefix(status, "2") {
+					continue
+				}
+				if mt, ok := r.Content[echo.MIMEApplicationJSON]; ok {
+					res = refName(mt.Schema.Ref)
+				}
+			}
+			if want.response != nil && res != schemaName(want.response) {
+				t.Errorf("%s: expected response %v but spec has %q", op.OperationID, reflect.TypeOf(want.response), res)
+			}
+			if want.response == nil && res != "" {
+				t.Errorf("%s: expected no JSON response but spec has %q", op.OperationID, res)
+			}
+		}
+	}
+
+	for id := range operations {
+		if !seen[id] {
+			t.Errorf("expected operation %q in the spec", id)
+		}
+	}
+}
+
+func TestRefs(t *testing.T) {
+	var doc interface{}
+	json.Unmarshal(Spec, &doc)
+
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok {
+				if !resolves(doc, ref) {
+					t.Errorf("unresolved reference %v", ref)
+				}
+			}
+			for _, e := range v {
+				walk(e)
+			}
+		case []interface{}:
+			for _, e := range v {
+				walk(e)
+			}
+		}
+	}
+	walk(doc)
+}
+
+func resolves(doc interface{}, ref string) bool {
+	v := doc
+	for _, p := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if v, ok = m[p]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func TestHandlers(t *testing.T) {
+	t.Run("should serve the spec", func(t *testing.T) {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/openapi.json", nil), rec)
+
+		SpecHandler(c)
+
+		if rec.Code != http.StatusOK || !json.Valid(rec.Body.Bytes()) {
+			t.Errorf("expected 200 with the spec but got %v", rec.Code)
+		}
+	})
+
+	t.Run("should serve swagger ui pointing at the spec", func(t *testing.T) {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/docs", nil), rec)
+
+		UIHandler(c)
+
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "/openapi.json") {
+			t.Errorf("expected 200 with swagger ui but got %v", rec.Code)
+		}
+	})
+}
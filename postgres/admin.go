@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/varissara-wo/assessment-tax/auth"
+)
+
+func (p *Postgres) AdminByUsername(username string) (auth.Admin, error) {
+	var a auth.Admin
+	err := p.Db.QueryRow(`SELECT id, username, role, password_hash, created_at FROM admins WHERE username = $1`, username).
+		Scan(&a.ID, &a.Username, &a.Role, &a.PasswordHash, &a.CreatedAt)
+	if err == sql.ErrNoRows {
+		return a, auth.ErrNotFound
+	}
+	return a, err
+}
+
+func (p *Postgres) CreateAdmin(a auth.Admin) (auth.Admin, error) {
+	err := p.Db.QueryRow(`INSERT INTO admins (username, role, password_hash) VALUES ($1, $2, $3)
+		ON CONFLICT (username) DO NOTHING RETURNING id, created_at`, a.Username, a.Role, a.PasswordHash).
+		Scan(&a.ID, &a.CreatedAt)
+	if err == sql.ErrNoRows {
+		return a, auth.ErrDuplicate
+	}
+	return a, err
+}
+
+func (p *Postgres) Admins() ([]auth.Admin, error) {
+	rows, err := p.Db.Query(`SELECT id, username, role, created_at FROM admins ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	as := []auth.Admin{}
+	for rows.Next() {
+		var a auth.Admin
+		if err := rows.Scan(&a.ID, &a.Username, &a.Role, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		as = append(as, a)
+	}
+
+	return as, rows.Err()
+}
+
+func (p *Postgres) SetAdminRole(username string, role auth.Role) (auth.Admin, error) {
+	a := auth.Admin{Username: username, Role: role}
+	err := p.Db.QueryRow(`UPDATE admins SET role = $2 WHERE username = $1 RETURNING id, created_at`, username, role).
+		Scan(&a.ID, &a.CreatedAt)
+	if err == sql.ErrNoRows {
+		return a, auth.ErrNotFound
+	}
+	return a, err
+}
+
+func (p *Postgres) SetAdminPassword(username, hash string) error {
+	res, err := p.Db.Exec(`UPDATE admins SET password_hash = $2 WHERE username = $1`, username, hash)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return auth.ErrNotFound
+	}
+	return err
+}
+
+func (p *Postgres) RevokeToken(id string, expiresAt time.Time) error {
+	_, err := p.Db.Exec(`INSERT INTO revoked_tokens (id, expires_at) VALUES ($1, $2) ON CONFLICT (id) DO NOTHING`, id, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.Db.Exec(`DELETE FROM revoked_tokens WHERE expires_at < now()`)
+	return err
+}
+
+func (p *Postgres) IsTokenRevoked(id string) (bool, error) {
+	var revoked bool
+	err := p.Db.QueryRow(`SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE id = $1)`, id).Scan(&revoked)
+	return revoked, err
+}
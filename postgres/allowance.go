@@ -1,13 +1,18 @@
 package postgres
 
 import (
+	"database/sql"
+	"time"
+
 	"github.com/varissara-wo/assessment-tax/allowance"
 )
 
-func (p *Postgres) GetAllowances() (allowance.MaxAllowance, error) {
+func (p *Postgres) GetAllowances(at time.Time) (allowance.MaxAllowance, error) {
 	var ma allowance.MaxAllowance
 
-	rows, err := p.Db.Query("SELECT * FROM allowances")
+	// Dates before a type's first cap fall back to that first cap.
+	rows, err := p.Db.Query(`SELECT DISTINCT ON (type) type, max_amount FROM allowance_caps
+		ORDER BY type, effective_from > $1, CASE WHEN effective_from <= $1 THEN effective_from END DESC, effective_from, id DESC`, at)
 	if err != nil {
 		return ma, err
 	}
@@ -16,8 +21,7 @@ func (p *Postgres) GetAllowances() (allowance.MaxAllowance, error) {
 	for rows.Next() {
 		var t allowance.AllowanceType
 		var amount float64
-		var id int
-		err := rows.Scan(&id, &t, &amount)
+		err := rows.Scan(&t, &amount)
 		if err != nil {
 			return ma, err
 		}
@@ -29,24 +33,157 @@ func (p *Postgres) GetAllowances() (allowance.MaxAllowance, error) {
 			ma.KReceipt = amount
 		case allowance.Personal:
 			ma.Personal = amount
+		case allowance.RMF:
+			ma.RMF = amount
+		case allowance.SSF:
+			ma.SSF = amount
+		}
+	}
+
+	return ma, rows.Err()
+}
+
+func (p *Postgres) AllowanceHistory(t allowance.AllowanceType) ([]allowance.CapRecord, error) {
+	rows, err := p.Db.Query(`SELECT id, type, max_amount, effective_from, changed_by, COALESCE(approved_by, ''), reason, created_at FROM allowance_caps
+		WHERE $1 = '' OR type = $1
+		ORDER BY effective_from DESC, id DESC`, t)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []allowance.CapRecord{}
+	for rows.Next() {
+		var r allowance.CapRecord
+		err := rows.Scan(&r.ID, &r.AllowanceType, &r.Amount, &r.EffectiveFrom, &r.ChangedBy, &r.ApprovedBy, &r.Reason, &r.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, r)
+	}
+
+	return history, rows.Err()
+}
+
+const proposalColumns = `id, type, max_amount, effective_from, reason, status, proposed_by, COALESCE(reviewed_by, ''), reviewed_at, created_at`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanProposal(row scanner) (allowance.Proposal, error) {
+	var pr allowance.Proposal
+	err := row.Scan(&pr.ID, &pr.AllowanceType, &pr.Amount, &pr.EffectiveFrom, &pr.Reason, &pr.Status, &pr.ProposedBy, &pr.ReviewedBy, &pr.ReviewedAt, &pr.CreatedAt)
+	return pr, err
+}
+
+func (p *Postgres) CreateProposal(pr allowance.Proposal, a allowance.Audit) (allowance.Proposal, error) {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return allowance.Proposal{}, err
+	}
+	defer tx.Rollback()
+
+	pr, err = scanProposal(tx.QueryRow(`INSERT INTO allowance_proposals (type, max_amount, effective_from, reason, proposed_by)
+		VALUES ($1, $2, $3, $4, $5) RETURNING `+proposalColumns,
+		pr.AllowanceType, pr.Amount, pr.EffectiveFrom, pr.Reason, pr.ProposedBy))
+	if err != nil {
+		return pr, err
+	}
+
+	if err := auditProposal(tx, a, pr); err != nil {
+		return pr, err
+	}
+
+	return pr, tx.Commit()
+}
+
+func (p *Postgres) Proposal(id int) (allowance.Proposal, error) {
+	pr, err := scanProposal(p.Db.QueryRow(`SELECT `+proposalColumns+` FROM allowance_proposals WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return pr, allowance.ErrNotFound
+	}
+	return pr, err
+}
+
+func (p *Postgres) Proposals(s allowance.ProposalStatus) ([]allowance.Proposal, error) {
+	rows, err := p.Db.Query(`SELECT `+proposalColumns+` FROM allowance_proposals WHERE status = $1 ORDER BY created_at, id`, s)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ps := []allowance.Proposal{}
+	for rows.Next() {
+		pr, err := scanProposal(rows)
+		if err != nil {
+			return nil, err
 		}
+		ps = append(ps, pr)
 	}
 
-	return ma, nil
+	return ps, rows.Err()
 }
 
-func (p *Postgres) SetPersonal(a float64) (allowance.PersonalDeduction, error) {
-	_, err := p.Db.Exec("UPDATE allowances SET max_amount = $1 WHERE type = 'personal'", a)
+func (p *Postgres) ApproveProposal(id int, reviewer string, a allowance.Audit) (allowance.Proposal, error) {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return allowance.Proposal{}, err
+	}
+	defer tx.Rollback()
+
+	pr, err := reviewProposal(tx, id, reviewer, allowance.Approved)
+	if err != nil {
+		return pr, err
+	}
+
+	_, err = tx.Exec(`INSERT INTO allowance_caps (type, max_amount, effective_from, changed_by, approved_by, reason)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		pr.AllowanceType, pr.Amount, pr.EffectiveAt(time.Now()), pr.ProposedBy, reviewer, pr.Reason)
 	if err != nil {
-		return allowance.PersonalDeduction{}, err
+		return pr, err
 	}
-	return allowance.PersonalDeduction{Personal: a}, nil
+
+	if err := auditProposal(tx, a, pr); err != nil {
+		return pr, err
+	}
+
+	return pr, tx.Commit()
 }
 
-func (p *Postgres) SetKReceipt(a float64) (allowance.KReceiptDeduction, error) {
-	_, err := p.Db.Exec("UPDATE allowances SET max_amount = $1 WHERE type = 'k-receipt'", a)
+func (p *Postgres) RejectProposal(id int, reviewer string, a allowance.Audit) (allowance.Proposal, error) {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return allowance.Proposal{}, err
+	}
+	defer tx.Rollback()
+
+	pr, err := reviewProposal(tx, id, reviewer, allowance.Rejected)
 	if err != nil {
-		return allowance.KReceiptDeduction{}, err
+		return pr, err
+	}
+
+	if err := auditProposal(tx, a, pr); err != nil {
+		return pr, err
+	}
+
+	return pr, tx.Commit()
+}
+
+func auditProposal(tx *sql.Tx, a allowance.Audit, pr allowance.Proposal) error {
+	if a == nil {
+		return nil
+	}
+	_, err := appendAudit(tx, a(pr))
+	return err
+}
+
+func reviewProposal(tx *sql.Tx, id int, reviewer string, s allowance.ProposalStatus) (allowance.Proposal, error) {
+	pr, err := scanProposal(tx.QueryRow(`UPDATE allowance_proposals SET status = $3, reviewed_by = $2, reviewed_at = now()
+		WHERE id = $1 AND status = 'pending'
+		RETURNING `+proposalColumns, id, reviewer, s))
+	if err == sql.ErrNoRows {
+		return pr, allowance.ErrNotPending
 	}
-	return allowance.KReceiptDeduction{KReceipt: a}, nil
+	return pr, err
 }
@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/varissara-wo/assessment-tax/allowance"
+	"github.com/varissara-wo/assessment-tax/tax"
+)
+
+func TestApproveProposal(t *testing.T) {
+	db, _ := baselineDB(t)
+	if err := Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	p := &Postgres{Db: db}
+
+	td := tax.TaxDetails{TotalIncome: 500000.0}
+	before, err := p.TaxCalculation(td)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from := allowance.FilingYearStart(time.Now())
+	pr, err := p.CreateProposal(allowance.Proposal{AllowanceType: allowance.Personal, Amount: 100000.0, EffectiveFrom: &from, Reason: "budget act", ProposedBy: "editor"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.ApproveProposal(pr.ID, "approver", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := p.TaxCalculation(td)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if before.Tax != 29000.0 || after.Tax != 25000.0 {
+		t.Errorf("expected the approved cap to lower the tax from 29000.0 to 25000.0 but got %v and %v", before.Tax, after.Tax)
+	}
+}
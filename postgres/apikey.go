@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/varissara-wo/assessment-tax/apikey"
+)
+
+const apiKeyColumns = `id, name, prefix, scopes, key_hash, created_by, created_at, last_used_at, revoked_at`
+
+func scanAPIKey(s scanner) (apikey.Key, error) {
+	var k apikey.Key
+	var scopes []string
+	err := s.Scan(&k.ID, &k.Name, &k.Prefix, pq.Array(&scopes), &k.Hash, &k.CreatedBy, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt)
+	for _, sc := range scopes {
+		k.Scopes = append(k.Scopes, apikey.Scope(sc))
+	}
+	return k, err
+}
+
+func (p *Postgres) CreateKey(k apikey.Key) (apikey.Key, error) {
+	scopes := make([]string, len(k.Scopes))
+	for i, s := range k.Scopes {
+		scopes[i] = string(s)
+	}
+
+	return scanAPIKey(p.Db.QueryRow(`INSERT INTO api_keys (name, prefix, scopes, key_hash, created_by)
+		VALUES ($1, $2, $3, $4, $5) RETURNING `+apiKeyColumns, k.Name, k.Prefix, pq.Array(scopes), k.Hash, k.CreatedBy))
+}
+
+func (p *Postgres) Keys() ([]apikey.Key, error) {
+	rows, err := p.Db.Query(`SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ks := []apikey.Key{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		ks = append(ks, k)
+	}
+
+	return ks, rows.Err()
+}
+
+func (p *Postgres) RevokeKey(id int) (apikey.Key, error) {
+	k, err := scanAPIKey(p.Db.QueryRow(`UPDATE api_keys SET revoked_at = COALESCE(revoked_at, now())
+		WHERE id = $1 RETURNING `+apiKeyColumns, id))
+	if err == sql.ErrNoRows {
+		return k, apikey.ErrNotFound
+	}
+	return k, err
+}
+
+func (p *Postgres) KeyByHash(hash string) (apikey.Key, error) {
+	k, err := scanAPIKey(p.Db.QueryRow(`SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1`, hash))
+	if err == sql.ErrNoRows {
+		return k, apikey.ErrNotFound
+	}
+	return k, err
+}
+
+func (p *Postgres) TouchKey(id int, at time.Time) error {
+	_, err := p.Db.Exec(`UPDATE api_keys SET last_used_at = $2 WHERE id = $1`, id, at)
+	return err
+}
@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/varissara-wo/assessment-tax/audit"
+)
+
+const auditLockKey = 39
+
+func (p *Postgres) Append(e audit.Entry) (audit.Entry, error) {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return e, err
+	}
+	defer tx.Rollback()
+
+	e, err = appendAudit(tx, e)
+	if err != nil {
+		return e, err
+	}
+
+	return e, tx.Commit()
+}
+
+// appendAudit chains e onto the log inside tx, so the entry commits or rolls
+// back together with the change it records.
+func appendAudit(tx *sql.Tx, e audit.Entry) (audit.Entry, error) {
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, auditLockKey); err != nil {
+		return e, err
+	}
+
+	var prev string
+	err := tx.QueryRow(`SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1`).Scan(&prev)
+	if err != nil && err != sql.ErrNoRows {
+		return e, err
+	}
+
+	e = e.Seal(prev)
+	err = tx.QueryRow(`INSERT INTO audit_log (at, actor, action, outcome, ip, request_id, target, before_value, after_value, detail, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id`,
+		e.At, e.Actor, e.Action, e.Outcome, e.IP, e.RequestID, e.Target, string(e.Before), string(e.After), e.Detail, e.PrevHash, e.Hash).
+		Scan(&e.ID)
+	return e, err
+}
+
+func (p *Postgres) Entries(f audit.Filter) ([]audit.Entry, error) {
+	where := []string{"TRUE"}
+	args := []interface{}{}
+	add := func(cond string, v interface{}) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+
+	if f.Actor != "" {
+		add("actor = $%d", f.Actor)
+	}
+	if f.Action != "" {
+		add("action = $%d", f.Action)
+	}
+	if f.From != nil {
+		add("at >= $%d", *f.From)
+	}
+	if f.To != nil {
+		add("at < $%d", *f.To)
+	}
+	if f.AfterID > 0 {
+		add("id > $%d", f.AfterID)
+	}
+
+	q := `SELECT id, at, actor, action, outcome, ip, request_id, target, before_value, after_value, detail, prev_hash, hash
+		FROM audit_log WHERE ` + strings.Join(where, " AND ") + ` ORDER BY id`
+	if f.Limit > 0 {
+		q += fmt.Sprintf(" LIMIT %d", f.Limit)
+	}
+
+	rows, err := p.Db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	es := []audit.Entry{}
+	for rows.Next() {
+		var e audit.Entry
+		var before, after string
+		if err := rows.Scan(&e.ID, &e.At, &e.Actor, &e.Action, &e.Outcome, &e.IP, &e.RequestID, &e.Target, &before, &after, &e.Detail, &e.PrevHash, &e.Hash); err != nil {
+			return nil, err
+		}
+		if before != "" {
+			e.Before = []byte(before)
+		}
+		if after != "" {
+			e.After = []byte(after)
+		}
+		es = append(es, e)
+	}
+
+	return es, rows.Err()
+}
@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/varissara-wo/assessment-tax/idempotency"
+)
+
+func (p *Postgres) ReserveKey(r idempotency.Record) (idempotency.Record, bool, error) {
+	if _, err := p.Db.Exec(`DELETE FROM idempotency_keys WHERE expires_at < now()`); err != nil {
+		return idempotency.Record{}, false, err
+	}
+
+	var owner string
+	err := p.Db.QueryRow(`INSERT INTO idempotency_keys (owner, key, fingerprint, expires_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (owner, key) DO NOTHING RETURNING owner`, r.Owner, r.Key, r.Fingerprint, r.ExpiresAt).Scan(&owner)
+	if err == nil {
+		return r, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return idempotency.Record{}, false, err
+	}
+
+	existing := idempotency.Record{Owner: r.Owner, Key: r.Key}
+	err = p.Db.QueryRow(`SELECT fingerprint, status, content_type, body, expires_at FROM idempotency_keys
+		WHERE owner = $1 AND key = $2`, r.Owner, r.Key).
+		Scan(&existing.Fingerprint, &existing.Status, &existing.ContentType, &existing.Body, &existing.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return p.ReserveKey(r)
+	}
+	return existing, false, err
+}
+
+func (p *Postgres) ExtendKey(owner, key string, expiresAt time.Time) error {
+	_, err := p.Db.Exec(`UPDATE idempotency_keys SET expires_at = $3 WHERE owner = $1 AND key = $2 AND status = 0`,
+		owner, key, expiresAt)
+	return err
+}
+
+func (p *Postgres) CompleteKey(r idempotency.Record) error {
+	_, err := p.Db.Exec(`UPDATE idempotency_keys SET status = $3, content_type = $4, body = $5, expires_at = $6
+		WHERE owner = $1 AND key = $2`, r.Owner, r.Key, r.Status, r.ContentType, r.Body, r.ExpiresAt)
+	return err
+}
+
+func (p *Postgres) ReleaseKey(owner, key string) error {
+	_, err := p.Db.Exec(`DELETE FROM idempotency_keys WHERE owner = $1 AND key = $2 AND status = 0`, owner, key)
+	return err
+}
@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// baseline is init.sql as first released, which existing volumes still have.
+const baseline = `CREATE TABLE IF NOT EXISTS allowances (
+    id SERIAL PRIMARY KEY,
+    type VARCHAR(25) NOT NULL UNIQUE,
+    max_amount FLOAT NOT NULL
+);
+
+INSERT INTO allowances (type, max_amount) VALUES
+('personal', 60000.0),
+('donation', 100000.0),
+('k-receipt', 50000.0);`
+
+var createTable = regexp.MustCompile(`CREATE TABLE IF NOT EXISTS (\w+)`)
+
+func tables(script string) map[string]bool {
+	ts := map[string]bool{}
+	for _, m := range createTable.FindAllStringSubmatch(script, -1) {
+		ts[m[1]] = true
+	}
+	return ts
+}
+
+func TestMigrationsCreateInitTables(t *testing.T) {
+	schemaSQL, err := os.ReadFile("../init.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := tables(baseline)
+	names, _ := fs.Glob(migrations, "migrations/*.sql")
+	for _, name := range names {
+		script, _ := migrations.ReadFile(name)
+		for table := range tables(string(script)) {
+			created[table] = true
+		}
+	}
+
+	for table := range tables(string(schemaSQL)) {
+		if !created[table] {
+			t.Errorf("expected a migration to create %s for databases from the baseline schema", table)
+		}
+	}
+}
+
+// baselineDB returns a connection to a new schema holding only the baseline,
+// or skips the test without a database.
+func baselineDB(t *testing.T) (*sql.DB, string) {
+	t.Helper()
+
+	url := os.Getenv("DATABASE_URL")
+	if url == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	// One connection keeps the search_path for every statement.
+	db.SetMaxOpenConns(1)
+	schema := fmt.Sprintf("migrate_test_%d", time.Now().UnixNano())
+	if _, err := db.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Exec(`DROP SCHEMA ` + schema + ` CASCADE`) })
+	if _, err := db.Exec(`SET search_path TO ` + schema); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Exec(baseline); err != nil {
+		t.Fatal(err)
+	}
+	return db, schema
+}
+
+func TestMigrateBaseline(t *testing.T) {
+	db, schema := baselineDB(t)
+
+	for i := 0; i < 2; i++ {
+		if err := Migrate(db); err != nil {
+			t.Fatalf("expected migrations to apply to the baseline schema but got %v", err)
+		}
+	}
+
+	schemaSQL, err := os.ReadFile("../init.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for table := range tables(string(schemaSQL)) {
+		var exists bool
+		if err := db.QueryRow(`SELECT to_regclass($1) IS NOT NULL`, schema+"."+table).Scan(&exists); err != nil || !exists {
+			t.Errorf("expected table %s to exist but got %v", table, err)
+		}
+	}
+
+	var caps int
+	if err := db.QueryRow(`SELECT count(DISTINCT type) FROM allowance_caps`).Scan(&caps); err != nil || caps != 5 {
+		t.Errorf("expected caps for 5 allowances but got %v %v", caps, err)
+	}
+}
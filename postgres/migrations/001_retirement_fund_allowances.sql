@@ -0,0 +1,21 @@
+-- Adds the RMF and SSF caps used by /tax/recommendations to databases
+-- created before they were part of init.sql.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'allowances' AND column_name = 'max_amount') THEN
+        INSERT INTO allowances (type, max_amount) VALUES
+        ('rmf', 500000.0),
+        ('ssf', 200000.0)
+        ON CONFLICT (type) DO NOTHING;
+    ELSE
+        INSERT INTO allowances (type) VALUES
+        ('rmf'),
+        ('ssf')
+        ON CONFLICT (type) DO NOTHING;
+
+        INSERT INTO allowance_caps (type, max_amount, effective_from, changed_by, reason)
+        SELECT v.type, v.max_amount, '2024-01-01T00:00:00+07:00', 'system', 'initial cap'
+        FROM (VALUES ('rmf', 500000.0), ('ssf', 200000.0)) AS v (type, max_amount)
+        WHERE NOT EXISTS (SELECT 1 FROM allowance_caps c WHERE c.type = v.type);
+    END IF;
+END $$;
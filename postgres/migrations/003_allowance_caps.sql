@@ -0,0 +1,26 @@
+-- Moves caps from allowances.max_amount, used before caps were effective
+-- dated, into allowance_caps and drops the column.
+CREATE TABLE IF NOT EXISTS allowance_caps (
+    id SERIAL PRIMARY KEY,
+    type VARCHAR(25) NOT NULL REFERENCES allowances (type),
+    max_amount FLOAT NOT NULL,
+    effective_from TIMESTAMPTZ NOT NULL,
+    changed_by VARCHAR(100) NOT NULL,
+    approved_by VARCHAR(100),
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS allowance_caps_type_effective_from_idx ON allowance_caps (type, effective_from DESC);
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'allowances' AND column_name = 'max_amount') THEN
+        INSERT INTO allowance_caps (type, max_amount, effective_from, changed_by, reason)
+        SELECT a.type, a.max_amount, '2024-01-01T00:00:00+07:00', 'system', 'migrated cap'
+        FROM allowances a
+        WHERE NOT EXISTS (SELECT 1 FROM allowance_caps c WHERE c.type = a.type);
+
+        ALTER TABLE allowances DROP COLUMN max_amount;
+    END IF;
+END $$;
@@ -0,0 +1,29 @@
+-- Creates the append-only audit log for databases created before it was part
+-- of init.sql.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    at TIMESTAMPTZ NOT NULL,
+    actor TEXT NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    outcome VARCHAR(10) NOT NULL,
+    ip TEXT NOT NULL,
+    request_id TEXT NOT NULL,
+    target VARCHAR(50) NOT NULL,
+    before_value TEXT NOT NULL,
+    after_value TEXT NOT NULL,
+    detail TEXT NOT NULL,
+    prev_hash VARCHAR(64) NOT NULL,
+    hash VARCHAR(64) NOT NULL UNIQUE
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_actor_at_idx ON audit_log (actor, at);
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER audit_log_append_only
+BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
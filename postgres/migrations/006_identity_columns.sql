@@ -0,0 +1,6 @@
+-- OIDC admins are recorded as oidc:<issuer>#<sub>, which does not fit in
+-- 100 characters.
+ALTER TABLE allowance_caps ALTER COLUMN changed_by TYPE TEXT, ALTER COLUMN approved_by TYPE TEXT;
+ALTER TABLE allowance_proposals ALTER COLUMN proposed_by TYPE TEXT, ALTER COLUMN reviewed_by TYPE TEXT;
+ALTER TABLE api_keys ALTER COLUMN created_by TYPE TEXT;
+ALTER TABLE webhook_subscriptions ALTER COLUMN created_by TYPE TEXT;
@@ -0,0 +1,3 @@
+-- Actors, IPs and request IDs are bounded by the application, not by the
+-- column, so a long OIDC identity or request ID cannot fail the write.
+ALTER TABLE audit_log ALTER COLUMN actor TYPE TEXT, ALTER COLUMN ip TYPE TEXT, ALTER COLUMN request_id TYPE TEXT;
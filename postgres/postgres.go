@@ -23,5 +23,9 @@ func New() (*Postgres, error) {
 	if err != nil {
 		log.Fatal(err)
 	}
+	err = Migrate(db)
+	if err != nil {
+		log.Fatal(err)
+	}
 	return &Postgres{Db: db}, nil
 }
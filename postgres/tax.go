@@ -1,12 +1,14 @@
 package postgres
 
 import (
+	"time"
+
 	"github.com/varissara-wo/assessment-tax/tax"
 )
 
 func (p *Postgres) TaxCalculation(td tax.TaxDetails) (tax.TaxResponse, error) {
 
-	ma, err := p.GetAllowances()
+	ma, err := p.GetAllowances(td.CapsAt(time.Now()))
 	if err != nil {
 		return tax.TaxResponse{}, err
 	}
@@ -16,34 +18,67 @@ func (p *Postgres) TaxCalculation(td tax.TaxDetails) (tax.TaxResponse, error) {
 	return tax.CalculateTax(netIncome, td.WHT), nil
 }
 
-func (p *Postgres) TaxesCalculation(tds []tax.TaxDetails) ([]tax.Taxes, error) {
+func (p *Postgres) TaxesCalculation(tds []tax.TaxDetails, progress tax.Progress) ([]tax.Taxes, error) {
 
-	ma, err := p.GetAllowances()
+	mas, err := tax.CapsFor(tds, time.Now(), p.GetAllowances)
 	if err != nil {
 		return []tax.Taxes{}, err
 	}
 
-	taxes := []tax.Taxes{}
+	return tax.CalculateRows(tds, mas, progress)
+}
+
+func (p *Postgres) TaxBatch(tds []tax.TaxDetails, workers int) ([]tax.TaxOutcome, error) {
+
+	mas, err := tax.CapsFor(tds, time.Now(), p.GetAllowances)
+	if err != nil {
+		return nil, err
+	}
+
+	return tax.CalculateTaxes(tds, mas, workers), nil
+}
+
+func (p *Postgres) TaxRecommendation(td tax.TaxDetails) (tax.RecommendationResponse, error) {
+
+	ma, err := p.GetAllowances(td.CapsAt(time.Now()))
+	if err != nil {
+		return tax.RecommendationResponse{}, err
+	}
+
+	return td.Recommend(ma), nil
+}
+
+func (p *Postgres) TaxCurve(cr tax.CurveRequest) (tax.CurveResponse, error) {
+
+	ma, err := p.GetAllowances(tax.TaxDetails{}.CapsAt(time.Now()))
+	if err != nil {
+		return tax.CurveResponse{}, err
+	}
 
-	for _, td := range tds {
+	return cr.CalculateCurve(ma), nil
+}
 
-		if err := td.ValidateTaxDetails(); err != nil {
-			return []tax.Taxes{}, err
-		}
+func (p *Postgres) TaxSummary(td tax.TaxDetails) (tax.TaxSummary, error) {
 
-		netIncome := td.CalculateNetIncome(ma)
+	ma, err := p.GetAllowances(td.CapsAt(time.Now()))
+	if err != nil {
+		return tax.TaxSummary{}, err
+	}
 
-		result := tax.CalculateTax(netIncome, td.WHT)
+	return td.Summarize(ma), nil
+}
 
-		tr := tax.Taxes{
-			TotalIncome: td.TotalIncome,
-			Tax:         result.Tax,
-			TaxRefund:   result.TaxRefund,
-		}
+func (p *Postgres) TaxSummaries(tds []tax.TaxDetails) ([]tax.TaxSummary, error) {
 
-		taxes = append(taxes, tr)
+	mas, err := tax.CapsFor(tds, time.Now(), p.GetAllowances)
+	if err != nil {
+		return []tax.TaxSummary{}, err
+	}
 
+	ts := []tax.TaxSummary{}
+	for i, td := range tds {
+		ts = append(ts, td.Summarize(mas[i]))
 	}
 
-	return taxes, nil
+	return ts, nil
 }
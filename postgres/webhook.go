@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/varissara-wo/assessment-tax/webhook"
+)
+
+const subscriptionColumns = `id, url, events, secret, created_by, created_at, deleted_at`
+
+const deliveryColumns = `id, subscription_id, event_id, event, attempt, status_code, error, succeeded, duration_ms, at`
+
+func scanSubscription(s scanner) (webhook.Subscription, error) {
+	var ws webhook.Subscription
+	err := s.Scan(&ws.ID, &ws.URL, pq.Array(&ws.Events), &ws.Secret, &ws.CreatedBy, &ws.CreatedAt, &ws.DeletedAt)
+	return ws, err
+}
+
+func scanDelivery(s scanner) (webhook.Delivery, error) {
+	var d webhook.Delivery
+	err := s.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.Event, &d.Attempt, &d.StatusCode, &d.Error, &d.Succeeded, &d.DurationMS, &d.At)
+	return d, err
+}
+
+func (p *Postgres) CreateSubscription(ws webhook.Subscription) (webhook.Subscription, error) {
+	return scanSubscription(p.Db.QueryRow(`INSERT INTO webhook_subscriptions (url, events, secret, created_by)
+		VALUES ($1, $2, $3, $4) RETURNING `+subscriptionColumns, ws.URL, pq.Array(ws.Events), ws.Secret, ws.CreatedBy))
+}
+
+func (p *Postgres) Subscriptions() ([]webhook.Subscription, error) {
+	rows, err := p.Db.Query(`SELECT ` + subscriptionColumns + ` FROM webhook_subscriptions ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ss := []webhook.Subscription{}
+	for rows.Next() {
+		ws, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		ss = append(ss, ws)
+	}
+
+	return ss, rows.Err()
+}
+
+func (p *Postgres) DeleteSubscription(id int) (webhook.Subscription, error) {
+	ws, err := scanSubscription(p.Db.QueryRow(`UPDATE webhook_subscriptions SET deleted_at = COALESCE(deleted_at, now())
+		WHERE id = $1 RETURNING `+subscriptionColumns, id))
+	if err == sql.ErrNoRows {
+		return ws, webhook.ErrNotFound
+	}
+	return ws, err
+}
+
+func (p *Postgres) RecordDelivery(d webhook.Delivery) (webhook.Delivery, error) {
+	return scanDelivery(p.Db.QueryRow(`INSERT INTO webhook_deliveries (subscription_id, event_id, event, attempt, status_code, error, succeeded, duration_ms, at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING `+deliveryColumns,
+		d.SubscriptionID, d.EventID, d.Event, d.Attempt, d.StatusCode, d.Error, d.Succeeded, d.DurationMS, d.At))
+}
+
+func (p *Postgres) Deliveries(subscriptionID, limit int) ([]webhook.Delivery, error) {
+	var exists bool
+	if err := p.Db.QueryRow(`SELECT EXISTS (SELECT 1 FROM webhook_subscriptions WHERE id = $1)`, subscriptionID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, webhook.ErrNotFound
+	}
+
+	rows, err := p.Db.Query(`SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE subscription_id = $1 ORDER BY id DESC LIMIT $2`, subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ds := []webhook.Delivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		ds = append(ds, d)
+	}
+
+	return ds, rows.Err()
+}
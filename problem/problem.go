@@ -0,0 +1,274 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/i18n"
+)
+
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+const (
+	CodeInvalidBody      = "REQUEST_BODY_INVALID"
+	CodeValidationFailed = "VALIDATION_FAILED"
+
+	keyTypeMismatch = "REQUEST_BODY_TYPE_MISMATCH"
+)
+
+// errInternal replaces uncoded errors on 5xx responses, whose text may come
+// from the database or another dependency.
+var errInternal = New("INTERNAL_ERROR", "", "internal server error")
+
+type Error struct {
+	Code    string `json:"code"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+
+	key    string
+	text   string
+	args   []interface{}
+	labels []*Error
+}
+
+type Errors []*Error
+
+type Problem struct {
+	Type     string   `json:"type"`
+	Title    string   `json:"title"`
+	Status   int      `json:"status"`
+	Code     string   `json:"code"`
+	Detail   string   `json:"detail"`
+	Field    string   `json:"field,omitempty"`
+	Instance string   `json:"instance,omitempty"`
+	Details  []*Error `json:"details,omitempty"`
+}
+
+func New(code, field, message string) *Error {
+	return newf(code, code, field, message)
+}
+
+func Newf(code, field, format string, args ...interface{}) *Error {
+	return newf(code, code, field, format, args...)
+}
+
+func newf(code, key, field, format string, args ...interface{}) *Error {
+	text := format
+	if len(args) > 0 {
+		text = fmt.Sprintf(format, args...)
+	}
+	return &Error{Code: code, Field: field, Message: text, key: key, text: text, args: args}
+}
+
+func (e *Error) Localize(l i18n.Lang) string {
+	var b strings.Builder
+	for _, label := range e.labels {
+		b.WriteString(label.Localize(l))
+	}
+
+	text := e.text
+	if s, ok := l.Lookup(e.key); ok {
+		text = s
+		if len(e.args) > 0 {
+			text = fmt.Sprintf(s, e.args...)
+		}
+	}
+	b.WriteString(text)
+
+	return b.String()
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (es Errors) Error() string {
+	messages := make([]string, len(es))
+	for i, e := range es {
+		messages[i] = e.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (es *Errors) Add(err error) {
+	if err == nil {
+		return
+	}
+
+	var many Errors
+	if errors.As(err, &many) {
+		*es = append(*es, many...)
+		return
+	}
+
+	var one *Error
+	if errors.As(err, &one) {
+		*es = append(*es, one)
+		return
+	}
+
+	*es = append(*es, &Error{Message: err.Error(), text: err.Error()})
+}
+
+func (es Errors) Err() error {
+	if len(es) == 0 {
+		return nil
+	}
+	return es
+}
+
+func Nest(err error, field string, label *Error) error {
+	var es Errors
+	es.Add(err)
+
+	nested := make(Errors, len(es))
+	for i, e := range es {
+		f := field
+		if e.Field != "" {
+			f += "." + e.Field
+		}
+		n := *e
+		n.Field = f
+		if label != nil {
+			n.Message = label.Message + e.Message
+			n.labels = append([]*Error{label}, e.labels...)
+		}
+		nested[i] = &n
+	}
+	return nested.Err()
+}
+
+func From(status int, err error) Problem {
+	return Localized(i18n.Default, status, err)
+}
+
+func Localized(l i18n.Lang, status int, err error) Problem {
+	p := Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Code:   statusCode(status),
+	}
+
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		return fromHTTPError(l, p, he)
+	}
+
+	if status >= http.StatusInternalServerError && !coded(err) {
+		err = errInternal
+	}
+
+	var added Errors
+	added.Add(err)
+
+	es := make(Errors, len(added))
+	for i, e := range added {
+		es[i] = &Error{Code: e.Code, Field: e.Field, Message: e.Localize(l)}
+		if es[i].Code == "" {
+			es[i].Code = p.Code
+		}
+	}
+
+	switch len(es) {
+	case 0:
+		p.Detail = p.Title
+	case 1:
+		p.Code = es[0].Code
+		p.Field = es[0].Field
+		p.Detail = es[0].Message
+		p.Details = es
+	default:
+		p.Code = CodeValidationFailed
+		p.Detail = Newf(CodeValidationFailed, "", "request has %d validation errors", len(es)).Localize(l)
+		p.Details = es
+	}
+
+	return p
+}
+
+func fromHTTPError(l i18n.Lang, p Problem, he *echo.HTTPError) Problem {
+	p.Status = he.Code
+	p.Title = http.StatusText(he.Code)
+	p.Code = statusCode(he.Code)
+	p.Detail = fmt.Sprint(he.Message)
+
+	if he.Code != http.StatusBadRequest {
+		return p
+	}
+
+	p.Code = CodeInvalidBody
+
+	var ute *json.UnmarshalTypeError
+	var se *json.SyntaxError
+	switch {
+	case errors.As(he.Internal, &ute):
+		p.Field = ute.Field
+		p.Detail = newf(CodeInvalidBody, keyTypeMismatch, ute.Field, "%s must be of type %s", ute.Field, ute.Type.String()).Localize(l)
+	case errors.As(he.Internal, &se), errors.Is(he.Internal, io.ErrUnexpectedEOF):
+		p.Detail = New(CodeInvalidBody, "", "request body is not valid JSON").Localize(l)
+	}
+
+	p.Details = []*Error{{Code: p.Code, Field: p.Field, Message: p.Detail}}
+	return p
+}
+
+func coded(err error) bool {
+	var one *Error
+	var many Errors
+	return errors.As(err, &one) || errors.As(err, &many)
+}
+
+func statusCode(status int) string {
+	switch status {
+	case http.StatusTooManyRequests:
+		return "RATE_LIMITED"
+	case http.StatusInternalServerError:
+		return "INTERNAL_ERROR"
+	}
+
+	text := http.StatusText(status)
+	if text == "" {
+		return "ERROR"
+	}
+	return strings.ToUpper(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text))
+}
+
+func Respond(c echo.Context, status int, err error) error {
+	if status >= http.StatusInternalServerError && err != nil && !coded(err) {
+		c.Logger().Error(err)
+	}
+
+	l := i18n.From(c)
+	p := Localized(l, status, err)
+	p.Instance = c.Request().URL.Path
+	c.Response().Header().Set(i18n.HeaderContentLanguage, string(l))
+
+	b, merr := json.Marshal(p)
+	if merr != nil {
+		return merr
+	}
+
+	return c.Blob(p.Status, MIMEApplicationProblemJSON, b)
+}
+
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	status := http.StatusInternalServerError
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		status = he.Code
+	}
+
+	if rerr := Respond(c, status, err); rerr != nil {
+		c.Logger().Error(rerr)
+	}
+}
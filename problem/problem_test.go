@@ -0,0 +1,257 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/i18n"
+)
+
+var (
+	errTooLow  = New("AMOUNT_TOO_LOW", "amount", "amount is too low")
+	errBadDate = New("DATE_INVALID", "date", "date is invalid")
+)
+
+func TestErrors(t *testing.T) {
+	t.Run("should return nil if nothing was added", func(t *testing.T) {
+		var es Errors
+		es.Add(nil)
+
+		if err := es.Err(); err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+	})
+
+	t.Run("should flatten added errors", func(t *testing.T) {
+		var inner Errors
+		inner.Add(errTooLow)
+		inner.Add(errBadDate)
+
+		var es Errors
+		es.Add(inner.Err())
+		es.Add(errors.New("plain error"))
+
+		if len(es) != 3 || es[2].Message != "plain error" {
+			t.Errorf("expected 3 flattened errors but got %v", es)
+		}
+
+		want := "amount is too low; date is invalid; plain error"
+		if es.Error() != want {
+			t.Errorf("expected %v but got %v", want, es.Error())
+		}
+	})
+
+	t.Run("should nest fields and label messages", func(t *testing.T) {
+		var es Errors
+		es.Add(errTooLow)
+		es.Add(errBadDate)
+
+		var got Errors
+		got.Add(Nest(es.Err(), "records[0]", Newf("RECORD", "", "record %d: ", 1)))
+
+		want := []Error{
+			{Code: "AMOUNT_TOO_LOW", Field: "records[0].amount", Message: "record 1: amount is too low"},
+			{Code: "DATE_INVALID", Field: "records[0].date", Message: "record 1: date is invalid"},
+		}
+		if len(got) != len(want) {
+			t.Fatalf("expected %v but got %v", want, got)
+		}
+		for i, e := range got {
+			if e.Code != want[i].Code || e.Field != want[i].Field || e.Message != want[i].Message {
+				t.Errorf("expected %+v but got %+v", want[i], *e)
+			}
+		}
+
+		if errTooLow.Field != "amount" {
+			t.Errorf("expected original error to be unchanged but got %v", errTooLow.Field)
+		}
+	})
+
+	t.Run("should return nil when nesting nil", func(t *testing.T) {
+		if err := Nest(nil, "records[0]", nil); err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+	})
+}
+
+func TestFrom(t *testing.T) {
+	t.Run("should use the code and field of a single error", func(t *testing.T) {
+		got := From(http.StatusBadRequest, errTooLow)
+
+		if got.Code != "AMOUNT_TOO_LOW" || got.Field != "amount" || got.Detail != "amount is too low" || len(got.Details) != 1 {
+			t.Errorf("expected AMOUNT_TOO_LOW on amount but got %+v", got)
+		}
+
+		if got.Type != "about:blank" || got.Title != "Bad Request" || got.Status != http.StatusBadRequest {
+			t.Errorf("expected bad request problem but got %+v", got)
+		}
+	})
+
+	t.Run("should list every error if there are several", func(t *testing.T) {
+		var es Errors
+		es.Add(errTooLow)
+		es.Add(errBadDate)
+
+		got := From(http.StatusBadRequest, es)
+
+		if got.Code != CodeValidationFailed || len(got.Details) != 2 || got.Details[1].Code != "DATE_INVALID" {
+			t.Errorf("expected %v with 2 details but got %+v", CodeValidationFailed, got)
+		}
+	})
+
+	t.Run("should hide the text of an uncoded error on a 5xx response", func(t *testing.T) {
+		got := From(http.StatusInternalServerError, errors.New("pq: relation \"allowances\" does not exist"))
+
+		if got.Code != "INTERNAL_ERROR" || got.Detail != "internal server error" {
+			t.Errorf("expected INTERNAL_ERROR with a generic detail but got %+v", got)
+		}
+	})
+
+	t.Run("should keep a coded error on a 5xx response", func(t *testing.T) {
+		got := From(http.StatusInternalServerError, New("AUDIT_WRITE_FAILED", "", "the change could not be recorded in the audit log"))
+
+		if got.Code != "AUDIT_WRITE_FAILED" {
+			t.Errorf("expected AUDIT_WRITE_FAILED but got %+v", got)
+		}
+	})
+
+	t.Run("should use a code for the status of an uncoded error", func(t *testing.T) {
+		got := From(http.StatusConflict, errors.New("already exists"))
+
+		if got.Code != "CONFLICT" || got.Detail != "already exists" {
+			t.Errorf("expected CONFLICT but got %+v", got)
+		}
+	})
+
+	t.Run("should report the field of a body with the wrong type", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": "invalid"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		c := e.NewContext(req, httptest.NewRecorder())
+
+		var body struct {
+			Amount float64 `json:"amount"`
+		}
+		got := From(http.StatusBadRequest, c.Bind(&body))
+
+		if got.Code != CodeInvalidBody || got.Field != "amount" {
+			t.Errorf("expected %v on amount but got %+v", CodeInvalidBody, got)
+		}
+	})
+
+	t.Run("should report a body that is not json", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount":`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		c := e.NewContext(req, httptest.NewRecorder())
+
+		var body struct {
+			Amount float64 `json:"amount"`
+		}
+		got := From(http.StatusBadRequest, c.Bind(&body))
+
+		if got.Code != CodeInvalidBody || got.Detail != "request body is not valid JSON" {
+			t.Errorf("expected %v but got %+v", CodeInvalidBody, got)
+		}
+	})
+}
+
+func TestRespond(t *testing.T) {
+	t.Run("should write problem json", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		Respond(c, http.StatusBadRequest, errTooLow)
+
+		if ct := rec.Header().Get(echo.HeaderContentType); ct != MIMEApplicationProblemJSON {
+			t.Errorf("expected content type %v but got %v", MIMEApplicationProblemJSON, ct)
+		}
+
+		var got Problem
+		json.Unmarshal(rec.Body.Bytes(), &got)
+
+		if got.Instance != "/tax/calculations" || got.Code != "AMOUNT_TOO_LOW" {
+			t.Errorf("expected problem for /tax/calculations but got %+v", got)
+		}
+	})
+
+	t.Run("should handle echo errors such as unknown routes", func(t *testing.T) {
+		e := echo.New()
+		e.HTTPErrorHandler = HTTPErrorHandler
+		rec := httptest.NewRecorder()
+
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/unknown", nil))
+
+		var got Problem
+		json.Unmarshal(rec.Body.Bytes(), &got)
+
+		if rec.Code != http.StatusNotFound || got.Code != "NOT_FOUND" {
+			t.Errorf("expected NOT_FOUND but got %v %+v", rec.Code, got)
+		}
+	})
+
+	t.Run("should answer in the language of the request", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount":`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(i18n.HeaderAcceptLanguage, "th-TH,th;q=0.9,en;q=0.8")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		var body struct {
+			Amount float64 `json:"amount"`
+		}
+		if err := Respond(c, http.StatusBadRequest, c.Bind(&body)); err != nil {
+			t.Fatal(err)
+		}
+
+		var got Problem
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+
+		if want := i18n.Thai.T(CodeInvalidBody); got.Detail != want {
+			t.Errorf("expected %v but got %v", want, got.Detail)
+		}
+
+		if lang := rec.Header().Get(i18n.HeaderContentLanguage); lang != "th" {
+			t.Errorf("expected content language th but got %v", lang)
+		}
+	})
+}
+
+func TestLocalized(t *testing.T) {
+	t.Run("should translate coded errors and their labels", func(t *testing.T) {
+		err := Nest(New("TAX_CSV_VALUE_EMPTY", "totalIncome", "invalid CSV data value cannot be empty"), "rows[0]", Newf("TAX_CSV_ROW", "", "row %d: ", 1))
+
+		got := Localized(i18n.Thai, http.StatusBadRequest, err)
+
+		want := i18n.Thai.T("TAX_CSV_ROW", 1) + i18n.Thai.T("TAX_CSV_VALUE_EMPTY")
+		if got.Detail != want || got.Details[0].Message != want {
+			t.Errorf("expected %v but got %+v", want, got)
+		}
+	})
+
+	t.Run("should format arguments into the translation", func(t *testing.T) {
+		got := Localized(i18n.Thai, http.StatusTooManyRequests, Newf("RATE_LIMITED", "", "too many requests, retry after %d seconds", 30))
+
+		if want := i18n.Thai.T("RATE_LIMITED", 30); got.Detail != want {
+			t.Errorf("expected %v but got %v", want, got.Detail)
+		}
+	})
+
+	t.Run("should keep the message of an error without a translation", func(t *testing.T) {
+		got := Localized(i18n.Thai, http.StatusBadRequest, errTooLow)
+
+		if got.Detail != "amount is too low" {
+			t.Errorf("expected amount is too low but got %v", got.Detail)
+		}
+	})
+}
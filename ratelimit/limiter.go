@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+type window struct {
+	start time.Time
+	count int
+}
+
+type Limiter struct {
+	limit   Limit
+	skipper func(echo.Context) bool
+	now     func() time.Time
+
+	mu      sync.Mutex
+	windows map[string]window
+	sweepAt time.Time
+}
+
+type Option func(*Limiter)
+
+func New(limit Limit, opts ...Option) *Limiter {
+	l := &Limiter{limit: limit, now: time.Now, windows: map[string]window{}}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+func WithSkipper(skipper func(echo.Context) bool) Option {
+	return func(l *Limiter) {
+		l.skipper = skipper
+	}
+}
+
+func (l *Limiter) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !l.limit.Enabled() || (l.skipper != nil && l.skipper(c)) {
+			return next(c)
+		}
+
+		remaining, reset, ok := l.take(ClientKey(c))
+
+		header := c.Response().Header()
+		header.Set(HeaderLimit, strconv.Itoa(l.limit.Requests))
+		header.Set(HeaderRemaining, strconv.Itoa(remaining))
+		header.Set(HeaderReset, strconv.Itoa(seconds(reset)))
+		header.Set(HeaderPolicy, l.limit.policy())
+
+		if !ok {
+			header.Set(HeaderRetry, strconv.Itoa(seconds(reset)))
+			return problem.Respond(c, http.StatusTooManyRequests, RateLimited(reset))
+		}
+
+		return next(c)
+	}
+}
+
+// Allow counts a request for key and returns when the window resets if the
+// request is over the limit.
+func (l *Limiter) Allow(key string) (time.Duration, bool) {
+	if !l.limit.Enabled() {
+		return 0, true
+	}
+	_, reset, ok := l.take(key)
+	return reset, ok
+}
+
+func (l *Limiter) take(key string) (int, time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	w := l.windows[key]
+	if now.Sub(w.start) >= l.limit.Window {
+		w = window{start: now}
+	}
+
+	reset := w.start.Add(l.limit.Window).Sub(now)
+	if w.count >= l.limit.Requests {
+		return 0, reset, false
+	}
+
+	w.count++
+	l.windows[key] = w
+	return l.limit.Requests - w.count, reset, true
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	if now.Before(l.sweepAt) {
+		return
+	}
+
+	for k, w := range l.windows {
+		if now.Sub(w.start) >= l.limit.Window {
+			delete(l.windows, k)
+		}
+	}
+	l.sweepAt = now.Add(l.limit.Window)
+}
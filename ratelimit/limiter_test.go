@@ -0,0 +1,203 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/apikey"
+)
+
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func serve(l *Limiter, method, ip string, key *apikey.Key) *httptest.ResponseRecorder {
+	e := echo.New()
+	req := httptest.NewRequest(method, "/tax/calculations", nil)
+	req.Header.Set(echo.HeaderXRealIP, ip)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	if key != nil {
+		c.Set(apikey.KeyContextKey, *key)
+	}
+
+	l.Middleware(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})(c)
+
+	return rec
+}
+
+func TestLimiter(t *testing.T) {
+	t.Run("should allow requests up to the limit and then return 429", func(t *testing.T) {
+		clk := &clock{t: time.Date(2025, 4, 8, 10, 0, 0, 0, time.UTC)}
+		l := New(Limit{Requests: 2, Window: time.Minute})
+		l.now = clk.now
+
+		wantRemaining := []string{"1", "0"}
+		for _, want := range wantRemaining {
+			rec := serve(l, http.MethodPost, "10.0.0.1", nil)
+
+			if rec.Code != http.StatusOK {
+				t.Errorf("expected status code %v but got %v", http.StatusOK, rec.Code)
+			}
+
+			if got := rec.Header().Get(HeaderRemaining); got != want {
+				t.Errorf("expected remaining %v but got %v", want, got)
+			}
+		}
+
+		clk.t = clk.t.Add(20 * time.Second)
+		rec := serve(l, http.MethodPost, "10.0.0.1", nil)
+
+		if rec.Code != http.StatusTooManyRequests {
+			t.Errorf("expected status code %v but got %v", http.StatusTooManyRequests, rec.Code)
+		}
+
+		if got := rec.Header().Get(HeaderRetry); got != "40" {
+			t.Errorf("expected retry after 40 but got %v", got)
+		}
+
+		if got := rec.Header().Get(HeaderPolicy); got != "2;w=60" {
+			t.Errorf("expected policy 2;w=60 but got %v", got)
+		}
+	})
+
+	t.Run("should start a new window after the window has passed", func(t *testing.T) {
+		clk := &clock{t: time.Date(2025, 4, 8, 10, 0, 0, 0, time.UTC)}
+		l := New(Limit{Requests: 1, Window: time.Minute})
+		l.now = clk.now
+
+		serve(l, http.MethodPost, "10.0.0.1", nil)
+		clk.t = clk.t.Add(time.Minute)
+		rec := serve(l, http.MethodPost, "10.0.0.1", nil)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status code %v but got %v", http.StatusOK, rec.Code)
+		}
+	})
+
+	t.Run("should count clients separately by api key or ip", func(t *testing.T) {
+		l := New(Limit{Requests: 1, Window: time.Minute})
+
+		serve(l, http.MethodPost, "10.0.0.1", nil)
+		byIP := serve(l, http.MethodPost, "10.0.0.2", nil)
+		byKey := serve(l, http.MethodPost, "10.0.0.1", &apikey.Key{ID: 7})
+
+		if byIP.Code != http.StatusOK || byKey.Code != http.StatusOK {
+			t.Errorf("expected other clients to be allowed but got %v and %v", byIP.Code, byKey.Code)
+		}
+	})
+
+	t.Run("should count a presented api key under the ip before it is verified", func(t *testing.T) {
+		l := New(Limit{Requests: 1, Window: time.Minute})
+
+		keyed := func(ip, secret string) *httptest.ResponseRecorder {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/tax/calculations", nil)
+			req.Header.Set(echo.HeaderXRealIP, ip)
+			req.Header.Set(apikey.HeaderAPIKey, secret)
+			rec := httptest.NewRecorder()
+			l.Middleware(func(c echo.Context) error { return c.NoContent(http.StatusOK) })(e.NewContext(req, rec))
+			return rec
+		}
+
+		keyed("10.0.0.1", "ktx_one")
+		otherKey := keyed("10.0.0.1", "ktx_two")
+		otherIP := keyed("10.0.0.2", "ktx_one")
+
+		if otherKey.Code != http.StatusTooManyRequests || otherIP.Code != http.StatusOK {
+			t.Errorf("expected another key from the same ip to be limited and another ip allowed but got %v and %v", otherKey.Code, otherIP.Code)
+		}
+	})
+
+	t.Run("should skip requests matched by the skipper", func(t *testing.T) {
+		l := New(Limit{Requests: 1, Window: time.Minute}, WithSkipper(SafeMethods))
+
+		serve(l, http.MethodPost, "10.0.0.1", nil)
+		rec := serve(l, http.MethodGet, "10.0.0.1", nil)
+
+		if rec.Code != http.StatusOK || rec.Header().Get(HeaderLimit) != "" {
+			t.Errorf("expected unlimited GET but got %v with limit %v", rec.Code, rec.Header().Get(HeaderLimit))
+		}
+	})
+
+	t.Run("should not limit if limit is disabled", func(t *testing.T) {
+		l := New(Limit{})
+
+		for i := 0; i < 3; i++ {
+			if rec := serve(l, http.MethodPost, "10.0.0.1", nil); rec.Code != http.StatusOK {
+				t.Errorf("expected status code %v but got %v", http.StatusOK, rec.Code)
+			}
+		}
+	})
+}
+
+func TestIPExtractor(t *testing.T) {
+	testCases := []struct {
+		name    string
+		trusted string
+		remote  string
+		want    string
+	}{
+		{"should use the peer address when no proxy is trusted", "", "10.0.0.9:4000", "10.0.0.9"},
+		{"should use the forwarded address from a trusted proxy", "10.0.0.0/8", "10.0.0.9:4000", "203.0.113.7"},
+		{"should ignore the forwarded address from an untrusted peer", "10.0.0.0/8", "198.51.100.1:4000", "198.51.100.1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			extract, err := IPExtractor(tc.trusted)
+			if err != nil {
+				t.Fatalf("expected nil but got %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/tax/calculations", nil)
+			req.RemoteAddr = tc.remote
+			req.Header.Set(echo.HeaderXForwardedFor, "203.0.113.7")
+
+			if got := extract(req); got != tc.want {
+				t.Errorf("expected %v but got %v", tc.want, got)
+			}
+		})
+	}
+
+	t.Run("should return an error for an invalid range", func(t *testing.T) {
+		if _, err := IPExtractor("10.0.0.0/8, proxy"); err == nil || err.Error() != ErrInvalidTrustedProxies {
+			t.Errorf("expected %v but got %v", ErrInvalidTrustedProxies, err)
+		}
+	})
+}
+
+func TestParseLimit(t *testing.T) {
+	testCases := []struct {
+		in      string
+		want    Limit
+		wantErr bool
+	}{
+		{"60/1m", Limit{Requests: 60, Window: time.Minute}, false},
+		{"10 / 1h", Limit{Requests: 10, Window: time.Hour}, false},
+		{"", Limit{}, false},
+		{"0", Limit{}, false},
+		{"60", Limit{}, true},
+		{"a/1m", Limit{}, true},
+		{"60/forever", Limit{}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := ParseLimit(tc.in)
+
+			if (err != nil) != tc.wantErr || got != tc.want {
+				t.Errorf("expected %v, error %v but got %v, %v", tc.want, tc.wantErr, got, err)
+			}
+		})
+	}
+}
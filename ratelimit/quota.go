@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type usage struct {
+	day  string
+	rows int
+}
+
+type Quota struct {
+	rows int
+	now  func() time.Time
+
+	mu   sync.Mutex
+	used map[string]usage
+}
+
+func NewQuota(rows int) *Quota {
+	return &Quota{rows: rows, now: time.Now, used: map[string]usage{}}
+}
+
+func (q *Quota) Consume(c echo.Context, rows int) bool {
+	if q.rows <= 0 {
+		return true
+	}
+
+	remaining, reset, ok := q.take(ClientKey(c), rows)
+
+	// The quota is a second policy next to the request limit. The limit,
+	// remaining and reset headers describe whichever is closer to running out.
+	header := c.Response().Header()
+	header.Add(HeaderPolicy, Limit{Requests: q.rows, Window: 24 * time.Hour}.policy())
+	if current, err := strconv.Atoi(header.Get(HeaderRemaining)); !ok || err != nil || remaining < current {
+		header.Set(HeaderLimit, strconv.Itoa(q.rows))
+		header.Set(HeaderRemaining, strconv.Itoa(remaining))
+		header.Set(HeaderReset, strconv.Itoa(seconds(reset)))
+	}
+
+	if !ok {
+		header.Set(HeaderRetry, strconv.Itoa(seconds(reset)))
+	}
+
+	return ok
+}
+
+// Take uses rows of the key's daily quota and returns when the quota resets
+// if the rows do not fit.
+func (q *Quota) Take(key string, rows int) (time.Duration, bool) {
+	if q.rows <= 0 {
+		return 0, true
+	}
+	_, reset, ok := q.take(key, rows)
+	return reset, ok
+}
+
+func (q *Quota) take(key string, rows int) (int, time.Duration, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now().In(bangkok)
+	day := now.Format("2006-01-02")
+	y, m, d := now.Date()
+	reset := time.Date(y, m, d+1, 0, 0, 0, 0, bangkok).Sub(now)
+
+	u := q.used[key]
+	if u.day != day {
+		for k, v := range q.used {
+			if v.day != day {
+				delete(q.used, k)
+			}
+		}
+		u = usage{day: day}
+	}
+
+	if u.rows+rows > q.rows {
+		return q.rows - u.rows, reset, false
+	}
+
+	u.rows += rows
+	q.used[key] = u
+	return q.rows - u.rows, reset, true
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func consume(q *Quota, rows int) (*httptest.ResponseRecorder, bool) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", nil)
+	req.Header.Set(echo.HeaderXRealIP, "10.0.0.1")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	return rec, q.Consume(c, rows)
+}
+
+func TestQuota(t *testing.T) {
+	t.Run("should reject rows over the daily quota until midnight in Bangkok", func(t *testing.T) {
+		clk := &clock{t: time.Date(2025, 4, 8, 22, 0, 0, 0, bangkok)}
+		q := NewQuota(100)
+		q.now = clk.now
+
+		if rec, ok := consume(q, 60); !ok || rec.Header().Get(HeaderRemaining) != "40" || rec.Header().Get(HeaderPolicy) != "100;w=86400" {
+			t.Errorf("expected 60 rows to be allowed with 40 remaining but got %v, %v", ok, rec.Header())
+		}
+
+		rec, ok := consume(q, 41)
+
+		if ok {
+			t.Errorf("expected 41 rows to be rejected")
+		}
+
+		if got := rec.Header().Get(HeaderRetry); got != "7200" {
+			t.Errorf("expected retry after 7200 but got %v", got)
+		}
+
+		if _, ok := consume(q, 40); !ok {
+			t.Errorf("expected remaining 40 rows to be allowed")
+		}
+
+		clk.t = time.Date(2025, 4, 9, 0, 0, 0, 0, bangkok)
+
+		if _, ok := consume(q, 100); !ok {
+			t.Errorf("expected quota to reset at midnight")
+		}
+	})
+
+	t.Run("should report the quota next to the request limit when it is closer to running out", func(t *testing.T) {
+		l := New(Limit{Requests: 10, Window: time.Minute})
+		q := NewQuota(100)
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		l.Middleware(func(c echo.Context) error {
+			q.Consume(c, 95)
+			return c.NoContent(http.StatusOK)
+		})(c)
+
+		if got := rec.Header().Values(HeaderPolicy); len(got) != 2 || got[0] != "10;w=60" || got[1] != "100;w=86400" {
+			t.Errorf("expected both policies but got %v", got)
+		}
+
+		if rec.Header().Get(HeaderLimit) != "100" || rec.Header().Get(HeaderRemaining) != "5" {
+			t.Errorf("expected the quota limit 100 with 5 remaining but got %v with %v", rec.Header().Get(HeaderLimit), rec.Header().Get(HeaderRemaining))
+		}
+	})
+
+	t.Run("should allow any rows if quota is disabled", func(t *testing.T) {
+		if _, ok := consume(NewQuota(0), 1000000); !ok {
+			t.Errorf("expected rows to be allowed")
+		}
+	})
+}
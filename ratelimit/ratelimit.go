@@ -0,0 +1,131 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/apikey"
+	"github.com/varissara-wo/assessment-tax/auth"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+const (
+	ErrInvalidLimit          = "limit must be in the form requests/duration, e.g. 60/1m"
+	ErrRateLimited           = "too many requests, retry after %d seconds"
+	ErrInvalidTrustedProxies = "trusted proxies must be a comma separated list of CIDR ranges"
+)
+
+const (
+	HeaderLimit     = "RateLimit-Limit"
+	HeaderRemaining = "RateLimit-Remaining"
+	HeaderReset     = "RateLimit-Reset"
+	HeaderPolicy    = "RateLimit-Policy"
+	HeaderRetry     = "Retry-After"
+)
+
+var bangkok = time.FixedZone("Asia/Bangkok", 7*60*60)
+
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+func ParseLimit(s string) (Limit, error) {
+	if s == "" || s == "0" {
+		return Limit{}, nil
+	}
+
+	n, d, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, errors.New(ErrInvalidLimit)
+	}
+
+	requests, err := strconv.Atoi(strings.TrimSpace(n))
+	if err != nil || requests < 0 {
+		return Limit{}, errors.New(ErrInvalidLimit)
+	}
+
+	window, err := time.ParseDuration(strings.TrimSpace(d))
+	if err != nil || window <= 0 {
+		return Limit{}, errors.New(ErrInvalidLimit)
+	}
+
+	return Limit{Requests: requests, Window: window}, nil
+}
+
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Window > 0
+}
+
+func (l Limit) policy() string {
+	return fmt.Sprintf("%d;w=%d", l.Requests, int(l.Window.Seconds()))
+}
+
+// ClientKey also works before the api key is verified, so limiters can run
+// ahead of the key lookup. Until then a presented key counts under the client
+// ip, so a new key on every request does not get a new bucket.
+func ClientKey(c echo.Context) string {
+	k, _ := apikey.KeyFrom(c)
+	if u := auth.Username(c); k.ID == 0 && u != "" {
+		return "admin:" + u
+	}
+	return PeerKey(k, c.RealIP())
+}
+
+// PeerKey is ClientKey for callers outside echo, such as the gRPC server, so
+// both count against the same limits.
+func PeerKey(k apikey.Key, ip string) string {
+	if k.ID != 0 {
+		return "key:" + strconv.Itoa(k.ID)
+	}
+	return "ip:" + ip
+}
+
+func RateLimited(reset time.Duration) error {
+	return problem.Newf("RATE_LIMITED", "", ErrRateLimited, seconds(reset))
+}
+
+// IPExtractor trusts X-Forwarded-For only from the given proxy ranges and
+// uses the peer address when there are none.
+func IPExtractor(trusted string) (echo.IPExtractor, error) {
+	var ranges []echo.TrustOption
+	for _, cidr := range strings.Split(trusted, ",") {
+		if strings.TrimSpace(cidr) == "" {
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, errors.New(ErrInvalidTrustedProxies)
+		}
+		ranges = append(ranges, echo.TrustIPRange(ipNet))
+	}
+
+	if len(ranges) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+
+	opts := append([]echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}, ranges...)
+	return echo.ExtractIPFromXFFHeader(opts...), nil
+}
+
+func SafeMethods(c echo.Context) bool {
+	switch c.Request().Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func seconds(d time.Duration) int {
+	s := int((d + time.Second - 1) / time.Second)
+	if s < 0 {
+		return 0
+	}
+	return s
+}
@@ -1,25 +1,43 @@
 package tax
 
 import (
+	"bytes"
 	"encoding/csv"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/i18n"
+	"github.com/varissara-wo/assessment-tax/problem"
 )
 
-type Err struct {
-	Message string `json:"message"`
-}
-
 type Storer interface {
 	TaxCalculation(TaxDetails) (TaxResponse, error)
-	TaxesCalculation([]TaxDetails) ([]Taxes, error)
+	TaxesCalculation([]TaxDetails, Progress) ([]Taxes, error)
+	TaxRecommendation(TaxDetails) (RecommendationResponse, error)
+	TaxCurve(CurveRequest) (CurveResponse, error)
+	TaxSummary(TaxDetails) (TaxSummary, error)
+	TaxSummaries([]TaxDetails) ([]TaxSummary, error)
+	TaxBatch([]TaxDetails, int) ([]TaxOutcome, error)
+}
+
+type RowQuota interface {
+	Consume(c echo.Context, rows int) bool
 }
 
 type Handler struct {
-	store Storer
+	store    Storer
+	formFont []byte
+	rowQuota RowQuota
+	runs     *Runs
+
+	batchLimit   int
+	batchWorkers int
 }
 
+type Option func(*Handler)
+
 type Taxes struct {
 	TotalIncome float64 `json:"totalIncome"`
 	Tax         float64 `json:"tax"`
@@ -30,54 +48,133 @@ type TaxesResponse struct {
 	Taxes []Taxes `json:"taxes"`
 }
 
-func New(store Storer) *Handler {
-	return &Handler{store: store}
+func New(store Storer, opts ...Option) *Handler {
+	h := &Handler{store: store, runs: NewRuns(RunConfig{}), batchLimit: DefaultBatchLimit, batchWorkers: 1}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func WithFormFont(font []byte) Option {
+	return func(h *Handler) {
+		h.formFont = font
+	}
+}
+
+func WithRowQuota(q RowQuota) Option {
+	return func(h *Handler) {
+		h.rowQuota = q
+	}
+}
+
+func WithBatchLimit(n int) Option {
+	return func(h *Handler) {
+		h.batchLimit = n
+	}
+}
+
+func WithBatchWorkers(n int) Option {
+	return func(h *Handler) {
+		h.batchWorkers = n
+	}
+}
+
+func WithRuns(rs *Runs) Option {
+	return func(h *Handler) {
+		h.runs = rs
+	}
+}
+
+func (h *Handler) consumeRows(c echo.Context, rows int) bool {
+	return h.rowQuota == nil || h.rowQuota.Consume(c, rows)
 }
 
 func (h *Handler) TaxHandler(c echo.Context) error {
 	td := TaxDetails{}
 
 	if err := c.Bind(&td); err != nil {
-		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+		return problem.Respond(c, http.StatusBadRequest, err)
 	}
 
 	if err := td.ValidateTaxDetails(); err != nil {
-		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+		return problem.Respond(c, http.StatusBadRequest, err)
 	}
 
 	t, err := h.store.TaxCalculation(td)
 
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+		return problem.Respond(c, http.StatusInternalServerError, err)
 	}
 
-	return c.JSON(http.StatusOK, t)
+	t, err = td.SchedulePayment(t)
+
+	if err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	return c.JSON(http.StatusOK, t.Localize(i18n.From(c)))
 }
 
-func (h *Handler) TaxCSVHandler(c echo.Context) error {
+func (h *Handler) upload(c echo.Context) ([]TaxDetails, int, error) {
 	file, err := c.FormFile("file")
 
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, http.StatusBadRequest, errMissingCSVFile
+	}
+
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+		return nil, http.StatusInternalServerError, err
 	}
 
 	src, err := file.Open()
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+		return nil, http.StatusInternalServerError, err
 	}
 	defer src.Close()
 
 	reader := csv.NewReader(src)
 	taxDetails, err := readCSV(reader)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+		return nil, http.StatusBadRequest, err
+	}
+
+	if !h.consumeRows(c, len(taxDetails)) {
+		return nil, http.StatusTooManyRequests, ErrRowQuota
+	}
+
+	return taxDetails, http.StatusOK, nil
+}
+
+func (h *Handler) TaxCSVHandler(c echo.Context) error {
+	run, status, err := h.runs.start(c)
+	if err != nil {
+		return problem.Respond(c, status, err)
+	}
+	if run.ID != "" {
+		c.Response().Header().Set(HeaderRunID, run.ID)
 	}
 
-	taxes, err := h.store.TaxesCalculation(taxDetails)
+	taxDetails, status, err := h.upload(c)
+	if err != nil {
+		run.complete(status, err)
+		return problem.Respond(c, status, err)
+	}
+	run.begin(len(taxDetails))
+
+	taxes, err := h.store.TaxesCalculation(taxDetails, run)
+
+	var rows problem.Errors
+	if errors.As(err, &rows) {
+		run.complete(http.StatusBadRequest, err)
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
 
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+		run.complete(http.StatusInternalServerError, err)
+		return problem.Respond(c, http.StatusInternalServerError, err)
 	}
+	run.complete(http.StatusOK, nil)
 
 	taxesResponse := TaxesResponse{
 		Taxes: taxes,
@@ -85,3 +182,197 @@ func (h *Handler) TaxCSVHandler(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, taxesResponse)
 }
+
+func (h *Handler) TaxRunEventsHandler(c echo.Context) error {
+	id := c.Param("id")
+	if err := ValidateRunID(id); err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	last, err := LastEventID(c.Request().Header.Get(HeaderLastEventID))
+	if err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	run, status, err := h.runs.subscribe(c, id)
+	if err != nil {
+		return problem.Respond(c, status, err)
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+
+	for {
+		evs, changed, done := run.since(last)
+		for _, ev := range evs {
+			if _, err := ev.WriteTo(res); err != nil {
+				return nil
+			}
+			last = ev.ID
+		}
+		res.Flush()
+
+		if done {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ticker.C:
+			if run.expired(time.Now()) {
+				return nil
+			}
+			res.Write([]byte(": keep-alive\n\n"))
+			res.Flush()
+		case <-c.Request().Context().Done():
+			return nil
+		case <-h.runs.closed:
+			return nil
+		}
+	}
+}
+
+func (h *Handler) TaxBatchHandler(c echo.Context) error {
+	br := BatchRequest{}
+
+	if err := c.Bind(&br); err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	if err := br.ValidateBatchRequest(h.batchLimit); err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	if !h.consumeRows(c, len(br.Items)) {
+		return problem.Respond(c, http.StatusTooManyRequests, ErrRowQuota)
+	}
+
+	outcomes, err := h.store.TaxBatch(br.TaxDetails(), h.batchWorkers)
+
+	if err != nil {
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, br.Results(outcomes, i18n.From(c)))
+}
+
+func (h *Handler) TaxV2Handler(c echo.Context) error {
+	cr := CalculationRequest{}
+
+	if err := c.Bind(&cr); err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	if err := cr.ValidateCalculationRequest(); err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	td := cr.TaxDetails()
+	ts, err := h.store.TaxSummary(td)
+
+	if err != nil {
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+
+	tr, err := td.SchedulePayment(ts.TaxResponse())
+
+	if err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	return c.JSON(http.StatusOK, ts.Calculation(tr, cr.Incomes, i18n.From(c)))
+}
+
+func (h *Handler) TaxCSVV2Handler(c echo.Context) error {
+	taxDetails, status, err := h.upload(c)
+	if err != nil {
+		return problem.Respond(c, status, err)
+	}
+
+	ts, err := h.store.TaxSummaries(taxDetails)
+
+	if err != nil {
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+
+	l := i18n.From(c)
+	cs := Calculations{Calculations: make([]Calculation, len(ts))}
+	for i, s := range ts {
+		cs.Calculations[i] = s.Calculation(s.TaxResponse(), nil, l)
+	}
+
+	return c.JSON(http.StatusOK, cs)
+}
+
+func (h *Handler) TaxRecommendationHandler(c echo.Context) error {
+	td := TaxDetails{}
+
+	if err := c.Bind(&td); err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	if err := td.ValidateRecommendation(); err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	r, err := h.store.TaxRecommendation(td)
+
+	if err != nil {
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, r.Localize(i18n.From(c)))
+}
+
+func (h *Handler) TaxCurveHandler(c echo.Context) error {
+	cr := CurveRequest{}
+
+	if err := c.Bind(&cr); err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	if err := cr.ValidateCurveRequest(); err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	curve, err := h.store.TaxCurve(cr)
+
+	if err != nil {
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, curve)
+}
+
+func (h *Handler) TaxFormHandler(c echo.Context) error {
+	td := TaxDetails{}
+
+	if err := c.Bind(&td); err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	if err := td.ValidateTaxDetails(); err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	ts, err := h.store.TaxSummary(td)
+
+	if err != nil {
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+
+	var b bytes.Buffer
+	if err := RenderPND91(&b, ts, td.Year(time.Now()), h.formFont); err != nil {
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+
+	return c.HTMLBlob(http.StatusOK, b.Bytes())
+}
@@ -8,17 +8,24 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/labstack/echo/v4"
 	"github.com/varissara-wo/assessment-tax/allowance"
+	"github.com/varissara-wo/assessment-tax/i18n"
+	"github.com/varissara-wo/assessment-tax/problem"
 )
 
 type stub struct {
-	TaxDetails TaxDetails
-	Tax        TaxResponse
-	err        error
-	Taxes      []Taxes
+	TaxDetails      TaxDetails
+	Tax             TaxResponse
+	err             error
+	Taxes           []Taxes
+	Recommendations RecommendationResponse
+	Curve           CurveResponse
+	Summary         TaxSummary
+	Summaries       []TaxSummary
 }
 
 type mockFileHeader struct {
@@ -35,8 +42,44 @@ func (s *stub) TaxCalculation(td TaxDetails) (TaxResponse, error) {
 	return s.Tax, s.err
 }
 
-func (s *stub) TaxesCalculation(tds []TaxDetails) ([]Taxes, error) {
-	return s.Taxes, s.err
+func (s *stub) TaxesCalculation(tds []TaxDetails, p Progress) ([]Taxes, error) {
+	if s.Taxes != nil || s.err != nil {
+		return s.Taxes, s.err
+	}
+	return CalculateRows(tds, mockCaps(tds), p)
+}
+
+func (s *stub) TaxRecommendation(td TaxDetails) (RecommendationResponse, error) {
+	return s.Recommendations, s.err
+}
+
+func (s *stub) TaxCurve(cr CurveRequest) (CurveResponse, error) {
+	return s.Curve, s.err
+}
+
+func (s *stub) TaxSummary(td TaxDetails) (TaxSummary, error) {
+	return s.Summary, s.err
+}
+
+func (s *stub) TaxSummaries(tds []TaxDetails) ([]TaxSummary, error) {
+	return s.Summaries, s.err
+}
+
+func (s *stub) TaxBatch(tds []TaxDetails, workers int) ([]TaxOutcome, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return CalculateTaxes(tds, mockCaps(tds), workers), nil
+}
+
+type quotaStub struct {
+	rows int
+	ok   bool
+}
+
+func (q *quotaStub) Consume(c echo.Context, rows int) bool {
+	q.rows = rows
+	return q.ok
 }
 
 func (m *mockFileHeader) Open() (multipart.File, error) {
@@ -78,11 +121,19 @@ func TestTaxHandler(t *testing.T) {
 			t.Errorf("expected error message but got %v", err)
 		}
 
-		var gotErr Err
+		var gotErr problem.Problem
 		json.Unmarshal(rec.Body.Bytes(), &gotErr)
 
-		if gotErr.Message != ErrInvalidTotalIncome {
-			t.Errorf("expected error message %v but got %v", ErrInvalidTotalIncome, gotErr.Message)
+		if gotErr.Detail != ErrInvalidTotalIncome {
+			t.Errorf("expected error message %v but got %v", ErrInvalidTotalIncome, gotErr.Detail)
+		}
+
+		if gotErr.Code != "TAX_TOTAL_INCOME_NEGATIVE" || gotErr.Field != "totalIncome" {
+			t.Errorf("expected TAX_TOTAL_INCOME_NEGATIVE on totalIncome but got %v on %v", gotErr.Code, gotErr.Field)
+		}
+
+		if ct := rec.Header().Get(echo.HeaderContentType); ct != problem.MIMEApplicationProblemJSON {
+			t.Errorf("expected content type %v but got %v", problem.MIMEApplicationProblemJSON, ct)
 		}
 
 		if rec.Code != http.StatusBadRequest {
@@ -118,11 +169,11 @@ func TestTaxHandler(t *testing.T) {
 			t.Errorf("got some error %v", err)
 		}
 
-		var gotErr Err
+		var gotErr problem.Problem
 		json.Unmarshal(rec.Body.Bytes(), &gotErr)
 
-		if gotErr.Message != st.err.Error() {
-			t.Errorf("expected error message %v but got %v", st.err, err)
+		if gotErr.Detail != "internal server error" || strings.Contains(rec.Body.String(), st.err.Error()) {
+			t.Errorf("expected a generic error message but got %v", gotErr.Detail)
 		}
 
 		if rec.Code != http.StatusInternalServerError {
@@ -170,9 +221,239 @@ func TestTaxHandler(t *testing.T) {
 			t.Errorf("got %v want %v", got, want)
 		}
 	})
+
+	t.Run("should return 200 and a payment schedule if the filing is late", func(t *testing.T) {
+		mockTaxDetails := TaxDetails{
+			TotalIncome: 500000.0,
+			WHT:         0.0,
+			FilingDate:  "2025-05-01",
+			DueDate:     "2025-04-08",
+		}
+
+		e := echo.New()
+		mockTaxDetailsJSON, _ := json.Marshal(mockTaxDetails)
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations", bytes.NewBuffer(mockTaxDetailsJSON))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		st := stub{Tax: TaxResponse{Tax: 29000.0, TaxLevel: []TaxBreakdown{}}}
+
+		p := New(&st)
+		err := p.TaxHandler(c)
+
+		if err != nil {
+			t.Errorf("got some error %v", err)
+		}
+
+		var got TaxResponse
+		json.Unmarshal(rec.Body.Bytes(), &got)
+
+		if got.PaymentSchedule == nil {
+			t.Fatalf("expected payment schedule but got nil")
+		}
+
+		if got.PaymentSchedule.MonthsLate != 1 || got.PaymentSchedule.Fine != lateFilingFine {
+			t.Errorf("expected 1 month late with fine but got %v", got.PaymentSchedule)
+		}
+	})
+
+	t.Run("should return 400 if installments are requested for tax below the threshold", func(t *testing.T) {
+		mockTaxDetails := TaxDetails{
+			TotalIncome:  250000.0,
+			WHT:          0.0,
+			Installments: true,
+		}
+
+		e := echo.New()
+		mockTaxDetailsJSON, _ := json.Marshal(mockTaxDetails)
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations", bytes.NewBuffer(mockTaxDetailsJSON))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		st := stub{Tax: TaxResponse{Tax: 3000.0, TaxLevel: []TaxBreakdown{}}}
+
+		p := New(&st)
+		err := p.TaxHandler(c)
+
+		if err != nil {
+			t.Errorf("got some error %v", err)
+		}
+
+		var gotErr problem.Problem
+		json.Unmarshal(rec.Body.Bytes(), &gotErr)
+
+		if gotErr.Detail != ErrInstallmentsBelowThreshold {
+			t.Errorf("expected error message %v but got %v", ErrInstallmentsBelowThreshold, gotErr.Detail)
+		}
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %v but got %v", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("should answer in thai if the client asks for it", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations", strings.NewReader(`{"totalIncome": -1.0}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(i18n.HeaderAcceptLanguage, "th-TH,th;q=0.9")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		p := New(&stub{})
+		p.TaxHandler(c)
+
+		var gotErr problem.Problem
+		json.Unmarshal(rec.Body.Bytes(), &gotErr)
+
+		if want := i18n.Thai.T("TAX_TOTAL_INCOME_NEGATIVE"); gotErr.Detail != want {
+			t.Errorf("expected error message %v but got %v", want, gotErr.Detail)
+		}
+	})
+
+	t.Run("should label tax levels in the language of the client", func(t *testing.T) {
+		for _, l := range i18n.Languages {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/tax/calculations", strings.NewReader(`{"totalIncome": 3000000.0}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(i18n.HeaderAcceptLanguage, string(l))
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			p := New(&stub{Tax: CalculateTax(3000000.0, 0.0)})
+			p.TaxHandler(c)
+
+			var got TaxResponse
+			json.Unmarshal(rec.Body.Bytes(), &got)
+
+			if len(got.TaxLevel) != len(taxBrackets) {
+				t.Fatalf("expected %v tax levels but got %v", len(taxBrackets), got.TaxLevel)
+			}
+
+			for i, tb := range got.TaxLevel {
+				if want := l.T(taxBrackets[i].Key); tb.Level != want {
+					t.Errorf("expected %v level %v but got %v", l, want, tb.Level)
+				}
+			}
+		}
+	})
 }
 
 func TestTaxCSV(t *testing.T) {
+	t.Run("should return 400 if the CSV file is invalid", func(t *testing.T) {
+		testCases := []struct {
+			name    string
+			csvData string
+			want    string
+		}{
+			{"invalid header", "income,wht,donation\n1000.0,200.0,300.0\n", ErrInvalidHeaderCSVData},
+			{"empty value", "totalIncome,wht,donation\n1000.0,,300.0\n", ErrorInvalidEmptyCSVData},
+		}
+
+		for _, tc := range testCases {
+			var buffer bytes.Buffer
+			writer := multipart.NewWriter(&buffer)
+			formFile, _ := writer.CreateFormFile("file", "file.csv")
+			formFile.Write([]byte(tc.csvData))
+			writer.Close()
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", &buffer)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			rec := httptest.NewRecorder()
+
+			New(&stub{}).TaxCSVHandler(e.NewContext(req, rec))
+
+			var gotErr problem.Problem
+			json.Unmarshal(rec.Body.Bytes(), &gotErr)
+
+			if rec.Code != http.StatusBadRequest || gotErr.Detail != tc.want {
+				t.Errorf("%s: expected status code %v with %v but got %v with %v", tc.name, http.StatusBadRequest, tc.want, rec.Code, gotErr.Detail)
+			}
+		}
+	})
+
+	t.Run("should return 400 with every invalid row", func(t *testing.T) {
+		var buffer bytes.Buffer
+		writer := multipart.NewWriter(&buffer)
+		formFile, _ := writer.CreateFormFile("file", "file.csv")
+		formFile.Write([]byte("totalIncome,wht,donation\n-1.0,0.0,0.0\n500000.0,0.0,0.0\n-2.0,0.0,0.0\n"))
+		writer.Close()
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", &buffer)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+
+		New(&stub{}).TaxCSVHandler(e.NewContext(req, rec))
+
+		var gotErr problem.Problem
+		json.Unmarshal(rec.Body.Bytes(), &gotErr)
+
+		if rec.Code != http.StatusBadRequest || len(gotErr.Details) != 2 || gotErr.Details[1].Field != "rows[2].totalIncome" {
+			t.Errorf("expected status code %v with 2 row errors but got %v %+v", http.StatusBadRequest, rec.Code, gotErr)
+		}
+	})
+
+	t.Run("should return 400 if no file is uploaded", func(t *testing.T) {
+		var buffer bytes.Buffer
+		writer := multipart.NewWriter(&buffer)
+		writer.WriteField("name", "file.csv")
+		writer.Close()
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", &buffer)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+
+		New(&stub{}).TaxCSVHandler(e.NewContext(req, rec))
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %v but got %v", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("should return 429 if the daily row quota is exceeded", func(t *testing.T) {
+		var buffer bytes.Buffer
+		writer := multipart.NewWriter(&buffer)
+		formFile, _ := writer.CreateFormFile("file", "file.csv")
+		formFile.Write([]byte(`totalIncome,wht,donation
+1000.0,200.0,300.0
+4000.0,500.0,600.0
+`))
+		writer.Close()
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", &buffer)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		q := &quotaStub{ok: false}
+		h := New(&stub{}, WithRowQuota(q))
+		err := h.TaxCSVHandler(c)
+
+		if err != nil {
+			t.Errorf("got some error %v", err)
+		}
+
+		if q.rows != 2 {
+			t.Errorf("expected 2 rows to be consumed but got %v", q.rows)
+		}
+
+		var gotErr problem.Problem
+		json.Unmarshal(rec.Body.Bytes(), &gotErr)
+
+		if gotErr.Detail != ErrRowQuotaExceeded {
+			t.Errorf("expected error message %v but got %v", ErrRowQuotaExceeded, gotErr.Detail)
+		}
+
+		if rec.Code != http.StatusTooManyRequests {
+			t.Errorf("expected status code %v but got %v", http.StatusTooManyRequests, rec.Code)
+		}
+	})
+
 	t.Run("should return 500 and an error message if the tax calculation fails", func(t *testing.T) {
 
 		var buffer bytes.Buffer
@@ -207,11 +488,11 @@ func TestTaxCSV(t *testing.T) {
 			t.Errorf("got some error %v", err)
 		}
 
-		var gotErr Err
+		var gotErr problem.Problem
 		json.Unmarshal(rec.Body.Bytes(), &gotErr)
 
-		if gotErr.Message != st.err.Error() {
-			t.Errorf("expected error message %v but got %v", st.err.Error(), gotErr.Message)
+		if gotErr.Detail != "internal server error" || strings.Contains(rec.Body.String(), st.err.Error()) {
+			t.Errorf("expected a generic error message but got %v", gotErr.Detail)
 		}
 
 		if rec.Code != http.StatusInternalServerError {
@@ -254,11 +535,11 @@ func TestTaxCSV(t *testing.T) {
 			t.Errorf("got unexpected error: %v", err)
 		}
 
-		var gotErr Err
+		var gotErr problem.Problem
 		json.Unmarshal(rec.Body.Bytes(), &gotErr)
 
-		if gotErr.Message != "file open error" {
-			t.Errorf("expected error message 'file open error' but got '%s'", gotErr.Message)
+		if gotErr.Detail != "internal server error" {
+			t.Errorf("expected error message 'internal server error' but got '%s'", gotErr.Detail)
 		}
 
 		if rec.Code != http.StatusInternalServerError {
@@ -319,7 +600,7 @@ func TestTaxCSV(t *testing.T) {
 			t.Errorf("got some error %v", err)
 		}
 
-		var gotErr Err
+		var gotErr problem.Problem
 		json.Unmarshal(rec.Body.Bytes(), &gotErr)
 
 		want := TaxesResponse{
@@ -339,3 +620,207 @@ func TestTaxCSV(t *testing.T) {
 	})
 
 }
+
+func TestTaxRecommendationHandler(t *testing.T) {
+	t.Run("should return 400 and an error if provide bad request payload", func(t *testing.T) {
+		mockTaxDetailsJSON, _ := json.Marshal(TaxDetails{TotalIncome: 100000.0, WHT: 200000.0})
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/recommendations", bytes.NewBuffer(mockTaxDetailsJSON))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		p := New(&stub{})
+		err := p.TaxRecommendationHandler(c)
+
+		if err != nil {
+			t.Errorf("got some error %v", err)
+		}
+
+		var gotErr problem.Problem
+		json.Unmarshal(rec.Body.Bytes(), &gotErr)
+
+		if gotErr.Detail != ErrInvalidWHT {
+			t.Errorf("expected error message %v but got %v", ErrInvalidWHT, gotErr.Detail)
+		}
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %v but got %v", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("should return 200 and recommendations", func(t *testing.T) {
+		mockTaxDetailsJSON, _ := json.Marshal(TaxDetails{TotalIncome: 500000.0})
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/recommendations", bytes.NewBuffer(mockTaxDetailsJSON))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		want := RecommendationResponse{
+			Tax:          29000.0,
+			MarginalRate: 0.1,
+			Recommendations: []Recommendation{
+				{AllowanceType: allowance.RMF, Name: "Retirement Mutual Fund (RMF) units", Amount: 290000.0, TaxSaved: 29000.0, TaxSavedPerBaht: 0.1},
+			},
+		}
+
+		p := New(&stub{Recommendations: want})
+		err := p.TaxRecommendationHandler(c)
+
+		if err != nil {
+			t.Errorf("got some error %v", err)
+		}
+
+		var got RecommendationResponse
+		json.Unmarshal(rec.Body.Bytes(), &got)
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v want %v", got, want)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status code %v but got %v", http.StatusOK, rec.Code)
+		}
+	})
+}
+
+func TestTaxCurveHandler(t *testing.T) {
+	t.Run("should return 400 and an error if step is invalid", func(t *testing.T) {
+		mockCurveJSON, _ := json.Marshal(CurveRequest{From: 0.0, To: 100000.0, Step: 0.0})
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/curves", bytes.NewBuffer(mockCurveJSON))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		p := New(&stub{})
+		err := p.TaxCurveHandler(c)
+
+		if err != nil {
+			t.Errorf("got some error %v", err)
+		}
+
+		var gotErr problem.Problem
+		json.Unmarshal(rec.Body.Bytes(), &gotErr)
+
+		if gotErr.Detail != ErrInvalidCurveStep {
+			t.Errorf("expected error message %v but got %v", ErrInvalidCurveStep, gotErr.Detail)
+		}
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %v but got %v", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("should return 500 and an error message if the curve calculation fails", func(t *testing.T) {
+		mockCurveJSON, _ := json.Marshal(CurveRequest{From: 0.0, To: 100000.0, Step: 50000.0})
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/curves", bytes.NewBuffer(mockCurveJSON))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		st := stub{err: errors.New("curve calculation fails")}
+		p := New(&st)
+		err := p.TaxCurveHandler(c)
+
+		if err != nil {
+			t.Errorf("got some error %v", err)
+		}
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("expected status code %v but got %v", http.StatusInternalServerError, rec.Code)
+		}
+	})
+
+	t.Run("should return 200 and the curve points", func(t *testing.T) {
+		mockCurveJSON, _ := json.Marshal(CurveRequest{From: 0.0, To: 100000.0, Step: 50000.0})
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/curves", bytes.NewBuffer(mockCurveJSON))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		want := CurveResponse{Points: []CurvePoint{
+			{Income: 0.0},
+			{Income: 50000.0},
+			{Income: 100000.0},
+		}}
+
+		p := New(&stub{Curve: want})
+		err := p.TaxCurveHandler(c)
+
+		if err != nil {
+			t.Errorf("got some error %v", err)
+		}
+
+		var got CurveResponse
+		json.Unmarshal(rec.Body.Bytes(), &got)
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v want %v", got, want)
+		}
+	})
+}
+
+func TestTaxFormHandler(t *testing.T) {
+	t.Run("should return 400 and an error if provide bad request payload", func(t *testing.T) {
+		mockTaxDetailsJSON, _ := json.Marshal(TaxDetails{TotalIncome: -1.0})
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/pnd91", bytes.NewBuffer(mockTaxDetailsJSON))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		p := New(&stub{})
+		err := p.TaxFormHandler(c)
+
+		if err != nil {
+			t.Errorf("got some error %v", err)
+		}
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %v but got %v", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("should return 200 and a printable html document with the embedded font", func(t *testing.T) {
+		mockTaxDetailsJSON, _ := json.Marshal(TaxDetails{TotalIncome: 500000.0, TaxYear: 2567})
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/pnd91", bytes.NewBuffer(mockTaxDetailsJSON))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		st := stub{Summary: TaxSummary{TotalIncome: 500000.0, NetIncome: 440000.0, Tax: 29000.0}}
+		p := New(&st, WithFormFont([]byte("font")))
+		err := p.TaxFormHandler(c)
+
+		if err != nil {
+			t.Errorf("got some error %v", err)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status code %v but got %v", http.StatusOK, rec.Code)
+		}
+
+		if !strings.HasPrefix(rec.Header().Get(echo.HeaderContentType), echo.MIMETextHTML) {
+			t.Errorf("expected html content type but got %v", rec.Header().Get(echo.HeaderContentType))
+		}
+
+		body := rec.Body.String()
+		for _, want := range []string{"ภ.ง.ด.91", "ปีภาษี 2567", "440,000.00", "29,000.00", "data:font/ttf;base64,Zm9udA=="} {
+			if !strings.Contains(body, want) {
+				t.Errorf("expected document to contain %v", want)
+			}
+		}
+	})
+}
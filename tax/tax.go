@@ -3,9 +3,13 @@ package tax
 import "github.com/varissara-wo/assessment-tax/allowance"
 
 type TaxDetails struct {
-	TotalIncome float64
-	WHT         float64
-	Allowances  []allowance.Allowance
+	TotalIncome  float64               `json:"totalIncome"`
+	WHT          float64               `json:"wht"`
+	Allowances   []allowance.Allowance `json:"allowances"`
+	TaxYear      int                   `json:"taxYear,omitempty"`
+	FilingDate   string                `json:"filingDate,omitempty"`
+	DueDate      string                `json:"dueDate,omitempty"`
+	Installments bool                  `json:"installments,omitempty"`
 }
 
 type TaxBreakdown struct {
@@ -14,12 +18,21 @@ type TaxBreakdown struct {
 }
 
 type TaxResponse struct {
-	Tax       float64        `json:"tax"`
-	TaxRefund float64        `json:"taxRefund"`
-	TaxLevel  []TaxBreakdown `json:"taxLevel"`
+	Tax             float64          `json:"tax"`
+	TaxRefund       float64          `json:"taxRefund"`
+	TaxLevel        []TaxBreakdown   `json:"taxLevel"`
+	PaymentSchedule *PaymentSchedule `json:"paymentSchedule,omitempty"`
+	Installments    []Installment    `json:"installments,omitempty"`
 }
 
 const (
-	ErrInvalidTotalIncome = "total income must be greater than or equals 0"
-	ErrInvalidWHT         = "wht must be greater than or equal to 0 and less than total income"
+	ErrInvalidTotalIncome         = "total income must be greater than or equals 0"
+	ErrInvalidWHT                 = "wht must be greater than or equal to 0 and less than total income"
+	ErrInvalidFilingDate          = "filing date must be in YYYY-MM-DD format"
+	ErrInvalidDueDate             = "due date must be in YYYY-MM-DD format"
+	ErrInvalidTaxYear             = "tax year must be a Buddhist era year between 2500 and 2999"
+	ErrInstallmentsBelowThreshold = "installments are only available when tax due exceeds 3,000"
+	ErrInvalidCurveRange          = "from must be greater than or equal to 0 and less than or equal to to"
+	ErrInvalidCurveStep           = "step must be greater than 0"
+	ErrTooManyCurvePoints         = "curve must not exceed 1000 points"
 )
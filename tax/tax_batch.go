@@ -0,0 +1,163 @@
+package tax
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/varissara-wo/assessment-tax/allowance"
+	"github.com/varissara-wo/assessment-tax/i18n"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+const (
+	DefaultBatchLimit = 1000
+	maxReferenceID    = 100
+)
+
+const (
+	ErrEmptyBatch         = "items must not be empty"
+	ErrBatchTooLarge      = "items must not exceed %d"
+	ErrInvalidReferenceID = "reference id must not be longer than 100 characters"
+)
+
+var (
+	errEmptyBatch         = problem.New("TAX_BATCH_EMPTY", "items", ErrEmptyBatch)
+	errInvalidReferenceID = problem.New("TAX_BATCH_REFERENCE_INVALID", "referenceId", ErrInvalidReferenceID)
+)
+
+type BatchItem struct {
+	ReferenceID string `json:"referenceId,omitempty"`
+	TaxDetails
+}
+
+type BatchRequest struct {
+	Items []BatchItem `json:"items"`
+}
+
+type BatchResult struct {
+	Index       int              `json:"index"`
+	ReferenceID string           `json:"referenceId,omitempty"`
+	Result      *TaxResponse     `json:"result,omitempty"`
+	Error       *problem.Problem `json:"error,omitempty"`
+}
+
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+}
+
+type TaxOutcome struct {
+	Result TaxResponse
+	Err    error
+}
+
+func (br BatchRequest) TaxDetails() []TaxDetails {
+	tds := make([]TaxDetails, len(br.Items))
+	for i, item := range br.Items {
+		tds[i] = item.TaxDetails
+	}
+	return tds
+}
+
+func (br *BatchRequest) ValidateBatchRequest(limit int) error {
+	if len(br.Items) == 0 {
+		return errEmptyBatch
+	}
+	if len(br.Items) > limit {
+		return BatchTooLarge(limit)
+	}
+	return nil
+}
+
+func BatchTooLarge(limit int) error {
+	return problem.Newf("TAX_BATCH_TOO_LARGE", "items", ErrBatchTooLarge, limit)
+}
+
+func (br BatchRequest) Results(outcomes []TaxOutcome, l i18n.Lang) BatchResponse {
+	res := BatchResponse{Results: make([]BatchResult, len(br.Items))}
+
+	for i, item := range br.Items {
+		var es problem.Errors
+		if len(item.ReferenceID) > maxReferenceID {
+			es.Add(errInvalidReferenceID)
+		}
+		es.Add(outcomes[i].Err)
+
+		r := BatchResult{Index: i, ReferenceID: item.ReferenceID}
+		if err := es.Err(); err != nil {
+			p := problem.Localized(l, http.StatusBadRequest, err)
+			r.Error = &p
+		} else {
+			tr := outcomes[i].Result.Localize(l)
+			r.Result = &tr
+		}
+		res.Results[i] = r
+	}
+
+	return res
+}
+
+func CalculateTaxOutcome(td TaxDetails, ma allowance.MaxAllowance) TaxOutcome {
+	if err := td.ValidateTaxDetails(); err != nil {
+		return TaxOutcome{Err: err}
+	}
+
+	tr, err := td.SchedulePayment(CalculateTax(td.CalculateNetIncome(ma), td.WHT))
+	return TaxOutcome{Result: tr, Err: err}
+}
+
+// CapsFor resolves the caps of every tax details, once per tax year.
+func CapsFor(tds []TaxDetails, now time.Time, get func(time.Time) (allowance.MaxAllowance, error)) ([]allowance.MaxAllowance, error) {
+	byDate := map[time.Time]allowance.MaxAllowance{}
+	mas := make([]allowance.MaxAllowance, len(tds))
+
+	for i, td := range tds {
+		at := td.CapsAt(now)
+		ma, ok := byDate[at]
+		if !ok {
+			var err error
+			if ma, err = get(at); err != nil {
+				return nil, err
+			}
+			byDate[at] = ma
+		}
+		mas[i] = ma
+	}
+
+	return mas, nil
+}
+
+func CalculateTaxes(tds []TaxDetails, mas []allowance.MaxAllowance, workers int) []TaxOutcome {
+	out := make([]TaxOutcome, len(tds))
+
+	calculate := func(i int) {
+		out[i] = CalculateTaxOutcome(tds[i], mas[i])
+	}
+
+	if workers <= 1 {
+		for i := range tds {
+			calculate(i)
+		}
+		return out
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				calculate(i)
+			}
+		}()
+	}
+
+	for i := range tds {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return out
+}
@@ -0,0 +1,173 @@
+package tax
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/varissara-wo/assessment-tax/allowance"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+func batch(t *testing.T, h *Handler, body string) (int, BatchResponse) {
+	t.Helper()
+
+	rec := post(h.TaxBatchHandler, body)
+
+	var got BatchResponse
+	json.Unmarshal(rec.Body.Bytes(), &got)
+	return rec.Code, got
+}
+
+func TestTaxBatch(t *testing.T) {
+	t.Run("should return results in request order with per-item errors", func(t *testing.T) {
+		status, got := batch(t, New(&stub{}), `{"items": [
+			{"referenceId": "a", "totalIncome": 500000.0, "allowances": [{"allowanceType": "donation", "amount": 200000.0}]},
+			{"referenceId": "b", "totalIncome": -1.0},
+			{"totalIncome": 500000.0, "wht": 25000.0, "allowances": [{"allowanceType": "k-receipt", "amount": 50000.0}]}
+		]}`)
+
+		if status != http.StatusOK || len(got.Results) != 3 {
+			t.Fatalf("expected 3 results but got %v %+v", status, got)
+		}
+
+		for i, r := range got.Results {
+			if r.Index != i {
+				t.Errorf("expected index %v but got %v", i, r.Index)
+			}
+		}
+
+		first := got.Results[0]
+		if first.ReferenceID != "a" || first.Error != nil || first.Result == nil || first.Result.Tax != 19000.0 {
+			t.Errorf("expected tax 19000.0 for a but got %+v", first)
+		}
+
+		second := got.Results[1]
+		if second.ReferenceID != "b" || second.Result != nil || second.Error == nil || second.Error.Code != "TAX_TOTAL_INCOME_NEGATIVE" {
+			t.Errorf("expected an error for b but got %+v", second)
+		}
+
+		third := got.Results[2]
+		if third.ReferenceID != "" || third.Result == nil || third.Result.Tax != 0.0 || third.Result.TaxRefund != 1000.0 {
+			t.Errorf("expected a refund of 1000.0 but got %+v", third)
+		}
+	})
+
+	t.Run("should schedule payments for items with dates", func(t *testing.T) {
+		_, got := batch(t, New(&stub{}), `{"items": [
+			{"totalIncome": 500000.0, "filingDate": "2024-04-10", "dueDate": "2024-03-31"}
+		]}`)
+
+		r := got.Results[0]
+		if r.Result == nil || r.Result.PaymentSchedule == nil {
+			t.Errorf("expected a payment schedule but got %+v", r)
+		}
+	})
+
+	t.Run("should reject a reference id that is too long", func(t *testing.T) {
+		_, got := batch(t, New(&stub{}), fmt.Sprintf(`{"items": [{"referenceId": %q, "totalIncome": 1.0}]}`, strings.Repeat("x", 101)))
+
+		r := got.Results[0]
+		if r.Error == nil || r.Error.Field != "referenceId" {
+			t.Errorf("expected an error on referenceId but got %+v", r)
+		}
+	})
+
+	t.Run("should require items", func(t *testing.T) {
+		rec := post(New(&stub{}).TaxBatchHandler, `{"items": []}`)
+
+		var got problem.Problem
+		json.Unmarshal(rec.Body.Bytes(), &got)
+
+		if rec.Code != http.StatusBadRequest || got.Code != "TAX_BATCH_EMPTY" {
+			t.Errorf("expected TAX_BATCH_EMPTY but got %v %+v", rec.Code, got)
+		}
+	})
+
+	t.Run("should reject a batch over the limit", func(t *testing.T) {
+		rec := post(New(&stub{}, WithBatchLimit(1)).TaxBatchHandler, `{"items": [{"totalIncome": 1.0}, {"totalIncome": 2.0}]}`)
+
+		var got problem.Problem
+		json.Unmarshal(rec.Body.Bytes(), &got)
+
+		if rec.Code != http.StatusBadRequest || got.Code != "TAX_BATCH_TOO_LARGE" || got.Detail != "items must not exceed 1" {
+			t.Errorf("expected TAX_BATCH_TOO_LARGE but got %v %+v", rec.Code, got)
+		}
+	})
+
+	t.Run("should consume the row quota", func(t *testing.T) {
+		q := &quotaStub{ok: false}
+		rec := post(New(&stub{}, WithRowQuota(q)).TaxBatchHandler, `{"items": [{"totalIncome": 1.0}, {"totalIncome": 2.0}]}`)
+
+		if q.rows != 2 || rec.Code != http.StatusTooManyRequests {
+			t.Errorf("expected 2 rows to be refused but got %v %v", q.rows, rec.Code)
+		}
+	})
+
+	t.Run("should return 500 if the store fails", func(t *testing.T) {
+		rec := post(New(&stub{err: errors.New("allowances unavailable")}).TaxBatchHandler, `{"items": [{"totalIncome": 1.0}]}`)
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("expected status code %v but got %v", http.StatusInternalServerError, rec.Code)
+		}
+	})
+}
+
+func TestCapsFor(t *testing.T) {
+	now := time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	byYear := map[int]allowance.MaxAllowance{
+		2024: {Personal: 60000.0},
+		2025: {Personal: 80000.0},
+	}
+
+	var calls int
+	get := func(at time.Time) (allowance.MaxAllowance, error) {
+		calls++
+		return byYear[at.Year()], nil
+	}
+
+	tds := []TaxDetails{{TaxYear: 2567}, {}, {TaxYear: 2567}, {FilingDate: "2026-03-01"}}
+	got, err := CapsFor(tds, now, get)
+
+	if err != nil || calls != 2 {
+		t.Fatalf("expected one lookup per tax year but got %v %v", calls, err)
+	}
+	for i, want := range []float64{60000.0, 80000.0, 60000.0, 80000.0} {
+		if got[i].Personal != want {
+			t.Errorf("expected caps %d to have personal %v but got %v", i, want, got[i].Personal)
+		}
+	}
+
+	if _, err := CapsFor(tds, now, func(time.Time) (allowance.MaxAllowance, error) {
+		return allowance.MaxAllowance{}, errors.New("allowances unavailable")
+	}); err == nil {
+		t.Errorf("expected the lookup error")
+	}
+}
+
+func TestCalculateTaxes(t *testing.T) {
+	var tds []TaxDetails
+	for i := 0; i < 50; i++ {
+		tds = append(tds, TaxDetails{
+			TotalIncome: float64(i) * 100000.0,
+			Allowances:  []allowance.Allowance{{AllowanceType: allowance.Donation, Amount: 50000.0}},
+		})
+	}
+	tds[7].WHT = -1.0
+
+	want := CalculateTaxes(tds, mockCaps(tds), 1)
+	got := CalculateTaxes(tds, mockCaps(tds), 8)
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected parallel results to match sequential results")
+	}
+
+	if want[7].Err == nil || want[8].Err != nil || want[8].Result.Tax != CalculateTax(800000.0-110000.0, 0.0).Tax {
+		t.Errorf("expected only row 7 to fail but got %+v %+v", want[7], want[8])
+	}
+}
@@ -4,21 +4,22 @@ import (
 	"math"
 
 	"github.com/varissara-wo/assessment-tax/allowance"
+	"github.com/varissara-wo/assessment-tax/i18n"
 )
 
 type TaxBracket struct {
-	Description string
-	MaxIncome   float64
-	TaxRate     float64
-	MaxTax      float64
+	Key       string
+	MaxIncome float64
+	TaxRate   float64
+	MaxTax    float64
 }
 
 var taxBrackets = []TaxBracket{
-	{Description: "0-150,000", MaxIncome: 150000.0, TaxRate: 0.0, MaxTax: 0.0},
-	{Description: "150,001-500,000", MaxIncome: 500000.0, TaxRate: 0.1, MaxTax: 35000.0},
-	{Description: "500,001-1,000,000", MaxIncome: 1000000.0, TaxRate: 0.15, MaxTax: 75000.0},
-	{Description: "1,000,001-2,000,000", MaxIncome: 2000000.0, TaxRate: 0.2, MaxTax: 200000.0},
-	{Description: "2,000,001 ขึ้นไป", MaxIncome: math.MaxFloat64, TaxRate: 0.35},
+	{Key: "TAX_BRACKET_1", MaxIncome: 150000.0, TaxRate: 0.0, MaxTax: 0.0},
+	{Key: "TAX_BRACKET_2", MaxIncome: 500000.0, TaxRate: 0.1, MaxTax: 35000.0},
+	{Key: "TAX_BRACKET_3", MaxIncome: 1000000.0, TaxRate: 0.15, MaxTax: 75000.0},
+	{Key: "TAX_BRACKET_4", MaxIncome: 2000000.0, TaxRate: 0.2, MaxTax: 200000.0},
+	{Key: "TAX_BRACKET_5", MaxIncome: math.MaxFloat64, TaxRate: 0.35},
 }
 
 func CalculateTax(income float64, wht float64) TaxResponse {
@@ -32,18 +33,18 @@ func CalculateTax(income float64, wht float64) TaxResponse {
 		if income <= bracket.MaxIncome && income > previousMaxIncome {
 			tax = ((income - previousMaxIncome) * bracket.TaxRate) + previousMaxTax
 			tb = TaxBreakdown{
-				Level: bracket.Description,
+				Level: i18n.Default.T(bracket.Key),
 				Tax:   (income - previousMaxIncome) * bracket.TaxRate,
 			}
 		} else {
 			if income > bracket.MaxIncome {
 				tb = TaxBreakdown{
-					Level: bracket.Description,
+					Level: i18n.Default.T(bracket.Key),
 					Tax:   bracket.MaxTax,
 				}
 			} else {
 				tb = TaxBreakdown{
-					Level: bracket.Description,
+					Level: i18n.Default.T(bracket.Key),
 					Tax:   0.0,
 				}
 			}
@@ -70,6 +71,24 @@ func CalculateTax(income float64, wht float64) TaxResponse {
 	return r
 }
 
+func (tr TaxResponse) Localize(l i18n.Lang) TaxResponse {
+	tr.TaxLevel = localizeLevels(tr.TaxLevel, l)
+	return tr
+}
+
+func localizeLevels(tbl []TaxBreakdown, l i18n.Lang) []TaxBreakdown {
+	if len(tbl) != len(taxBrackets) {
+		return tbl
+	}
+
+	localized := make([]TaxBreakdown, len(tbl))
+	for i, tb := range tbl {
+		tb.Level = l.T(taxBrackets[i].Key)
+		localized[i] = tb
+	}
+	return localized
+}
+
 func (td TaxDetails) CalculateNetIncome(ma allowance.MaxAllowance) float64 {
 	return td.TotalIncome - allowance.CalculateAllowances(td.Allowances, ma)
 }
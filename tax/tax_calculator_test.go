@@ -6,13 +6,14 @@ import (
 	"testing"
 
 	"github.com/varissara-wo/assessment-tax/allowance"
+	"github.com/varissara-wo/assessment-tax/i18n"
 )
 
 func generateTaxBreakdown(taxValues ...float64) []TaxBreakdown {
 	var breakdown []TaxBreakdown
 	for i, tax := range taxValues {
 		breakdown = append(breakdown, TaxBreakdown{
-			Level: taxBrackets[i].Description,
+			Level: i18n.Default.T(taxBrackets[i].Key),
 			Tax:   tax,
 		})
 	}
@@ -74,6 +75,14 @@ var mockMaxAllowance = allowance.MaxAllowance{
 	Personal: 60000.0,
 }
 
+func mockCaps(tds []TaxDetails) []allowance.MaxAllowance {
+	mas := make([]allowance.MaxAllowance, len(tds))
+	for i := range mas {
+		mas[i] = mockMaxAllowance
+	}
+	return mas
+}
+
 func TestNetIncomeCalculation(t *testing.T) {
 	t.Run("should return 60000", func(t *testing.T) {
 		want := 790000.0
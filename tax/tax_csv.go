@@ -2,27 +2,43 @@ package tax
 
 import (
 	"encoding/csv"
-	"errors"
 	"io"
 	"strconv"
 	"strings"
 
 	"github.com/varissara-wo/assessment-tax/allowance"
+	"github.com/varissara-wo/assessment-tax/problem"
 )
 
 const (
 	ErrInvalidHeaderCSVData  = "invalid CSV header, expected totalIncome, wht, donation"
 	ErrorInvalidEmptyCSVData = "invalid CSV data value cannot be empty"
+	ErrRowQuotaExceeded      = "daily row quota exceeded"
+	ErrMissingCSVFile        = "file must be uploaded as a multipart form field named file"
+	ErrInvalidCSV            = "file must be a valid CSV with three values per row"
+	ErrInvalidCSVValue       = "invalid CSV data value must be a number"
+)
+
+var (
+	errInvalidHeaderCSVData = problem.New("TAX_CSV_HEADER_INVALID", "file", ErrInvalidHeaderCSVData)
+	errEmptyCSVData         = problem.New("TAX_CSV_VALUE_EMPTY", "file", ErrorInvalidEmptyCSVData)
+	errMissingCSVFile       = problem.New("TAX_CSV_FILE_MISSING", "file", ErrMissingCSVFile)
+	errInvalidCSV           = problem.New("TAX_CSV_INVALID", "file", ErrInvalidCSV)
+	errInvalidCSVValue      = problem.New("TAX_CSV_VALUE_INVALID", "file", ErrInvalidCSVValue)
+
+	ErrRowQuota = problem.New("TAX_ROW_QUOTA_EXCEEDED", "", ErrRowQuotaExceeded)
 )
 
 func readCSV(reader *csv.Reader) ([]TaxDetails, error) {
 	row, err := reader.Read()
-	if err != nil {
-		return nil, err
+	if err == io.EOF {
+		return nil, errInvalidHeaderCSVData
+	} else if err != nil {
+		return nil, errInvalidCSV
 	}
 
-	if row[0] != "totalIncome" || row[1] != "wht" || row[2] != "donation" {
-		return nil, errors.New(ErrInvalidHeaderCSVData)
+	if len(row) != 3 || row[0] != "totalIncome" || row[1] != "wht" || row[2] != "donation" {
+		return nil, errInvalidHeaderCSVData
 	}
 
 	tds := []TaxDetails{}
@@ -32,19 +48,19 @@ func readCSV(reader *csv.Reader) ([]TaxDetails, error) {
 		if err == io.EOF {
 			break
 		} else if err != nil {
-			return nil, err
+			return nil, errInvalidCSV
 		}
 
 		td := TaxDetails{}
 
 		if row[0] == "" || row[1] == "" || row[2] == "" {
-			return nil, errors.New(ErrorInvalidEmptyCSVData)
+			return nil, errEmptyCSVData
 		}
 
 		for i, r := range row {
 			v, err := strconv.ParseFloat(strings.Replace(r, ",", "", -1), 64)
 			if err != nil {
-				return nil, err
+				return nil, errInvalidCSVValue
 			}
 
 			switch i {
@@ -78,4 +78,25 @@ func TestReadCSV(t *testing.T) {
 			t.Errorf("expected error message %v but got %v", want, got)
 		}
 	})
+
+	testCases := []struct {
+		name    string
+		csvData string
+		want    string
+	}{
+		{"should return error if CSV header is short", "totalIncome\n1000.0\n", ErrInvalidHeaderCSVData},
+		{"should return error if CSV file is empty", "", ErrInvalidHeaderCSVData},
+		{"should return error if CSV data value is not a number", "totalIncome,wht,donation\n1000.0,abc,0.0\n", ErrInvalidCSVValue},
+		{"should return error if CSV row has a missing value", "totalIncome,wht,donation\n1000.0,200.0\n", ErrInvalidCSV},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, got := readCSV(csv.NewReader(strings.NewReader(tc.csvData)))
+
+			if got == nil || got.Error() != tc.want {
+				t.Errorf("expected error message %v but got %v", tc.want, got)
+			}
+		})
+	}
 }
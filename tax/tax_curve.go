@@ -0,0 +1,70 @@
+package tax
+
+import (
+	"sort"
+
+	"github.com/varissara-wo/assessment-tax/allowance"
+)
+
+const maxCurvePoints = 1000
+
+type CurveRequest struct {
+	From       float64               `json:"from"`
+	To         float64               `json:"to"`
+	Step       float64               `json:"step"`
+	Allowances []allowance.Allowance `json:"allowances"`
+}
+
+type CurvePoint struct {
+	Income        float64 `json:"income"`
+	Tax           float64 `json:"tax"`
+	EffectiveRate float64 `json:"effectiveRate"`
+	MarginalRate  float64 `json:"marginalRate"`
+}
+
+type CurveResponse struct {
+	Points []CurvePoint `json:"points"`
+}
+
+func (cr CurveRequest) CalculateCurve(ma allowance.MaxAllowance) CurveResponse {
+	deduction := allowance.CalculateAllowances(cr.Allowances, ma)
+
+	incomes := []float64{}
+	for i := 0; cr.From+float64(i)*cr.Step < cr.To; i++ {
+		incomes = append(incomes, cr.From+float64(i)*cr.Step)
+	}
+	incomes = append(incomes, cr.To)
+
+	for _, bracket := range taxBrackets[:len(taxBrackets)-1] {
+		breakpoint := bracket.MaxIncome + deduction
+		if breakpoint >= cr.From && breakpoint <= cr.To {
+			incomes = append(incomes, breakpoint)
+		}
+	}
+
+	sort.Float64s(incomes)
+
+	points := []CurvePoint{}
+	for i, income := range incomes {
+		if i > 0 && income == incomes[i-1] {
+			continue
+		}
+
+		netIncome := income - deduction
+		tax := CalculateTax(netIncome, 0.0).Tax
+
+		effectiveRate := 0.0
+		if income > 0 {
+			effectiveRate = tax / income
+		}
+
+		points = append(points, CurvePoint{
+			Income:        income,
+			Tax:           tax,
+			EffectiveRate: effectiveRate,
+			MarginalRate:  MarginalRate(netIncome),
+		})
+	}
+
+	return CurveResponse{Points: points}
+}
@@ -0,0 +1,69 @@
+package tax
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCalculateCurve(t *testing.T) {
+	t.Run("should return a point per step including bracket breakpoints", func(t *testing.T) {
+		cr := CurveRequest{From: 0.0, To: 400000.0, Step: 100000.0}
+
+		got := cr.CalculateCurve(mockMaxAllowance)
+
+		want := CurveResponse{Points: []CurvePoint{
+			{Income: 0.0, Tax: 0.0, EffectiveRate: 0.0, MarginalRate: 0.0},
+			{Income: 100000.0, Tax: 0.0, EffectiveRate: 0.0, MarginalRate: 0.0},
+			{Income: 200000.0, Tax: 0.0, EffectiveRate: 0.0, MarginalRate: 0.0},
+			{Income: 210000.0, Tax: 0.0, EffectiveRate: 0.0, MarginalRate: 0.1},
+			{Income: 300000.0, Tax: 9000.0, EffectiveRate: 0.03, MarginalRate: 0.1},
+			{Income: 400000.0, Tax: 19000.0, EffectiveRate: 0.0475, MarginalRate: 0.1},
+		}}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+
+	t.Run("should include the end of the range and not repeat breakpoints", func(t *testing.T) {
+		cr := CurveRequest{From: 500000.0, To: 560000.0, Step: 30000.0}
+
+		got := cr.CalculateCurve(mockMaxAllowance)
+
+		want := []float64{500000.0, 530000.0, 560000.0}
+
+		if len(got.Points) != len(want) {
+			t.Fatalf("expected %v points but got %v", len(want), got.Points)
+		}
+
+		for i, p := range got.Points {
+			if p.Income != want[i] {
+				t.Errorf("expected income %v but got %v", want[i], p.Income)
+			}
+		}
+
+		if got.Points[2].MarginalRate != 0.15 {
+			t.Errorf("expected marginal rate 0.15 at breakpoint but got %v", got.Points[2].MarginalRate)
+		}
+	})
+}
+
+func TestCurveRequestJSON(t *testing.T) {
+	b, _ := json.Marshal(CurveRequest{From: 0.0, To: 100000.0, Step: 10000.0})
+
+	var fields map[string]interface{}
+	json.Unmarshal(b, &fields)
+
+	got := []string{}
+	for k := range fields {
+		got = append(got, k)
+	}
+	sort.Strings(got)
+
+	want := []string{"allowances", "from", "step", "to"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected fields %v but got %v", want, got)
+	}
+}
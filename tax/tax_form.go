@@ -0,0 +1,118 @@
+package tax
+
+import (
+	"embed"
+	"encoding/base64"
+	"html/template"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/varissara-wo/assessment-tax/allowance"
+	"github.com/varissara-wo/assessment-tax/i18n"
+)
+
+//go:embed templates/pnd91.html
+var templates embed.FS
+
+//go:embed fonts/FreeSerif.ttf
+var formFont []byte
+
+var pnd91Template = template.Must(template.New("pnd91.html").Funcs(template.FuncMap{
+	"baht":           formatBaht,
+	"allowanceLabel": allowanceLabel,
+	"inc":            func(i int) int { return i + 1 },
+}).ParseFS(templates, "templates/pnd91.html"))
+
+var formAllowances = []allowance.AllowanceType{
+	allowance.Personal,
+	allowance.RMF,
+	allowance.SSF,
+	allowance.KReceipt,
+	allowance.Donation,
+}
+
+type AllowanceLine struct {
+	AllowanceType allowance.AllowanceType `json:"allowanceType"`
+	Amount        float64                 `json:"amount"`
+}
+
+type TaxSummary struct {
+	TotalIncome    float64         `json:"totalIncome"`
+	Allowances     []AllowanceLine `json:"allowances"`
+	TotalAllowance float64         `json:"totalAllowance"`
+	NetIncome      float64         `json:"netIncome"`
+	TaxLevel       []TaxBreakdown  `json:"taxLevel"`
+	TotalTax       float64         `json:"totalTax"`
+	WHT            float64         `json:"wht"`
+	Tax            float64         `json:"tax"`
+	TaxRefund      float64         `json:"taxRefund"`
+}
+
+type pnd91Page struct {
+	TaxSummary
+	TaxYear int
+	Font    template.URL
+}
+
+func (td TaxDetails) Summarize(ma allowance.MaxAllowance) TaxSummary {
+	claimed := allowance.ClaimAllowances(td.Allowances, ma)
+
+	ts := TaxSummary{
+		TotalIncome: td.TotalIncome,
+		Allowances:  []AllowanceLine{},
+		WHT:         td.WHT,
+	}
+
+	for _, t := range formAllowances {
+		ts.Allowances = append(ts.Allowances, AllowanceLine{AllowanceType: t, Amount: claimed.Get(t)})
+		ts.TotalAllowance += claimed.Get(t)
+	}
+
+	ts.NetIncome = td.TotalIncome - ts.TotalAllowance
+
+	tr := CalculateTax(ts.NetIncome, td.WHT)
+	ts.TaxLevel = tr.TaxLevel
+	ts.Tax = tr.Tax
+	ts.TaxRefund = tr.TaxRefund
+	ts.TotalTax = CalculateTax(ts.NetIncome, 0.0).Tax
+
+	return ts
+}
+
+func RenderPND91(w io.Writer, ts TaxSummary, taxYear int, font []byte) error {
+	if len(font) == 0 {
+		font = formFont
+	}
+
+	ts.TaxLevel = localizeLevels(ts.TaxLevel, i18n.Thai)
+	p := pnd91Page{
+		TaxSummary: ts,
+		TaxYear:    taxYear,
+		Font:       template.URL("data:font/ttf;base64," + base64.StdEncoding.EncodeToString(font)),
+	}
+	return pnd91Template.Execute(w, p)
+}
+
+func allowanceLabel(t allowance.AllowanceType) string {
+	return t.Name(i18n.Thai)
+}
+
+func formatBaht(v float64) string {
+	s := strconv.FormatFloat(math.Abs(v), 'f', 2, 64)
+	whole, fraction := s[:len(s)-3], s[len(s)-3:]
+
+	var b strings.Builder
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+
+	if v < 0 {
+		return "-" + b.String() + fraction
+	}
+	return b.String() + fraction
+}
@@ -0,0 +1,98 @@
+package tax
+
+import (
+	"bytes"
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/varissara-wo/assessment-tax/allowance"
+)
+
+func TestSummarize(t *testing.T) {
+	t.Run("should break down claimed allowances and tax levels", func(t *testing.T) {
+		td := TaxDetails{
+			TotalIncome: 500000.0,
+			WHT:         25000.0,
+			Allowances: []allowance.Allowance{
+				{AllowanceType: allowance.KReceipt, Amount: 200000.0},
+				{AllowanceType: allowance.Donation, Amount: 100000.0},
+			},
+		}
+
+		got := td.Summarize(mockMaxAllowance)
+
+		wantAllowances := []AllowanceLine{
+			{AllowanceType: allowance.Personal, Amount: 60000.0},
+			{AllowanceType: allowance.RMF, Amount: 0.0},
+			{AllowanceType: allowance.SSF, Amount: 0.0},
+			{AllowanceType: allowance.KReceipt, Amount: 50000.0},
+			{AllowanceType: allowance.Donation, Amount: 100000.0},
+		}
+
+		if !reflect.DeepEqual(got.Allowances, wantAllowances) {
+			t.Errorf("expected %v but got %v", wantAllowances, got.Allowances)
+		}
+
+		if got.TotalAllowance != 210000.0 || got.NetIncome != 290000.0 {
+			t.Errorf("expected total allowance 210000 and net income 290000 but got %v and %v", got.TotalAllowance, got.NetIncome)
+		}
+
+		if got.TotalTax != 14000.0 || got.TaxRefund != 11000.0 || got.Tax != 0.0 {
+			t.Errorf("expected total tax 14000 and refund 11000 but got %v", got)
+		}
+
+		if !reflect.DeepEqual(got.TaxLevel, generateTaxBreakdown(0.0, 14000.0, 0.0, 0.0, 0.0)) {
+			t.Errorf("expected tax level breakdown but got %v", got.TaxLevel)
+		}
+	})
+}
+
+func TestRenderPND91(t *testing.T) {
+	t.Run("should render Thai labels for every allowance line", func(t *testing.T) {
+		ts := TaxDetails{TotalIncome: 1234567.5}.Summarize(mockMaxAllowance)
+
+		var b bytes.Buffer
+		if err := RenderPND91(&b, ts, 2568, nil); err != nil {
+			t.Fatalf("expected nil but got %v", err)
+		}
+
+		body := b.String()
+		for _, want := range []string{"1,234,567.50", "ค่าลดหย่อนส่วนตัว", "เงินบริจาค", "2,000,001 ขึ้นไป", "ปีภาษี 2568"} {
+			if !strings.Contains(body, want) {
+				t.Errorf("expected document to contain %v", want)
+			}
+		}
+	})
+
+	t.Run("should embed the bundled Thai font unless one is configured", func(t *testing.T) {
+		ts := TaxDetails{TotalIncome: 500000.0}.Summarize(mockMaxAllowance)
+
+		var bundled, custom bytes.Buffer
+		RenderPND91(&bundled, ts, 2568, nil)
+		RenderPND91(&custom, ts, 2568, []byte("font"))
+
+		if !strings.Contains(bundled.String(), "data:font/ttf;base64,"+base64.StdEncoding.EncodeToString(formFont)) {
+			t.Errorf("expected the bundled font to be embedded")
+		}
+		if !strings.Contains(custom.String(), "data:font/ttf;base64,"+base64.StdEncoding.EncodeToString([]byte("font"))) {
+			t.Errorf("expected the configured font to be embedded")
+		}
+	})
+}
+
+func TestFormatBaht(t *testing.T) {
+	tests := map[float64]string{
+		0.0:        "0.00",
+		999.999:    "1,000.00",
+		150000.0:   "150,000.00",
+		-1234567.0: "-1,234,567.00",
+	}
+
+	for v, want := range tests {
+		if got := formatBaht(v); got != want {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	}
+}
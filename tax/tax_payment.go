@@ -0,0 +1,175 @@
+package tax
+
+import (
+	"math"
+	"time"
+
+	"github.com/varissara-wo/assessment-tax/allowance"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+var errInstallmentsBelowThreshold = problem.New("TAX_INSTALLMENTS_BELOW_THRESHOLD", "installments", ErrInstallmentsBelowThreshold)
+
+const (
+	DateLayout            = "2006-01-02"
+	buddhistEraOffset     = 543
+	minTaxYear            = 2500
+	maxTaxYear            = 2999
+	surchargeRate         = 0.015
+	lateFilingFine        = 200.0
+	installmentCount      = 3
+	installmentsThreshold = 3000.0
+)
+
+type Installment struct {
+	Number  int     `json:"number"`
+	Amount  float64 `json:"amount"`
+	DueDate string  `json:"dueDate"`
+}
+
+type MonthlySurcharge struct {
+	Month       int     `json:"month"`
+	Surcharge   float64 `json:"surcharge"`
+	Accumulated float64 `json:"accumulated"`
+}
+
+type PaymentSchedule struct {
+	DueDate      string             `json:"dueDate"`
+	FilingDate   string             `json:"filingDate"`
+	MonthsLate   int                `json:"monthsLate"`
+	Surcharges   []MonthlySurcharge `json:"surcharges"`
+	Surcharge    float64            `json:"surcharge"`
+	Fine         float64            `json:"fine"`
+	TotalPayable float64            `json:"totalPayable"`
+}
+
+func DefaultDueDate(taxYear int) time.Time {
+	return time.Date(taxYear-buddhistEraOffset+1, time.April, 8, 0, 0, 0, 0, time.UTC)
+}
+
+func (td TaxDetails) Year(now time.Time) int {
+	if td.TaxYear != 0 {
+		return td.TaxYear
+	}
+	if filed, err := time.Parse(DateLayout, td.FilingDate); err == nil {
+		now = filed
+	}
+	return now.Year() - 1 + buddhistEraOffset
+}
+
+// CapsAt is the date allowance caps are resolved at: the last moment of the
+// tax year in Bangkok.
+func (td TaxDetails) CapsAt(now time.Time) time.Time {
+	return time.Date(td.Year(now)-buddhistEraOffset+1, time.January, 1, 0, 0, 0, 0, allowance.Bangkok).Add(-time.Nanosecond)
+}
+
+func (td TaxDetails) dueDate() (time.Time, error) {
+	if td.DueDate == "" {
+		return DefaultDueDate(td.Year(time.Now())), nil
+	}
+	due, err := time.Parse(DateLayout, td.DueDate)
+	if err != nil {
+		return time.Time{}, errInvalidDueDate
+	}
+	return due, nil
+}
+
+func (td TaxDetails) SchedulePayment(tr TaxResponse) (TaxResponse, error) {
+	due, err := td.dueDate()
+	if err != nil {
+		return tr, err
+	}
+
+	if td.Installments {
+		if tr.Tax <= installmentsThreshold {
+			return tr, errInstallmentsBelowThreshold
+		}
+		tr.Installments = CalculateInstallments(tr.Tax, due)
+	}
+
+	if td.FilingDate == "" || tr.Tax <= 0 {
+		return tr, nil
+	}
+
+	filed, err := time.Parse(DateLayout, td.FilingDate)
+	if err != nil {
+		return tr, errInvalidFilingDate
+	}
+
+	ps := CalculatePaymentSchedule(tr.Tax, due, filed)
+	tr.PaymentSchedule = &ps
+
+	return tr, nil
+}
+
+func CalculateInstallments(tax float64, due time.Time) []Installment {
+	amount := math.Floor(tax/installmentCount*100) / 100
+
+	is := []Installment{}
+	for i := 0; i < installmentCount; i++ {
+		if i == installmentCount-1 {
+			amount = tax - amount*(installmentCount-1)
+		}
+
+		is = append(is, Installment{
+			Number:  i + 1,
+			Amount:  amount,
+			DueDate: addMonths(due, i).Format(DateLayout),
+		})
+	}
+
+	return is
+}
+
+func CalculatePaymentSchedule(tax float64, due, filed time.Time) PaymentSchedule {
+	ps := PaymentSchedule{
+		DueDate:      due.Format(DateLayout),
+		FilingDate:   filed.Format(DateLayout),
+		MonthsLate:   monthsLate(due, filed),
+		Surcharges:   []MonthlySurcharge{},
+		TotalPayable: tax,
+	}
+
+	if ps.MonthsLate == 0 {
+		return ps
+	}
+
+	for m := 1; m <= ps.MonthsLate && ps.Surcharge < tax; m++ {
+		s := tax * surchargeRate
+		if ps.Surcharge+s > tax {
+			s = tax - ps.Surcharge
+		}
+		ps.Surcharge += s
+
+		ps.Surcharges = append(ps.Surcharges, MonthlySurcharge{
+			Month:       m,
+			Surcharge:   s,
+			Accumulated: ps.Surcharge,
+		})
+	}
+
+	ps.Fine = lateFilingFine
+	ps.TotalPayable = tax + ps.Surcharge + ps.Fine
+
+	return ps
+}
+
+func monthsLate(due, filed time.Time) int {
+	if !filed.After(due) {
+		return 0
+	}
+
+	m := 1
+	for addMonths(due, m).Before(filed) {
+		m++
+	}
+	return m
+}
+
+func addMonths(t time.Time, months int) time.Time {
+	d := t.AddDate(0, months, 0)
+	if d.Day() != t.Day() {
+		return d.AddDate(0, 0, -d.Day())
+	}
+	return d
+}
@@ -0,0 +1,218 @@
+package tax
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/varissara-wo/assessment-tax/allowance"
+)
+
+func mustParseDate(t *testing.T, d string) time.Time {
+	t.Helper()
+	v, err := time.Parse(DateLayout, d)
+	if err != nil {
+		t.Fatalf("invalid date %v", d)
+	}
+	return v
+}
+
+func TestCalculatePaymentSchedule(t *testing.T) {
+	t.Run("should not charge surcharge if filed on time", func(t *testing.T) {
+		got := CalculatePaymentSchedule(10000.0, mustParseDate(t, "2025-04-08"), mustParseDate(t, "2025-04-08"))
+
+		if got.MonthsLate != 0 || got.Surcharge != 0.0 || got.Fine != 0.0 {
+			t.Errorf("expected no surcharge but got %v", got)
+		}
+
+		if got.TotalPayable != 10000.0 {
+			t.Errorf("expected total payable 10000 but got %v", got.TotalPayable)
+		}
+	})
+
+	t.Run("should count a fraction of a month as a full month", func(t *testing.T) {
+		got := CalculatePaymentSchedule(10000.0, mustParseDate(t, "2025-04-08"), mustParseDate(t, "2025-06-10"))
+
+		if got.MonthsLate != 3 {
+			t.Errorf("expected 3 months late but got %v", got.MonthsLate)
+		}
+
+		if len(got.Surcharges) != 3 {
+			t.Fatalf("expected 3 monthly surcharges but got %v", got.Surcharges)
+		}
+
+		if got.Surcharges[2].Accumulated != got.Surcharge {
+			t.Errorf("expected accumulated %v but got %v", got.Surcharge, got.Surcharges[2].Accumulated)
+		}
+
+		if got.Surcharge < 449.99 || got.Surcharge > 450.01 {
+			t.Errorf("expected surcharge 450 but got %v", got.Surcharge)
+		}
+
+		if got.TotalPayable != 10000.0+got.Surcharge+lateFilingFine {
+			t.Errorf("expected total payable to include surcharge and fine but got %v", got.TotalPayable)
+		}
+	})
+
+	t.Run("should cap surcharge at the tax due", func(t *testing.T) {
+		got := CalculatePaymentSchedule(1000.0, mustParseDate(t, "2015-04-08"), mustParseDate(t, "2025-04-08"))
+
+		if got.Surcharge != 1000.0 {
+			t.Errorf("expected surcharge 1000 but got %v", got.Surcharge)
+		}
+
+		last := got.Surcharges[len(got.Surcharges)-1]
+		if last.Surcharge >= 15.0 || last.Accumulated != 1000.0 {
+			t.Errorf("expected capped surcharge in last month but got %v", last)
+		}
+	})
+}
+
+func TestSchedulePayment(t *testing.T) {
+	t.Run("should not schedule payment without a filing date", func(t *testing.T) {
+		got, _ := TaxDetails{DueDate: "2025-04-08"}.SchedulePayment(TaxResponse{Tax: 1000.0})
+
+		if got.PaymentSchedule != nil {
+			t.Errorf("expected no payment schedule but got %v", got.PaymentSchedule)
+		}
+	})
+
+	t.Run("should not schedule payment for a refund", func(t *testing.T) {
+		got, _ := TaxDetails{FilingDate: "2025-05-08"}.SchedulePayment(TaxResponse{TaxRefund: 1000.0})
+
+		if got.PaymentSchedule != nil {
+			t.Errorf("expected no payment schedule but got %v", got.PaymentSchedule)
+		}
+	})
+
+	t.Run("should default the due date to 8 April after the year before filing", func(t *testing.T) {
+		got, _ := TaxDetails{FilingDate: "2025-05-08"}.SchedulePayment(TaxResponse{Tax: 1000.0})
+
+		if got.PaymentSchedule == nil || got.PaymentSchedule.DueDate != "2025-04-08" {
+			t.Fatalf("expected due date 2025-04-08 but got %v", got.PaymentSchedule)
+		}
+
+		if got.PaymentSchedule.MonthsLate != 1 {
+			t.Errorf("expected 1 month late but got %v", got.PaymentSchedule.MonthsLate)
+		}
+	})
+
+	t.Run("should default the due date from the tax year", func(t *testing.T) {
+		got, _ := TaxDetails{TaxYear: 2568, FilingDate: "2026-06-01"}.SchedulePayment(TaxResponse{Tax: 1000.0})
+
+		if got.PaymentSchedule == nil || got.PaymentSchedule.DueDate != "2026-04-08" || got.PaymentSchedule.MonthsLate != 2 {
+			t.Fatalf("expected due date 2026-04-08 and 2 months late but got %v", got.PaymentSchedule)
+		}
+	})
+
+	t.Run("should return an error for invalid dates", func(t *testing.T) {
+		_, err := TaxDetails{FilingDate: "2025-05-08", DueDate: "08/04/2025"}.SchedulePayment(TaxResponse{Tax: 1000.0})
+		if err == nil || err.Error() != ErrInvalidDueDate {
+			t.Errorf("expected %v but got %v", ErrInvalidDueDate, err)
+		}
+
+		_, err = TaxDetails{FilingDate: "2025-13-08"}.SchedulePayment(TaxResponse{Tax: 1000.0})
+		if err == nil || err.Error() != ErrInvalidFilingDate {
+			t.Errorf("expected %v but got %v", ErrInvalidFilingDate, err)
+		}
+	})
+}
+
+func TestTaxYear(t *testing.T) {
+	now := time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name string
+		td   TaxDetails
+		want int
+	}{
+		{"should use the given tax year", TaxDetails{TaxYear: 2566, FilingDate: "2026-03-01"}, 2566},
+		{"should use the year before filing", TaxDetails{FilingDate: "2025-03-01"}, 2567},
+		{"should use the year before now", TaxDetails{}, 2568},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.td.Year(now); got != tc.want {
+				t.Errorf("expected %v but got %v", tc.want, got)
+			}
+		})
+	}
+
+	if got := DefaultDueDate(2567).Format(DateLayout); got != "2025-04-08" {
+		t.Errorf("expected 2025-04-08 but got %v", got)
+	}
+}
+
+func TestCapsAt(t *testing.T) {
+	now := time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+	got := TaxDetails{TaxYear: 2567}.CapsAt(now)
+	if want := time.Date(2024, time.December, 31, 23, 59, 59, 999999999, allowance.Bangkok); !got.Equal(want) {
+		t.Errorf("expected %v but got %v", want, got)
+	}
+
+	got = TaxDetails{}.CapsAt(now)
+	if want := time.Date(2026, time.January, 1, 0, 0, 0, 0, allowance.Bangkok); !got.Before(want) || got.Year() != 2025 {
+		t.Errorf("expected the end of 2025 but got %v", got)
+	}
+}
+
+func TestCalculateInstallments(t *testing.T) {
+	t.Run("should split tax into 3 installments a month apart", func(t *testing.T) {
+		got := CalculateInstallments(10000.0, mustParseDate(t, "2025-04-08"))
+
+		want := []Installment{
+			{Number: 1, Amount: 3333.33, DueDate: "2025-04-08"},
+			{Number: 2, Amount: 3333.33, DueDate: "2025-05-08"},
+			{Number: 3, Amount: 3333.34, DueDate: "2025-06-08"},
+		}
+
+		if len(got) != len(want) {
+			t.Fatalf("expected %v but got %v", want, got)
+		}
+
+		for i := range want {
+			if got[i].Number != want[i].Number || got[i].DueDate != want[i].DueDate || math.Abs(got[i].Amount-want[i].Amount) > 0.001 {
+				t.Errorf("expected %v but got %v", want[i], got[i])
+			}
+		}
+	})
+}
+
+func TestSchedulePaymentInstallments(t *testing.T) {
+	t.Run("should return an error if tax due does not exceed the threshold", func(t *testing.T) {
+		_, err := TaxDetails{Installments: true}.SchedulePayment(TaxResponse{Tax: 3000.0})
+
+		if err == nil || err.Error() != ErrInstallmentsBelowThreshold {
+			t.Errorf("expected %v but got %v", ErrInstallmentsBelowThreshold, err)
+		}
+	})
+
+	t.Run("should return an installment plan from the due date", func(t *testing.T) {
+		got, err := TaxDetails{Installments: true, DueDate: "2025-03-31"}.SchedulePayment(TaxResponse{Tax: 9000.0})
+
+		if err != nil {
+			t.Fatalf("expected nil but got %v", err)
+		}
+
+		want := []Installment{
+			{Number: 1, Amount: 3000.0, DueDate: "2025-03-31"},
+			{Number: 2, Amount: 3000.0, DueDate: "2025-04-30"},
+			{Number: 3, Amount: 3000.0, DueDate: "2025-05-31"},
+		}
+
+		if !reflect.DeepEqual(got.Installments, want) {
+			t.Errorf("expected %v but got %v", want, got.Installments)
+		}
+	})
+
+	t.Run("should not return an installment plan if not requested", func(t *testing.T) {
+		got, _ := TaxDetails{}.SchedulePayment(TaxResponse{Tax: 9000.0})
+
+		if got.Installments != nil {
+			t.Errorf("expected no installments but got %v", got.Installments)
+		}
+	})
+}
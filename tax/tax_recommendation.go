@@ -0,0 +1,93 @@
+package tax
+
+import (
+	"sort"
+
+	"github.com/varissara-wo/assessment-tax/allowance"
+	"github.com/varissara-wo/assessment-tax/i18n"
+)
+
+type Recommendation struct {
+	AllowanceType   allowance.AllowanceType `json:"allowanceType"`
+	Name            string                  `json:"name"`
+	Amount          float64                 `json:"amount"`
+	TaxSaved        float64                 `json:"taxSaved"`
+	TaxSavedPerBaht float64                 `json:"taxSavedPerBaht"`
+}
+
+type RecommendationResponse struct {
+	Tax             float64          `json:"tax"`
+	TaxRefund       float64          `json:"taxRefund"`
+	MarginalRate    float64          `json:"marginalRate"`
+	Recommendations []Recommendation `json:"recommendations"`
+}
+
+var recommendableAllowances = []allowance.AllowanceType{
+	allowance.RMF,
+	allowance.SSF,
+	allowance.Donation,
+	allowance.KReceipt,
+}
+
+func MarginalRate(income float64) float64 {
+	for _, bracket := range taxBrackets {
+		if income < bracket.MaxIncome {
+			return bracket.TaxRate
+		}
+	}
+	return taxBrackets[len(taxBrackets)-1].TaxRate
+}
+
+func (td TaxDetails) Recommend(ma allowance.MaxAllowance) RecommendationResponse {
+	ma = ma.ForIncome(td.TotalIncome)
+	claimed := allowance.ClaimAllowances(td.Allowances, ma)
+	netIncome := td.CalculateNetIncome(ma)
+	currentTax := CalculateTax(netIncome, 0.0).Tax
+	marginalRate := MarginalRate(netIncome)
+	taxableIncome := netIncome - taxBrackets[0].MaxIncome
+
+	rs := []Recommendation{}
+	for _, t := range recommendableAllowances {
+		amount := allowance.AllowanceAmount(ma).Get(t) - claimed.Get(t)
+		if amount > taxableIncome {
+			amount = taxableIncome
+		}
+		if amount <= 0 {
+			continue
+		}
+
+		// The deduction can drop income into lower brackets, so the saving per
+		// baht is averaged over the amount rather than taken from the marginal rate.
+		saved := currentTax - CalculateTax(netIncome-amount, 0.0).Tax
+		rs = append(rs, Recommendation{
+			AllowanceType:   t,
+			Name:            t.Name(i18n.Default),
+			Amount:          amount,
+			TaxSaved:        saved,
+			TaxSavedPerBaht: saved / amount,
+		})
+	}
+
+	sort.SliceStable(rs, func(i, j int) bool {
+		return rs[i].TaxSaved > rs[j].TaxSaved
+	})
+
+	tr := CalculateTax(netIncome, td.WHT)
+
+	return RecommendationResponse{
+		Tax:             tr.Tax,
+		TaxRefund:       tr.TaxRefund,
+		MarginalRate:    marginalRate,
+		Recommendations: rs,
+	}
+}
+
+func (rr RecommendationResponse) Localize(l i18n.Lang) RecommendationResponse {
+	rs := make([]Recommendation, len(rr.Recommendations))
+	for i, r := range rr.Recommendations {
+		r.Name = r.AllowanceType.Name(l)
+		rs[i] = r
+	}
+	rr.Recommendations = rs
+	return rr
+}
@@ -0,0 +1,135 @@
+package tax
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/varissara-wo/assessment-tax/allowance"
+)
+
+var mockRecommendationMaxAllowance = allowance.MaxAllowance{
+	Donation: 100000.0,
+	KReceipt: 50000.0,
+	Personal: 60000.0,
+	RMF:      500000.0,
+	SSF:      200000.0,
+}
+
+func TestRecommend(t *testing.T) {
+	t.Run("should rank unused allowances by tax saved", func(t *testing.T) {
+		td := TaxDetails{TotalIncome: 1000000.0}
+
+		got := td.Recommend(mockRecommendationMaxAllowance)
+
+		want := RecommendationResponse{
+			Tax:          101000.0,
+			MarginalRate: 0.15,
+			Recommendations: []Recommendation{
+				{AllowanceType: allowance.RMF, Name: "Retirement Mutual Fund (RMF) units", Amount: 300000.0, TaxSaved: 45000.0, TaxSavedPerBaht: 0.15},
+				{AllowanceType: allowance.SSF, Name: "Super Savings Fund (SSF) units", Amount: 200000.0, TaxSaved: 30000.0, TaxSavedPerBaht: 0.15},
+				{AllowanceType: allowance.Donation, Name: "Donations", Amount: 100000.0, TaxSaved: 15000.0, TaxSavedPerBaht: 0.15},
+				{AllowanceType: allowance.KReceipt, Name: "Goods and services purchases (Easy e-Receipt)", Amount: 50000.0, TaxSaved: 7500.0, TaxSavedPerBaht: 0.15},
+			},
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+
+	t.Run("should only recommend the amount left until each cap", func(t *testing.T) {
+		td := TaxDetails{
+			TotalIncome: 500000.0,
+			Allowances: []allowance.Allowance{
+				{AllowanceType: allowance.Donation, Amount: 100000.0},
+				{AllowanceType: allowance.KReceipt, Amount: 20000.0},
+			},
+		}
+
+		got := td.Recommend(mockRecommendationMaxAllowance)
+
+		want := []Recommendation{
+			{AllowanceType: allowance.RMF, Name: "Retirement Mutual Fund (RMF) units", Amount: 150000.0, TaxSaved: 15000.0, TaxSavedPerBaht: 0.1},
+			{AllowanceType: allowance.SSF, Name: "Super Savings Fund (SSF) units", Amount: 150000.0, TaxSaved: 15000.0, TaxSavedPerBaht: 0.1},
+			{AllowanceType: allowance.KReceipt, Name: "Goods and services purchases (Easy e-Receipt)", Amount: 30000.0, TaxSaved: 3000.0, TaxSavedPerBaht: 0.1},
+		}
+
+		if !reflect.DeepEqual(got.Recommendations, want) {
+			t.Errorf("expected %v but got %v", want, got.Recommendations)
+		}
+	})
+
+	t.Run("should count claimed retirement funds against 30% of income", func(t *testing.T) {
+		td := TaxDetails{
+			TotalIncome: 400000.0,
+			Allowances: []allowance.Allowance{
+				{AllowanceType: allowance.RMF, Amount: 100000.0},
+			},
+		}
+
+		got := td.Recommend(mockRecommendationMaxAllowance)
+
+		var rmf Recommendation
+		for _, r := range got.Recommendations {
+			if r.AllowanceType == allowance.RMF {
+				rmf = r
+			}
+		}
+
+		want := Recommendation{AllowanceType: allowance.RMF, Name: "Retirement Mutual Fund (RMF) units", Amount: 20000.0, TaxSaved: 2000.0, TaxSavedPerBaht: 0.1}
+		if rmf != want {
+			t.Errorf("expected %v but got %v", want, rmf)
+		}
+	})
+
+	t.Run("should return the tax saved per baht below a bracket boundary", func(t *testing.T) {
+		td := TaxDetails{TotalIncome: 560000.0}
+
+		got := td.Recommend(mockRecommendationMaxAllowance)
+
+		want := []Recommendation{
+			{AllowanceType: allowance.RMF, Name: "Retirement Mutual Fund (RMF) units", Amount: 168000.0, TaxSaved: 16800.0, TaxSavedPerBaht: 0.1},
+			{AllowanceType: allowance.SSF, Name: "Super Savings Fund (SSF) units", Amount: 168000.0, TaxSaved: 16800.0, TaxSavedPerBaht: 0.1},
+			{AllowanceType: allowance.Donation, Name: "Donations", Amount: 100000.0, TaxSaved: 10000.0, TaxSavedPerBaht: 0.1},
+			{AllowanceType: allowance.KReceipt, Name: "Goods and services purchases (Easy e-Receipt)", Amount: 50000.0, TaxSaved: 5000.0, TaxSavedPerBaht: 0.1},
+		}
+
+		if got.MarginalRate != 0.15 {
+			t.Errorf("expected marginal rate 0.15 but got %v", got.MarginalRate)
+		}
+		if !reflect.DeepEqual(got.Recommendations, want) {
+			t.Errorf("expected %v but got %v", want, got.Recommendations)
+		}
+	})
+
+	t.Run("should return no recommendations if income is not taxed", func(t *testing.T) {
+		td := TaxDetails{TotalIncome: 200000.0}
+
+		got := td.Recommend(mockRecommendationMaxAllowance)
+
+		if len(got.Recommendations) != 0 {
+			t.Errorf("expected no recommendations but got %v", got.Recommendations)
+		}
+	})
+}
+
+func TestMarginalRate(t *testing.T) {
+	testCases := []struct {
+		income float64
+		want   float64
+	}{
+		{0.0, 0.0},
+		{149999.0, 0.0},
+		{150000.0, 0.1},
+		{500000.0, 0.15},
+		{1000000.0, 0.2},
+		{2000000.0, 0.35},
+		{5000000.0, 0.35},
+	}
+
+	for _, tc := range testCases {
+		if got := MarginalRate(tc.income); got != tc.want {
+			t.Errorf("expected marginal rate %v at %v but got %v", tc.want, tc.income, got)
+		}
+	}
+}
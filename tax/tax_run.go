@@ -0,0 +1,366 @@
+package tax
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/allowance"
+	"github.com/varissara-wo/assessment-tax/i18n"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+const (
+	HeaderRunID       = "X-Batch-Run-Id"
+	HeaderLastEventID = "Last-Event-ID"
+)
+
+const (
+	EventRowProcessed = "row-processed"
+	EventRowFailed    = "row-failed"
+	EventCompleted    = "completed"
+)
+
+const (
+	DefaultRunRetention   = 5 * time.Minute
+	DefaultMaxPendingRuns = 10
+	keepAlive             = 15 * time.Second
+	maxRunID              = 64
+)
+
+const (
+	ErrInvalidRunID   = "run id must be 1 to 64 letters, digits, '-' or '_'"
+	ErrRunExists      = "a batch run with this id has already started"
+	ErrRunKeyRequired = "batch run events require an API key"
+	ErrTooManyRuns    = "too many batch runs are pending, wait for one to complete"
+	ErrLastEventID    = "Last-Event-ID must be a non-negative event id"
+)
+
+var (
+	errInvalidRunID   = problem.New("TAX_RUN_ID_INVALID", "id", ErrInvalidRunID)
+	errRunExists      = problem.New("TAX_RUN_EXISTS", "id", ErrRunExists)
+	errRunKeyRequired = problem.New("TAX_RUN_KEY_REQUIRED", "", ErrRunKeyRequired)
+	errTooManyRuns    = problem.New("TAX_RUN_LIMIT_EXCEEDED", "", ErrTooManyRuns)
+	errLastEventID    = problem.New("TAX_RUN_LAST_EVENT_ID_INVALID", "", ErrLastEventID)
+)
+
+type Progress interface {
+	Processed(row int, t Taxes)
+	Failed(row int, err error)
+}
+
+type RunProgress struct {
+	Row       int              `json:"row,omitempty"`
+	Processed int              `json:"processed"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Total     int              `json:"total"`
+	Result    *Taxes           `json:"result,omitempty"`
+	Error     *problem.Problem `json:"error,omitempty"`
+}
+
+type RunEvent struct {
+	ID   int
+	Type string
+	Data RunProgress
+}
+
+type Run struct {
+	ID    string
+	owner string
+
+	mu        sync.Mutex
+	lang      i18n.Lang
+	started   bool
+	done      bool
+	totals    RunProgress
+	events    []RunEvent
+	changed   chan struct{}
+	expiresAt time.Time
+	retention time.Duration
+}
+
+// Runs belong to the principal Owner returns. Callers without one, such as
+// anonymous clients, get untracked runs: their uploads are processed but no
+// events are kept. MaxPending caps the runs an owner can have subscribed to
+// or in progress at once.
+type RunConfig struct {
+	Retention  time.Duration
+	MaxPending int
+	Owner      func(echo.Context) string
+}
+
+type Runs struct {
+	cfg    RunConfig
+	mu     sync.Mutex
+	runs   map[string]*Run
+	closed chan struct{}
+	once   sync.Once
+}
+
+func NewRuns(cfg RunConfig) *Runs {
+	if cfg.Retention <= 0 {
+		cfg.Retention = DefaultRunRetention
+	}
+	if cfg.MaxPending <= 0 {
+		cfg.MaxPending = DefaultMaxPendingRuns
+	}
+	return &Runs{cfg: cfg, runs: map[string]*Run{}, closed: make(chan struct{})}
+}
+
+// LastEventID reads the Last-Event-ID header, which is 0 when absent.
+func LastEventID(header string) (int, error) {
+	if header == "" {
+		return 0, nil
+	}
+	last, err := strconv.Atoi(header)
+	if err != nil || last < 0 {
+		return 0, errLastEventID
+	}
+	return last, nil
+}
+
+func ValidateRunID(id string) error {
+	if id == "" || len(id) > maxRunID {
+		return errInvalidRunID
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return errInvalidRunID
+		}
+	}
+	return nil
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CalculateRows keeps the v1 CSV calculation: rows are validated and taxed
+// without a payment schedule. Invalid rows are reported and returned together.
+func CalculateRows(tds []TaxDetails, mas []allowance.MaxAllowance, progress Progress) ([]Taxes, error) {
+	taxes := []Taxes{}
+	var es problem.Errors
+
+	for i, td := range tds {
+		if err := td.ValidateTaxDetails(); err != nil {
+			progress.Failed(i, err)
+			es.Add(problem.Nest(err, fmt.Sprintf("rows[%d]", i), problem.Newf("TAX_CSV_ROW", "", "row %d: ", i+1)))
+			continue
+		}
+
+		r := CalculateTax(td.CalculateNetIncome(mas[i]), td.WHT)
+		t := Taxes{
+			TotalIncome: td.TotalIncome,
+			Tax:         r.Tax,
+			TaxRefund:   r.TaxRefund,
+		}
+		progress.Processed(i, t)
+		taxes = append(taxes, t)
+	}
+
+	if err := es.Err(); err != nil {
+		return []Taxes{}, err
+	}
+	return taxes, nil
+}
+
+func (rs *Runs) Close() {
+	rs.once.Do(func() { close(rs.closed) })
+}
+
+func (rs *Runs) owner(c echo.Context) string {
+	if rs == nil || rs.cfg.Owner == nil {
+		return ""
+	}
+	return rs.cfg.Owner(c)
+}
+
+func (rs *Runs) run(owner, id string) (*Run, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	now := time.Now()
+	pending := 0
+	for k, r := range rs.runs {
+		if r.expired(now) {
+			delete(rs.runs, k)
+		} else if r.owner == owner && r.pending() {
+			pending++
+		}
+	}
+
+	key := owner + "\x00" + id
+	r, ok := rs.runs[key]
+	if !ok {
+		if pending >= rs.cfg.MaxPending {
+			return nil, errTooManyRuns
+		}
+		r = newRun(id, rs.cfg.Retention)
+		r.owner = owner
+		rs.runs[key] = r
+	}
+	return r, nil
+}
+
+// subscribe returns the owner's run, creating it so clients can subscribe
+// with their own run id before they upload.
+func (rs *Runs) subscribe(c echo.Context, id string) (*Run, int, error) {
+	owner := rs.owner(c)
+	if owner == "" {
+		return nil, http.StatusUnauthorized, errRunKeyRequired
+	}
+
+	r, err := rs.run(owner, id)
+	if err != nil {
+		return nil, http.StatusTooManyRequests, err
+	}
+	return r, http.StatusOK, nil
+}
+
+// start takes the run id from X-Batch-Run-Id, or generates one, and marks
+// the run as started. Anonymous uploads get an untracked run without an id.
+func (rs *Runs) start(c echo.Context) (*Run, int, error) {
+	owner := rs.owner(c)
+	if owner == "" {
+		return newRun("", DefaultRunRetention), http.StatusOK, nil
+	}
+
+	id := c.Request().Header.Get(HeaderRunID)
+	if id == "" {
+		var err error
+		if id, err = newID(); err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+	} else if err := ValidateRunID(id); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	r, err := rs.run(owner, id)
+	if err != nil {
+		return nil, http.StatusTooManyRequests, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.started {
+		return nil, http.StatusConflict, errRunExists
+	}
+	r.started = true
+	r.lang = i18n.From(c)
+	r.expiresAt = time.Time{}
+
+	return r, http.StatusOK, nil
+}
+
+func newRun(id string, retention time.Duration) *Run {
+	return &Run{
+		ID:        id,
+		changed:   make(chan struct{}),
+		retention: retention,
+		expiresAt: time.Now().Add(retention),
+	}
+}
+
+func (r *Run) Processed(row int, t Taxes) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.totals.Processed++
+	r.totals.Succeeded++
+
+	p := r.totals
+	p.Row = row + 1
+	p.Result = &t
+	r.emit(EventRowProcessed, p)
+}
+
+func (r *Run) Failed(row int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.totals.Processed++
+	r.totals.Failed++
+
+	pr := problem.Localized(r.lang, http.StatusBadRequest, err)
+	p := r.totals
+	p.Row = row + 1
+	p.Error = &pr
+	r.emit(EventRowFailed, p)
+}
+
+func (r *Run) begin(total int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.totals.Total = total
+}
+
+func (r *Run) complete(status int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p := r.totals
+	if err != nil {
+		pr := problem.Localized(r.lang, status, err)
+		p.Error = &pr
+	}
+	r.emit(EventCompleted, p)
+	r.done = true
+	r.expiresAt = time.Now().Add(r.retention)
+}
+
+func (r *Run) emit(event string, p RunProgress) {
+	r.events = append(r.events, RunEvent{ID: len(r.events) + 1, Type: event, Data: p})
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+func (r *Run) since(last int) ([]RunEvent, <-chan struct{}, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var evs []RunEvent
+	if last < 0 {
+		last = 0
+	}
+	if last < len(r.events) {
+		evs = append(evs, r.events[last:]...)
+	}
+	return evs, r.changed, r.done
+}
+
+func (r *Run) pending() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return !r.done
+}
+
+func (r *Run) expired(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return !r.expiresAt.IsZero() && now.After(r.expiresAt)
+}
+
+func (ev RunEvent) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.Marshal(ev.Data)
+	if err != nil {
+		return 0, err
+	}
+	n, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data)
+	return int64(n), err
+}
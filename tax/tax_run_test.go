@@ -0,0 +1,376 @@
+package tax
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/allowance"
+)
+
+const runCSV = `totalIncome,wht,donation
+500000.0,0.0,0.0
+-1.0,0.0,0.0
+600000.0,40000.0,20000.0
+`
+
+type progressRecorder struct {
+	events []string
+}
+
+func (p *progressRecorder) Processed(row int, t Taxes) {
+	p.events = append(p.events, EventRowProcessed+":"+strconv.Itoa(row))
+}
+
+func (p *progressRecorder) Failed(row int, err error) {
+	p.events = append(p.events, EventRowFailed+":"+strconv.Itoa(row))
+}
+
+type streamed struct {
+	id    int
+	event string
+	data  RunProgress
+}
+
+// keyOwner stands in for apikey.Owner: requests belong to key 1 unless
+// X-Owner names another key.
+func keyOwner(c echo.Context) string {
+	if o := c.Request().Header.Get("X-Owner"); o != "" {
+		return o
+	}
+	return "key:1"
+}
+
+func runServer(t *testing.T, opts ...Option) (*httptest.Server, *Handler) {
+	t.Helper()
+
+	h := New(&stub{}, append([]Option{WithRuns(NewRuns(RunConfig{Owner: keyOwner}))}, opts...)...)
+	e := echo.New()
+	e.POST("/tax/calculations/upload-csv", h.TaxCSVHandler)
+	e.GET("/tax/calculations/runs/:id/events", h.TaxRunEventsHandler)
+
+	srv := httptest.NewServer(e)
+	t.Cleanup(srv.Close)
+	return srv, h
+}
+
+func uploadRun(t *testing.T, srv *httptest.Server, runID, csv string) *http.Response {
+	t.Helper()
+
+	var buffer bytes.Buffer
+	writer := multipart.NewWriter(&buffer)
+	formFile, _ := writer.CreateFormFile("file", "file.csv")
+	formFile.Write([]byte(csv))
+	writer.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/tax/calculations/upload-csv", &buffer)
+	req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+	if runID != "" {
+		req.Header.Set(HeaderRunID, runID)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+	res.Body.Close()
+	return res
+}
+
+func subscribe(t *testing.T, srv *httptest.Server, runID string, header http.Header) *http.Response {
+	t.Helper()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/tax/calculations/runs/"+runID+"/events", nil)
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+	return res
+}
+
+func readEvents(res *http.Response) []streamed {
+	var evs []streamed
+	var cur streamed
+
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if cur.event != "" {
+				evs = append(evs, cur)
+			}
+			cur = streamed{}
+		case strings.HasPrefix(line, "id: "):
+			cur.id, _ = strconv.Atoi(strings.TrimPrefix(line, "id: "))
+		case strings.HasPrefix(line, "event: "):
+			cur.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &cur.data)
+		}
+	}
+	return evs
+}
+
+func TestCalculateRows(t *testing.T) {
+	t.Run("should report every row and return the row errors", func(t *testing.T) {
+		p := &progressRecorder{}
+		tds := []TaxDetails{{TotalIncome: 500000.0}, {TotalIncome: -1.0}, {TotalIncome: 600000.0}}
+
+		got, err := CalculateRows(tds, mockCaps(tds), p)
+
+		want := []string{EventRowProcessed + ":0", EventRowFailed + ":1", EventRowProcessed + ":2"}
+		if strings.Join(p.events, ",") != strings.Join(want, ",") {
+			t.Errorf("expected %v but got %v", want, p.events)
+		}
+
+		wantErr := "row 2: " + ErrInvalidTotalIncome
+		if err == nil || err.Error() != wantErr || len(got) != 0 {
+			t.Errorf("expected %v and no taxes but got %v %v", wantErr, got, err)
+		}
+	})
+
+	t.Run("should not schedule payments for v1 rows", func(t *testing.T) {
+		tds := []TaxDetails{{TotalIncome: 200000.0, Installments: true}}
+
+		got, err := CalculateRows(tds, mockCaps(tds), &progressRecorder{})
+
+		if err != nil || len(got) != 1 || got[0].Tax != 0.0 {
+			t.Errorf("expected tax 0.0 without installment checks but got %+v %v", got, err)
+		}
+	})
+
+	t.Run("should return taxes in row order", func(t *testing.T) {
+		tds := []TaxDetails{
+			{TotalIncome: 500000.0},
+			{TotalIncome: 500000.0, Allowances: []allowance.Allowance{{AllowanceType: allowance.Donation, Amount: 200000.0}}},
+		}
+
+		got, err := CalculateRows(tds, mockCaps(tds), &progressRecorder{})
+
+		if err != nil || len(got) != 2 || got[0].Tax != 29000.0 || got[1].Tax != 19000.0 {
+			t.Errorf("expected taxes 29000.0 and 19000.0 but got %+v %v", got, err)
+		}
+	})
+}
+
+func TestValidateRunID(t *testing.T) {
+	testCases := []struct {
+		id    string
+		valid bool
+	}{
+		{"run-1", true},
+		{"c0ffee_42", true},
+		{strings.Repeat("a", maxRunID), true},
+		{"", false},
+		{strings.Repeat("a", maxRunID+1), false},
+		{"run 1", false},
+		{"run/1", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			if err := ValidateRunID(tc.id); (err == nil) != tc.valid {
+				t.Errorf("expected valid %v but got %v", tc.valid, err)
+			}
+		})
+	}
+}
+
+func TestTaxRunEvents(t *testing.T) {
+	t.Run("should stream row events with running totals to a subscriber opened with a client run id before the upload", func(t *testing.T) {
+		srv, _ := runServer(t)
+
+		res := subscribe(t, srv, "run-1", nil)
+		if res.StatusCode != http.StatusOK || res.Header.Get(echo.HeaderContentType) != "text/event-stream" {
+			t.Fatalf("expected an event stream but got %v %v", res.StatusCode, res.Header.Get(echo.HeaderContentType))
+		}
+
+		done := make(chan []streamed)
+		go func() { done <- readEvents(res) }()
+
+		up := uploadRun(t, srv, "run-1", runCSV)
+		if up.Header.Get(HeaderRunID) != "run-1" {
+			t.Errorf("expected run id header run-1 but got %q", up.Header.Get(HeaderRunID))
+		}
+
+		var got []streamed
+		select {
+		case got = <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("stream did not end after completed")
+		}
+
+		if len(got) != 4 {
+			t.Fatalf("expected 4 events but got %+v", got)
+		}
+
+		want := []struct {
+			event                                  string
+			row, processed, succeeded, failed, all int
+		}{
+			{EventRowProcessed, 1, 1, 1, 0, 3},
+			{EventRowFailed, 2, 2, 1, 1, 3},
+			{EventRowProcessed, 3, 3, 2, 1, 3},
+			{EventCompleted, 0, 3, 2, 1, 3},
+		}
+		for i, w := range want {
+			ev := got[i]
+			if ev.id != i+1 || ev.event != w.event || ev.data.Row != w.row || ev.data.Processed != w.processed ||
+				ev.data.Succeeded != w.succeeded || ev.data.Failed != w.failed || ev.data.Total != w.all {
+				t.Errorf("event %d: expected %+v but got %+v", i, w, ev)
+			}
+		}
+
+		if got[0].data.Result == nil || got[0].data.Result.Tax != 29000.0 {
+			t.Errorf("expected the first row result but got %+v", got[0].data.Result)
+		}
+		if got[1].data.Error == nil || got[1].data.Error.Code != "TAX_TOTAL_INCOME_NEGATIVE" {
+			t.Errorf("expected the row error but got %+v", got[1].data.Error)
+		}
+		if got[3].data.Error == nil || got[3].data.Error.Status != up.StatusCode {
+			t.Errorf("expected completed to carry the upload error %v but got %+v", up.StatusCode, got[3].data.Error)
+		}
+	})
+
+	t.Run("should replay a finished run after Last-Event-ID", func(t *testing.T) {
+		srv, _ := runServer(t)
+		uploadRun(t, srv, "run-2", "totalIncome,wht,donation\n500000.0,0.0,0.0\n")
+
+		got := readEvents(subscribe(t, srv, "run-2", http.Header{HeaderLastEventID: {"1"}}))
+
+		if len(got) != 1 || got[0].id != 2 || got[0].event != EventCompleted || got[0].data.Error != nil || got[0].data.Succeeded != 1 {
+			t.Errorf("expected only a successful completed event but got %+v", got)
+		}
+	})
+
+	t.Run("should generate a run id when none is given", func(t *testing.T) {
+		srv, _ := runServer(t)
+		up := uploadRun(t, srv, "", "totalIncome,wht,donation\n500000.0,0.0,0.0\n")
+
+		id := up.Header.Get(HeaderRunID)
+		if ValidateRunID(id) != nil {
+			t.Fatalf("expected a generated run id but got %q", id)
+		}
+
+		if got := readEvents(subscribe(t, srv, id, nil)); len(got) != 2 {
+			t.Errorf("expected 2 events for the generated run but got %+v", got)
+		}
+	})
+
+	t.Run("should complete the run with the error when the upload is invalid", func(t *testing.T) {
+		srv, _ := runServer(t)
+		up := uploadRun(t, srv, "run-3", "income,wht,donation\n1,0,0\n")
+
+		got := readEvents(subscribe(t, srv, "run-3", nil))
+
+		if len(got) != 1 || got[0].event != EventCompleted || got[0].data.Error == nil || got[0].data.Error.Status != up.StatusCode {
+			t.Errorf("expected a completed event with the upload error but got %+v", got)
+		}
+	})
+
+	t.Run("should return 409 when a run id is reused", func(t *testing.T) {
+		srv, _ := runServer(t)
+		uploadRun(t, srv, "run-4", "totalIncome,wht,donation\n500000.0,0.0,0.0\n")
+
+		if up := uploadRun(t, srv, "run-4", "totalIncome,wht,donation\n500000.0,0.0,0.0\n"); up.StatusCode != http.StatusConflict {
+			t.Errorf("expected status code %v but got %v", http.StatusConflict, up.StatusCode)
+		}
+	})
+
+	t.Run("should return 400 for an invalid run id", func(t *testing.T) {
+		srv, _ := runServer(t)
+
+		if up := uploadRun(t, srv, "run 5", runCSV); up.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected upload status code %v but got %v", http.StatusBadRequest, up.StatusCode)
+		}
+
+		if res := subscribe(t, srv, strings.Repeat("a", maxRunID+1), nil); res.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected stream status code %v but got %v", http.StatusBadRequest, res.StatusCode)
+		}
+	})
+
+	t.Run("should return 400 for an invalid Last-Event-ID", func(t *testing.T) {
+		srv, _ := runServer(t)
+		uploadRun(t, srv, "run-10", "totalIncome,wht,donation\n500000.0,0.0,0.0\n")
+
+		for _, last := range []string{"-1", "one"} {
+			if res := subscribe(t, srv, "run-10", http.Header{HeaderLastEventID: {last}}); res.StatusCode != http.StatusBadRequest {
+				t.Errorf("expected status code %v for %q but got %v", http.StatusBadRequest, last, res.StatusCode)
+			}
+		}
+	})
+
+	t.Run("should not show runs of other owners and end streams on close", func(t *testing.T) {
+		runs := NewRuns(RunConfig{Owner: keyOwner})
+		srv, _ := runServer(t, WithRuns(runs))
+		uploadRun(t, srv, "run-6", "totalIncome,wht,donation\n500000.0,0.0,0.0\n")
+
+		res := subscribe(t, srv, "run-6", http.Header{"X-Owner": {"key:2"}})
+		done := make(chan []streamed)
+		go func() { done <- readEvents(res) }()
+
+		runs.Close()
+
+		select {
+		case got := <-done:
+			if len(got) != 0 {
+				t.Errorf("expected no events from another owner but got %+v", got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("stream did not end after close")
+		}
+	})
+
+	t.Run("should not keep runs of anonymous callers", func(t *testing.T) {
+		srv, _ := runServer(t, WithRuns(NewRuns(RunConfig{})))
+
+		up := uploadRun(t, srv, "run-7", "totalIncome,wht,donation\n500000.0,0.0,0.0\n")
+		if up.StatusCode != http.StatusOK || up.Header.Get(HeaderRunID) != "" {
+			t.Errorf("expected the upload to succeed without a run id but got %v %q", up.StatusCode, up.Header.Get(HeaderRunID))
+		}
+
+		if res := subscribe(t, srv, "run-7", nil); res.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected stream status code %v but got %v", http.StatusUnauthorized, res.StatusCode)
+		}
+	})
+
+	t.Run("should cap the pending runs of an owner", func(t *testing.T) {
+		runs := NewRuns(RunConfig{MaxPending: 1, Owner: keyOwner})
+		srv, _ := runServer(t, WithRuns(runs))
+		t.Cleanup(runs.Close)
+
+		if res := subscribe(t, srv, "run-8", nil); res.StatusCode != http.StatusOK {
+			t.Fatalf("expected status code %v but got %v", http.StatusOK, res.StatusCode)
+		}
+
+		if res := subscribe(t, srv, "run-9", nil); res.StatusCode != http.StatusTooManyRequests {
+			t.Errorf("expected stream status code %v but got %v", http.StatusTooManyRequests, res.StatusCode)
+		}
+		if up := uploadRun(t, srv, "", runCSV); up.StatusCode != http.StatusTooManyRequests {
+			t.Errorf("expected upload status code %v but got %v", http.StatusTooManyRequests, up.StatusCode)
+		}
+		if res := subscribe(t, srv, "run-9", http.Header{"X-Owner": {"key:2"}}); res.StatusCode != http.StatusOK {
+			t.Errorf("expected another owner to get status code %v but got %v", http.StatusOK, res.StatusCode)
+		}
+
+		uploadRun(t, srv, "run-8", "totalIncome,wht,donation\n500000.0,0.0,0.0\n")
+
+		if res := subscribe(t, srv, "run-9", nil); res.StatusCode != http.StatusOK {
+			t.Errorf("expected status code %v after the pending run completed but got %v", http.StatusOK, res.StatusCode)
+		}
+	})
+}
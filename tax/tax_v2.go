@@ -0,0 +1,177 @@
+package tax
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/varissara-wo/assessment-tax/allowance"
+	"github.com/varissara-wo/assessment-tax/i18n"
+)
+
+type IncomeCategory string
+
+const (
+	Salary       IncomeCategory = "salary"
+	Service      IncomeCategory = "service"
+	Royalty      IncomeCategory = "royalty"
+	Investment   IncomeCategory = "investment"
+	Rental       IncomeCategory = "rental"
+	Professional IncomeCategory = "professional"
+	Contract     IncomeCategory = "contract"
+	Business     IncomeCategory = "business"
+)
+
+var IncomeCategories = []IncomeCategory{Salary, Service, Royalty, Investment, Rental, Professional, Contract, Business}
+
+const (
+	ErrEmptyIncomes          = "incomes must not be empty"
+	ErrInvalidIncomeCategory = "category must be salary, service, royalty, investment, rental, professional, contract or business"
+	ErrInvalidIncomeAmount   = "income amount must be greater than or equal to 0"
+)
+
+type Decimal float64
+
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(strconv.FormatFloat(math.Round(float64(d)*100)/100, 'f', 2, 64))), nil
+}
+
+type Income struct {
+	Category IncomeCategory `json:"category"`
+	Amount   float64        `json:"amount"`
+}
+
+type CalculationRequest struct {
+	Incomes      []Income              `json:"incomes"`
+	WHT          float64               `json:"wht"`
+	Allowances   []allowance.Allowance `json:"allowances"`
+	TaxYear      int                   `json:"taxYear,omitempty"`
+	FilingDate   string                `json:"filingDate,omitempty"`
+	DueDate      string                `json:"dueDate,omitempty"`
+	Installments bool                  `json:"installments,omitempty"`
+}
+
+type IncomeLine struct {
+	Category IncomeCategory `json:"category"`
+	Amount   Decimal        `json:"amount"`
+}
+
+type IncomeSummary struct {
+	Total      Decimal      `json:"total"`
+	Categories []IncomeLine `json:"categories,omitempty"`
+}
+
+type AllowanceClaim struct {
+	AllowanceType allowance.AllowanceType `json:"allowanceType"`
+	Name          string                  `json:"name"`
+	Claimed       Decimal                 `json:"claimed"`
+}
+
+type Bracket struct {
+	Level         string   `json:"level"`
+	From          Decimal  `json:"from"`
+	To            *Decimal `json:"to,omitempty"`
+	Rate          Decimal  `json:"rate"`
+	TaxableIncome Decimal  `json:"taxableIncome"`
+	Tax           Decimal  `json:"tax"`
+}
+
+type Calculation struct {
+	Income          IncomeSummary    `json:"income"`
+	Allowances      []AllowanceClaim `json:"allowances"`
+	TotalAllowance  Decimal          `json:"totalAllowance"`
+	NetIncome       Decimal          `json:"netIncome"`
+	Brackets        []Bracket        `json:"brackets"`
+	TotalTax        Decimal          `json:"totalTax"`
+	WHT             Decimal          `json:"wht"`
+	Tax             Decimal          `json:"tax"`
+	TaxRefund       Decimal          `json:"taxRefund"`
+	PaymentSchedule *PaymentSchedule `json:"paymentSchedule,omitempty"`
+	Installments    []Installment    `json:"installments,omitempty"`
+}
+
+type Calculations struct {
+	Calculations []Calculation `json:"calculations"`
+}
+
+func (cr CalculationRequest) TaxDetails() TaxDetails {
+	td := TaxDetails{
+		WHT:          cr.WHT,
+		Allowances:   cr.Allowances,
+		TaxYear:      cr.TaxYear,
+		FilingDate:   cr.FilingDate,
+		DueDate:      cr.DueDate,
+		Installments: cr.Installments,
+	}
+	for _, i := range cr.Incomes {
+		td.TotalIncome += i.Amount
+	}
+	return td
+}
+
+func (ts TaxSummary) Calculation(tr TaxResponse, incomes []Income, l i18n.Lang) Calculation {
+	c := Calculation{
+		Income:          IncomeSummary{Total: Decimal(ts.TotalIncome)},
+		Allowances:      []AllowanceClaim{},
+		TotalAllowance:  Decimal(ts.TotalAllowance),
+		NetIncome:       Decimal(ts.NetIncome),
+		Brackets:        brackets(ts.NetIncome, ts.TaxLevel, l),
+		TotalTax:        Decimal(ts.TotalTax),
+		WHT:             Decimal(ts.WHT),
+		Tax:             Decimal(tr.Tax),
+		TaxRefund:       Decimal(tr.TaxRefund),
+		PaymentSchedule: tr.PaymentSchedule,
+		Installments:    tr.Installments,
+	}
+
+	for _, i := range incomes {
+		c.Income.Categories = append(c.Income.Categories, IncomeLine{Category: i.Category, Amount: Decimal(i.Amount)})
+	}
+
+	for _, a := range ts.Allowances {
+		c.Allowances = append(c.Allowances, AllowanceClaim{
+			AllowanceType: a.AllowanceType,
+			Name:          a.AllowanceType.Name(l),
+			Claimed:       Decimal(a.Amount),
+		})
+	}
+
+	return c
+}
+
+func (ts TaxSummary) TaxResponse() TaxResponse {
+	return TaxResponse{Tax: ts.Tax, TaxRefund: ts.TaxRefund, TaxLevel: ts.TaxLevel}
+}
+
+func BracketTable(l i18n.Lang) []Bracket {
+	bs := make([]Bracket, len(taxBrackets))
+
+	from := 0.0
+	for i, tb := range taxBrackets {
+		bs[i] = Bracket{Level: l.T(tb.Key), From: Decimal(from), Rate: Decimal(tb.TaxRate)}
+		if tb.MaxIncome != math.MaxFloat64 {
+			to := Decimal(tb.MaxIncome)
+			bs[i].To = &to
+		}
+		from = tb.MaxIncome
+	}
+
+	return bs
+}
+
+func brackets(netIncome float64, tbl []TaxBreakdown, l i18n.Lang) []Bracket {
+	if len(tbl) != len(taxBrackets) {
+		return []Bracket{}
+	}
+
+	bs := BracketTable(l)
+	for i := range bs {
+		bs[i].Tax = Decimal(tbl[i].Tax)
+
+		from := float64(bs[i].From)
+		if netIncome > from {
+			bs[i].TaxableIncome = Decimal(math.Min(netIncome, taxBrackets[i].MaxIncome) - from)
+		}
+	}
+
+	return bs
+}
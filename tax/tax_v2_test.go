@@ -0,0 +1,186 @@
+package tax
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/allowance"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+func post(h echo.HandlerFunc, body string) *httptest.ResponseRecorder {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	h(e.NewContext(req, rec))
+	return rec
+}
+
+func upload(h echo.HandlerFunc, csv string) *httptest.ResponseRecorder {
+	var buffer bytes.Buffer
+	writer := multipart.NewWriter(&buffer)
+	formFile, _ := writer.CreateFormFile("file", "file.csv")
+	formFile.Write([]byte(csv))
+	writer.Close()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/", &buffer)
+	req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+	rec := httptest.NewRecorder()
+	h(e.NewContext(req, rec))
+	return rec
+}
+
+func fields(t *testing.T, v interface{}) []string {
+	t.Helper()
+
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected an object but got %v", v)
+	}
+
+	var ks []string
+	for k := range m {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	return ks
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("expected json but got %v", rec.Body.String())
+	}
+	return got
+}
+
+func TestV1Contract(t *testing.T) {
+	t.Run("should keep the calculation shape", func(t *testing.T) {
+		h := New(&stub{Tax: CalculateTax(440000.0, 0.0)})
+		got := decode(t, post(h.TaxHandler, `{"totalIncome": 500000.0}`))
+
+		if want := []string{"tax", "taxLevel", "taxRefund"}; !reflect.DeepEqual(fields(t, got), want) {
+			t.Errorf("expected fields %v but got %v", want, fields(t, got))
+		}
+
+		if _, ok := got["tax"].(float64); !ok {
+			t.Errorf("expected tax to be a number but got %v", got["tax"])
+		}
+
+		levels := got["taxLevel"].([]interface{})
+		if want := []string{"level", "tax"}; len(levels) != len(taxBrackets) || !reflect.DeepEqual(fields(t, levels[0]), want) {
+			t.Errorf("expected %v levels with fields %v but got %v", len(taxBrackets), want, levels)
+		}
+	})
+
+	t.Run("should keep the csv shape", func(t *testing.T) {
+		h := New(&stub{Taxes: []Taxes{{TotalIncome: 500000.0, Tax: 29000.0}}})
+		got := decode(t, upload(h.TaxCSVHandler, "totalIncome,wht,donation\n500000.0,0.0,0.0\n"))
+
+		if want := []string{"taxes"}; !reflect.DeepEqual(fields(t, got), want) {
+			t.Errorf("expected fields %v but got %v", want, fields(t, got))
+		}
+
+		taxes := got["taxes"].([]interface{})
+		if want := []string{"tax", "taxRefund", "totalIncome"}; len(taxes) != 1 || !reflect.DeepEqual(fields(t, taxes[0]), want) {
+			t.Errorf("expected taxes with fields %v but got %v", want, taxes)
+		}
+	})
+}
+
+func TestV2Contract(t *testing.T) {
+	td := TaxDetails{
+		TotalIncome: 500000.0,
+		WHT:         25000.0,
+		Allowances:  []allowance.Allowance{{AllowanceType: allowance.Donation, Amount: 200000.0}},
+	}
+
+	t.Run("should return the expanded calculation", func(t *testing.T) {
+		h := New(&stub{Summary: td.Summarize(mockMaxAllowance)})
+		rec := post(h.TaxV2Handler, `{
+			"incomes": [{"category": "salary", "amount": 400000.0}, {"category": "rental", "amount": 100000.0}],
+			"wht": 25000.0,
+			"allowances": [{"allowanceType": "donation", "amount": 200000.0}]
+		}`)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status code %v but got %v %v", http.StatusOK, rec.Code, rec.Body.String())
+		}
+
+		got := decode(t, rec)
+
+		want := []string{"allowances", "brackets", "income", "netIncome", "tax", "taxRefund", "totalAllowance", "totalTax", "wht"}
+		if !reflect.DeepEqual(fields(t, got), want) {
+			t.Errorf("expected fields %v but got %v", want, fields(t, got))
+		}
+
+		if got["tax"] != "0.00" || got["taxRefund"] != "6000.00" || got["totalTax"] != "19000.00" || got["netIncome"] != "340000.00" {
+			t.Errorf("expected decimal strings but got %v", got)
+		}
+
+		income := got["income"].(map[string]interface{})
+		categories := income["categories"].([]interface{})
+		if income["total"] != "500000.00" || len(categories) != 2 || categories[1].(map[string]interface{})["category"] != "rental" {
+			t.Errorf("expected income categories but got %v", income)
+		}
+
+		brackets := got["brackets"].([]interface{})
+		second := brackets[1].(map[string]interface{})
+		if len(brackets) != len(taxBrackets) || second["taxableIncome"] != "190000.00" || second["rate"] != "0.10" || second["tax"] != "19000.00" {
+			t.Errorf("expected bracket breakdown but got %v", brackets)
+		}
+
+		if _, ok := brackets[len(brackets)-1].(map[string]interface{})["to"]; ok {
+			t.Errorf("expected the top bracket to have no upper bound but got %v", brackets[len(brackets)-1])
+		}
+
+		allowances := got["allowances"].([]interface{})
+		if len(allowances) != len(formAllowances) || fields(t, allowances[0])[0] != "allowanceType" {
+			t.Errorf("expected claimed allowances but got %v", allowances)
+		}
+	})
+
+	t.Run("should return the expanded calculation for every csv row", func(t *testing.T) {
+		h := New(&stub{Summaries: []TaxSummary{td.Summarize(mockMaxAllowance), td.Summarize(mockMaxAllowance)}})
+		got := decode(t, upload(h.TaxCSVV2Handler, "totalIncome,wht,donation\n500000.0,25000.0,200000.0\n500000.0,25000.0,200000.0\n"))
+
+		cs := got["calculations"].([]interface{})
+		if len(cs) != 2 || cs[0].(map[string]interface{})["taxRefund"] != "6000.00" {
+			t.Errorf("expected 2 calculations but got %v", got)
+		}
+	})
+
+	t.Run("should report every invalid income", func(t *testing.T) {
+		rec := post(New(&stub{}).TaxV2Handler, `{"incomes": [{"category": "lottery", "amount": -1.0}]}`)
+
+		var got problem.Problem
+		json.Unmarshal(rec.Body.Bytes(), &got)
+
+		if rec.Code != http.StatusBadRequest || len(got.Details) != 2 || got.Details[0].Field != "incomes[0].category" || got.Details[1].Field != "incomes[0].amount" {
+			t.Errorf("expected errors on the category and amount but got %v %+v", rec.Code, got)
+		}
+	})
+
+	t.Run("should require incomes", func(t *testing.T) {
+		rec := post(New(&stub{}).TaxV2Handler, `{"wht": 0.0}`)
+
+		var got problem.Problem
+		json.Unmarshal(rec.Body.Bytes(), &got)
+
+		if got.Code != "TAX_INCOMES_EMPTY" {
+			t.Errorf("expected TAX_INCOMES_EMPTY but got %+v", got)
+		}
+	})
+}
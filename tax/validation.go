@@ -1,39 +1,145 @@
 package tax
 
 import (
-	"errors"
+	"fmt"
+	"time"
 
 	"github.com/varissara-wo/assessment-tax/allowance"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+var (
+	errNegativeTotalIncome = problem.New("TAX_TOTAL_INCOME_NEGATIVE", "totalIncome", ErrInvalidTotalIncome)
+	errNegativeWHT         = problem.New("TAX_WHT_NEGATIVE", "wht", ErrInvalidWHT)
+	errWHTExceedsIncome    = problem.New("TAX_WHT_EXCEEDS_INCOME", "wht", ErrInvalidWHT)
+	errInvalidFilingDate   = problem.New("TAX_FILING_DATE_INVALID", "filingDate", ErrInvalidFilingDate)
+	errInvalidDueDate      = problem.New("TAX_DUE_DATE_INVALID", "dueDate", ErrInvalidDueDate)
+	errTaxYearOutOfRange   = problem.New("TAX_YEAR_INVALID", "taxYear", ErrInvalidTaxYear)
+	errInvalidCurveRange   = problem.New("TAX_CURVE_RANGE_INVALID", "from", ErrInvalidCurveRange)
+	errInvalidCurveStep    = problem.New("TAX_CURVE_STEP_INVALID", "step", ErrInvalidCurveStep)
+	errTooManyCurvePoints  = problem.New("TAX_CURVE_TOO_MANY_POINTS", "step", ErrTooManyCurvePoints)
+	errEmptyIncomes        = problem.New("TAX_INCOMES_EMPTY", "incomes", ErrEmptyIncomes)
+	errInvalidCategory     = problem.New("TAX_INCOME_CATEGORY_INVALID", "category", ErrInvalidIncomeCategory)
+	errNegativeIncome      = problem.New("TAX_INCOME_AMOUNT_NEGATIVE", "amount", ErrInvalidIncomeAmount)
 )
 
 func (td *TaxDetails) ValidateTaxDetails() error {
-	if err := validateTotalIncome(td.TotalIncome); err != nil {
-		return err
+	return td.validate(allowance.ValidateAllowance)
+}
+
+func (td *TaxDetails) ValidateRecommendation() error {
+	return td.validate(allowance.ValidateRecommendationAllowance)
+}
+
+func (td *TaxDetails) validate(validateAllowance func(allowance.Allowance) error) error {
+	var es problem.Errors
+
+	es.Add(validateTotalIncome(td.TotalIncome))
+	es.Add(validateWHT(td.WHT, td.TotalIncome))
+	es.Add(validateAllowances(td.Allowances, validateAllowance))
+	es.Add(validateTaxYear(td.TaxYear))
+	es.Add(validateDate(td.FilingDate, errInvalidFilingDate))
+	es.Add(validateDate(td.DueDate, errInvalidDueDate))
+
+	return es.Err()
+}
+
+func (cr *CalculationRequest) ValidateCalculationRequest() error {
+	var es problem.Errors
+
+	if len(cr.Incomes) == 0 {
+		es.Add(errEmptyIncomes)
+	}
+	for i, in := range cr.Incomes {
+		es.Add(problem.Nest(validateIncome(in), fmt.Sprintf("incomes[%d]", i), nil))
 	}
 
-	if err := validateWHT(td.WHT, td.TotalIncome); err != nil {
-		return err
+	td := cr.TaxDetails()
+	es.Add(validateWHT(td.WHT, td.TotalIncome))
+	es.Add(validateAllowances(td.Allowances, allowance.ValidateAllowance))
+	es.Add(validateTaxYear(td.TaxYear))
+	es.Add(validateDate(td.FilingDate, errInvalidFilingDate))
+	es.Add(validateDate(td.DueDate, errInvalidDueDate))
+
+	return es.Err()
+}
+
+func (cr *CurveRequest) ValidateCurveRequest() error {
+	var es problem.Errors
+
+	if cr.From < 0 || cr.From > cr.To {
+		es.Add(errInvalidCurveRange)
+	}
+
+	if cr.Step <= 0 {
+		es.Add(errInvalidCurveStep)
+	} else if (cr.To-cr.From)/cr.Step >= maxCurvePoints {
+		es.Add(errTooManyCurvePoints)
+	}
+
+	es.Add(validateAllowances(cr.Allowances, allowance.ValidateAllowance))
+
+	return es.Err()
+}
+
+func validateAllowances(as []allowance.Allowance, validate func(allowance.Allowance) error) error {
+	var es problem.Errors
+	for i, a := range as {
+		es.Add(problem.Nest(validate(a), fmt.Sprintf("allowances[%d]", i), nil))
 	}
+	return es.Err()
+}
+
+func validateIncome(in Income) error {
+	var es problem.Errors
 
-	for _, a := range td.Allowances {
-		if err := allowance.ValidateAllowance(a); err != nil {
-			return err
+	valid := false
+	for _, c := range IncomeCategories {
+		if in.Category == c {
+			valid = true
 		}
 	}
+	if !valid {
+		es.Add(errInvalidCategory)
+	}
 
-	return nil
+	if in.Amount < 0 {
+		es.Add(errNegativeIncome)
+	}
+
+	return es.Err()
 }
 
 func validateTotalIncome(i float64) error {
 	if i < 0 {
-		return errors.New(ErrInvalidTotalIncome)
+		return errNegativeTotalIncome
 	}
 	return nil
 }
 
 func validateWHT(wht, totalIncome float64) error {
-	if wht < 0 || wht > totalIncome {
-		return errors.New(ErrInvalidWHT)
+	if wht < 0 {
+		return errNegativeWHT
+	}
+	if totalIncome >= 0 && wht > totalIncome {
+		return errWHTExceedsIncome
+	}
+	return nil
+}
+
+func validateTaxYear(y int) error {
+	if y != 0 && (y < minTaxYear || y > maxTaxYear) {
+		return errTaxYearOutOfRange
+	}
+	return nil
+}
+
+func validateDate(d string, err error) error {
+	if d == "" {
+		return nil
+	}
+	if _, perr := time.Parse(DateLayout, d); perr != nil {
+		return err
 	}
 	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/varissara-wo/assessment-tax/allowance"
+	"github.com/varissara-wo/assessment-tax/problem"
 )
 
 func TestValidateTaxDetails(t *testing.T) {
@@ -117,3 +118,169 @@ func TestValidateTaxDetails(t *testing.T) {
 	}
 
 }
+
+func TestValidateCurveRequest(t *testing.T) {
+	testCases := []struct {
+		name          string
+		curveRequest  CurveRequest
+		expectedError error
+	}{
+		{
+			name:          "should return nil if curve request is valid",
+			curveRequest:  CurveRequest{From: 0.0, To: 1000000.0, Step: 10000.0},
+			expectedError: nil,
+		},
+		{
+			name:          "should return an error if from is less than 0",
+			curveRequest:  CurveRequest{From: -1.0, To: 1000000.0, Step: 10000.0},
+			expectedError: errors.New(ErrInvalidCurveRange),
+		},
+		{
+			name:          "should return an error if from is greater than to",
+			curveRequest:  CurveRequest{From: 2000000.0, To: 1000000.0, Step: 10000.0},
+			expectedError: errors.New(ErrInvalidCurveRange),
+		},
+		{
+			name:          "should return an error if step is 0",
+			curveRequest:  CurveRequest{From: 0.0, To: 1000000.0, Step: 0.0},
+			expectedError: errors.New(ErrInvalidCurveStep),
+		},
+		{
+			name:          "should return an error if curve has too many points",
+			curveRequest:  CurveRequest{From: 0.0, To: 1000000.0, Step: 1.0},
+			expectedError: errors.New(ErrTooManyCurvePoints),
+		},
+		{
+			name: "should return an error if allowance type is invalid",
+			curveRequest: CurveRequest{From: 0.0, To: 1000000.0, Step: 10000.0, Allowances: []allowance.Allowance{
+				{AllowanceType: "invalid", Amount: 0.0},
+			}},
+			expectedError: errors.New(allowance.ErrInvalidAllowance),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.curveRequest.ValidateCurveRequest()
+
+			if tc.expectedError == nil {
+				if err != nil {
+					t.Errorf("expected nil but got %v", err)
+				}
+				return
+			}
+
+			if err == nil || err.Error() != tc.expectedError.Error() {
+				t.Errorf("expected %v but got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidateTaxDetailsDates(t *testing.T) {
+	t.Run("should return an error if filing date is invalid", func(t *testing.T) {
+		td := TaxDetails{TotalIncome: 500000.0, FilingDate: "08/04/2025"}
+
+		err := td.ValidateTaxDetails()
+
+		if err == nil || err.Error() != ErrInvalidFilingDate {
+			t.Errorf("expected %v but got %v", ErrInvalidFilingDate, err)
+		}
+	})
+
+	t.Run("should return an error if due date is invalid", func(t *testing.T) {
+		td := TaxDetails{TotalIncome: 500000.0, FilingDate: "2025-04-08", DueDate: "2025-13-01"}
+
+		err := td.ValidateTaxDetails()
+
+		if err == nil || err.Error() != ErrInvalidDueDate {
+			t.Errorf("expected %v but got %v", ErrInvalidDueDate, err)
+		}
+	})
+}
+
+func TestValidateTaxYear(t *testing.T) {
+	for _, y := range []int{2499, 3000, 2025} {
+		td := TaxDetails{TotalIncome: 500000.0, TaxYear: y}
+
+		if err := td.ValidateTaxDetails(); err == nil || err.Error() != ErrInvalidTaxYear {
+			t.Errorf("expected %v for %v but got %v", ErrInvalidTaxYear, y, err)
+		}
+	}
+
+	td := TaxDetails{TotalIncome: 500000.0, TaxYear: 2568}
+	if err := td.ValidateTaxDetails(); err != nil {
+		t.Errorf("expected nil but got %v", err)
+	}
+}
+
+func TestValidateTaxDetailsCollectsErrors(t *testing.T) {
+	td := TaxDetails{
+		TotalIncome: 500000.0,
+		WHT:         600000.0,
+		Allowances: []allowance.Allowance{
+			{AllowanceType: "donation", Amount: 0.0},
+			{AllowanceType: "invalid", Amount: -1.0},
+		},
+		FilingDate: "08/04/2025",
+	}
+
+	err := td.ValidateTaxDetails()
+
+	var got problem.Errors
+	if !errors.As(err, &got) {
+		t.Fatalf("expected problem.Errors but got %v", err)
+	}
+
+	want := []struct{ code, field string }{
+		{"TAX_WHT_EXCEEDS_INCOME", "wht"},
+		{"ALLOWANCE_TYPE_INVALID", "allowances[1].allowanceType"},
+		{"ALLOWANCE_AMOUNT_NEGATIVE", "allowances[1].amount"},
+		{"TAX_FILING_DATE_INVALID", "filingDate"},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("expected %v errors but got %v", len(want), got)
+	}
+
+	for i, w := range want {
+		if got[i].Code != w.code || got[i].Field != w.field {
+			t.Errorf("expected %v on %v but got %v on %v", w.code, w.field, got[i].Code, got[i].Field)
+		}
+	}
+}
+
+func TestValidateRecommendation(t *testing.T) {
+	funds := []allowance.Allowance{
+		{AllowanceType: allowance.RMF, Amount: 100000.0},
+		{AllowanceType: allowance.SSF, Amount: 50000.0},
+	}
+
+	t.Run("should accept rmf and ssf for recommendations", func(t *testing.T) {
+		td := TaxDetails{TotalIncome: 500000.0, Allowances: funds}
+
+		if err := td.ValidateRecommendation(); err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+	})
+
+	t.Run("should reject rmf and ssf for calculations", func(t *testing.T) {
+		td := TaxDetails{TotalIncome: 500000.0, Allowances: funds}
+
+		err := td.ValidateTaxDetails()
+
+		if err == nil || err.Error() != allowance.ErrInvalidAllowance+"; "+allowance.ErrInvalidAllowance {
+			t.Errorf("expected %v twice but got %v", allowance.ErrInvalidAllowance, err)
+		}
+	})
+
+	t.Run("should reject unknown types for recommendations", func(t *testing.T) {
+		td := TaxDetails{TotalIncome: 500000.0, Allowances: []allowance.Allowance{{AllowanceType: "ltf", Amount: 1.0}}}
+
+		err := td.ValidateRecommendation()
+
+		if err == nil || err.Error() != allowance.ErrInvalidRecommendAllowance {
+			t.Errorf("expected %v but got %v", allowance.ErrInvalidRecommendAllowance, err)
+		}
+	})
+}
@@ -0,0 +1,367 @@
+package taxgrpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/varissara-wo/assessment-tax/allowance"
+	"github.com/varissara-wo/assessment-tax/apikey"
+	"github.com/varissara-wo/assessment-tax/i18n"
+	"github.com/varissara-wo/assessment-tax/problem"
+	"github.com/varissara-wo/assessment-tax/ratelimit"
+	"github.com/varissara-wo/assessment-tax/tax"
+	"github.com/varissara-wo/assessment-tax/taxpb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const errorDomain = "assessment-tax"
+
+type Storer interface {
+	tax.Storer
+	GetAllowances(at time.Time) (allowance.MaxAllowance, error)
+}
+
+type Server struct {
+	taxpb.UnimplementedTaxServiceServer
+
+	store Storer
+	cfg   Config
+}
+
+// The limiters and the row quota are shared with the HTTP routes so a client
+// has the same allowance on both.
+type Config struct {
+	Keys         *apikey.Handler
+	CalcLimiter  *ratelimit.Limiter
+	BatchLimiter *ratelimit.Limiter
+	RowQuota     *ratelimit.Quota
+	BatchLimit   int
+	BatchWorkers int
+}
+
+var scopes = map[string]apikey.Scope{
+	taxpb.TaxService_Calculate_FullMethodName:        apikey.Calc,
+	taxpb.TaxService_CalculateBatch_FullMethodName:   apikey.Batch,
+	taxpb.TaxService_GetAllowanceCaps_FullMethodName: apikey.Calc,
+}
+
+func New(store Storer, cfg Config) *Server {
+	if cfg.BatchLimit <= 0 {
+		cfg.BatchLimit = tax.DefaultBatchLimit
+	}
+	if cfg.BatchWorkers <= 0 {
+		cfg.BatchWorkers = 1
+	}
+	return &Server{store: store, cfg: cfg}
+}
+
+func (s *Server) Register(gs *grpc.Server) {
+	taxpb.RegisterTaxServiceServer(gs, s)
+}
+
+func (s *Server) Calculate(ctx context.Context, req *taxpb.CalculateRequest) (*taxpb.CalculateResponse, error) {
+	l := lang(ctx)
+	td := taxDetails(req)
+
+	if err := td.ValidateTaxDetails(); err != nil {
+		return nil, statusError(l, codes.InvalidArgument, err)
+	}
+
+	tr, err := s.store.TaxCalculation(td)
+	if err != nil {
+		return nil, statusError(l, codes.Internal, err)
+	}
+
+	return calculateResponse(tr.Localize(l)), nil
+}
+
+func (s *Server) CalculateBatch(stream taxpb.TaxService_CalculateBatchServer) error {
+	l := lang(stream.Context())
+
+	var reqs []*taxpb.CalculateRequest
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		// Rejected before the client finishes so a stream cannot buffer
+		// more than the limit.
+		if len(reqs) == s.cfg.BatchLimit {
+			return statusError(l, codes.InvalidArgument, tax.BatchTooLarge(s.cfg.BatchLimit))
+		}
+		reqs = append(reqs, req)
+	}
+
+	br := tax.BatchRequest{Items: make([]tax.BatchItem, len(reqs))}
+	for i, req := range reqs {
+		br.Items[i] = tax.BatchItem{ReferenceID: req.ReferenceId, TaxDetails: taxDetails(req)}
+	}
+
+	if err := br.ValidateBatchRequest(s.cfg.BatchLimit); err != nil {
+		return statusError(l, codes.InvalidArgument, err)
+	}
+
+	outcomes, err := s.store.TaxBatch(br.TaxDetails(), s.cfg.BatchWorkers)
+	if err != nil {
+		return statusError(l, codes.Internal, err)
+	}
+
+	res := &taxpb.CalculateBatchResponse{}
+	for _, r := range br.Results(outcomes, l).Results {
+		pr := &taxpb.BatchResult{Index: int32(r.Index), ReferenceId: r.ReferenceID}
+		if r.Result != nil {
+			pr.Result = calculateResponse(*r.Result)
+		}
+		if r.Error != nil {
+			for _, d := range r.Error.Details {
+				pr.Errors = append(pr.Errors, &taxpb.FieldError{Code: d.Code, Field: d.Field, Message: d.Message})
+			}
+		}
+		res.Results = append(res.Results, pr)
+	}
+
+	return stream.SendAndClose(res)
+}
+
+func (s *Server) GetAllowanceCaps(ctx context.Context, req *taxpb.GetAllowanceCapsRequest) (*taxpb.GetAllowanceCapsResponse, error) {
+	ma, err := s.store.GetAllowances(time.Now())
+	if err != nil {
+		return nil, statusError(lang(ctx), codes.Internal, err)
+	}
+
+	res := &taxpb.GetAllowanceCapsResponse{}
+	for _, t := range allowance.AllowanceTypes {
+		res.Caps = append(res.Caps, &taxpb.AllowanceCap{AllowanceType: string(t), MaxAmount: allowance.AllowanceAmount(ma).Get(t)})
+	}
+
+	return res, nil
+}
+
+func (s *Server) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ interface{}, err error) {
+	defer recoverPanic(ctx, &err)
+
+	if err := s.limit(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	if _, err := s.authorize(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) StreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	ctx := ss.Context()
+	defer recoverPanic(ctx, &err)
+
+	if err := s.limit(ctx, info.FullMethod); err != nil {
+		return err
+	}
+
+	k, err := s.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return err
+	}
+
+	if info.FullMethod == taxpb.TaxService_CalculateBatch_FullMethodName && s.cfg.RowQuota != nil {
+		ss = &quotaStream{ServerStream: ss, quota: s.cfg.RowQuota, key: ratelimit.PeerKey(k, peerIP(ctx)), max: s.cfg.BatchLimit}
+	}
+	return handler(srv, ss)
+}
+
+// recoverPanic turns a panic in a call into Internal, like the HTTP server's
+// Recover middleware, instead of stopping the process.
+func recoverPanic(ctx context.Context, err *error) {
+	if r := recover(); r != nil {
+		grpclog.Errorf("panic: %v\n%s", r, debug.Stack())
+		*err = statusError(lang(ctx), codes.Internal, fmt.Errorf("panic: %v", r))
+	}
+}
+
+// limit runs ahead of authorize, like the HTTP limiters run ahead of the key
+// lookup, so every call counts under the peer ip.
+func (s *Server) limit(ctx context.Context, method string) error {
+	l := s.cfg.CalcLimiter
+	if scopes[method] == apikey.Batch {
+		l = s.cfg.BatchLimiter
+	}
+	if l == nil {
+		return nil
+	}
+
+	if reset, ok := l.Allow(ratelimit.PeerKey(apikey.Key{}, peerIP(ctx))); !ok {
+		return exhausted(lang(ctx), ratelimit.RateLimited(reset), reset)
+	}
+	return nil
+}
+
+func (s *Server) authorize(ctx context.Context, method string) (apikey.Key, error) {
+	scope, ok := scopes[method]
+	if !ok || s.cfg.Keys == nil {
+		return apikey.Key{}, nil
+	}
+
+	k, code, err := s.cfg.Keys.Authorize(header(ctx, strings.ToLower(apikey.HeaderAPIKey)), scope)
+	if err != nil {
+		return apikey.Key{}, statusError(lang(ctx), grpcCode(code), err)
+	}
+
+	if k.ID != 0 {
+		if err := s.cfg.Keys.Touch(k); err != nil {
+			grpclog.Error(err)
+		}
+	}
+
+	return k, nil
+}
+
+// quotaStream counts the rows of a batch and takes them from the daily quota
+// once the client has sent them all. Empty and oversized batches are left for
+// the handler to reject without using the quota.
+type quotaStream struct {
+	grpc.ServerStream
+	quota *ratelimit.Quota
+	key   string
+	max   int
+	rows  int
+}
+
+func (q *quotaStream) RecvMsg(m interface{}) error {
+	err := q.ServerStream.RecvMsg(m)
+	if err == nil {
+		q.rows++
+		return nil
+	}
+	if err != io.EOF || q.rows == 0 || q.rows > q.max {
+		return err
+	}
+
+	if reset, ok := q.quota.Take(q.key, q.rows); !ok {
+		return exhausted(lang(q.Context()), tax.ErrRowQuota, reset)
+	}
+	return err
+}
+
+func Shutdown(ctx context.Context, gs *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		gs.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		gs.Stop()
+	}
+}
+
+func taxDetails(req *taxpb.CalculateRequest) tax.TaxDetails {
+	td := tax.TaxDetails{TotalIncome: req.TotalIncome, WHT: req.Wht}
+	for _, a := range req.Allowances {
+		td.Allowances = append(td.Allowances, allowance.Allowance{AllowanceType: allowance.AllowanceType(a.AllowanceType), Amount: a.Amount})
+	}
+	return td
+}
+
+func calculateResponse(tr tax.TaxResponse) *taxpb.CalculateResponse {
+	res := &taxpb.CalculateResponse{Tax: tr.Tax, TaxRefund: tr.TaxRefund}
+	for _, tb := range tr.TaxLevel {
+		res.TaxLevel = append(res.TaxLevel, &taxpb.TaxLevel{Level: tb.Level, Tax: tb.Tax})
+	}
+	return res
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+		return host
+	}
+	return p.Addr.String()
+}
+
+func header(ctx context.Context, key string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if vs := md.Get(key); len(vs) > 0 {
+		return vs[0]
+	}
+	return ""
+}
+
+func lang(ctx context.Context) i18n.Lang {
+	return i18n.Negotiate(header(ctx, strings.ToLower(i18n.HeaderAcceptLanguage)))
+}
+
+func statusError(l i18n.Lang, code codes.Code, err error) error {
+	p := problem.Localized(l, httpStatus(code), err)
+	st := status.New(code, p.Detail)
+
+	info := &errdetails.ErrorInfo{Reason: p.Code, Domain: errorDomain}
+	br := &errdetails.BadRequest{}
+	for _, d := range p.Details {
+		if d.Field != "" {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{Field: d.Field, Description: d.Message})
+		}
+	}
+
+	ds, derr := st.WithDetails(info)
+	if len(br.FieldViolations) > 0 {
+		ds, derr = st.WithDetails(info, br)
+	}
+
+	if derr != nil {
+		return st.Err()
+	}
+	return ds.Err()
+}
+
+func exhausted(l i18n.Lang, err error, reset time.Duration) error {
+	st := status.Convert(statusError(l, codes.ResourceExhausted, err))
+	ds, derr := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(reset)})
+	if derr != nil {
+		return st.Err()
+	}
+	return ds.Err()
+}
+
+var statuses = map[codes.Code]int{
+	codes.InvalidArgument:   http.StatusBadRequest,
+	codes.Unauthenticated:   http.StatusUnauthorized,
+	codes.PermissionDenied:  http.StatusForbidden,
+	codes.ResourceExhausted: http.StatusTooManyRequests,
+	codes.Internal:          http.StatusInternalServerError,
+}
+
+func httpStatus(code codes.Code) int {
+	if s, ok := statuses[code]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
+
+func grpcCode(status int) codes.Code {
+	for c, s := range statuses {
+		if s == status {
+			return c
+		}
+	}
+	return codes.Internal
+}
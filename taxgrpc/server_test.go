@@ -0,0 +1,438 @@
+package taxgrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/allowance"
+	"github.com/varissara-wo/assessment-tax/apikey"
+	"github.com/varissara-wo/assessment-tax/ratelimit"
+	"github.com/varissara-wo/assessment-tax/tax"
+	"github.com/varissara-wo/assessment-tax/taxpb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var caps = allowance.MaxAllowance{Donation: 100000.0, KReceipt: 50000.0, Personal: 60000.0, RMF: 500000.0, SSF: 200000.0}
+
+type stub struct {
+	err    error
+	panics bool
+}
+
+func (s *stub) TaxCalculation(td tax.TaxDetails) (tax.TaxResponse, error) {
+	if s.panics {
+		panic("calculation failed")
+	}
+	return tax.CalculateTax(td.CalculateNetIncome(caps), td.WHT), s.err
+}
+
+func (s *stub) TaxesCalculation(tds []tax.TaxDetails, p tax.Progress) ([]tax.Taxes, error) {
+	return nil, s.err
+}
+
+func (s *stub) TaxRecommendation(td tax.TaxDetails) (tax.RecommendationResponse, error) {
+	return td.Recommend(caps), s.err
+}
+
+func (s *stub) TaxCurve(cr tax.CurveRequest) (tax.CurveResponse, error) {
+	return tax.CurveResponse{}, s.err
+}
+
+func (s *stub) TaxSummary(td tax.TaxDetails) (tax.TaxSummary, error) {
+	return td.Summarize(caps), s.err
+}
+
+func (s *stub) TaxSummaries(tds []tax.TaxDetails) ([]tax.TaxSummary, error) {
+	return nil, s.err
+}
+
+func (s *stub) TaxBatch(tds []tax.TaxDetails, workers int) ([]tax.TaxOutcome, error) {
+	if s.panics {
+		panic("batch failed")
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return tax.CalculateTaxes(tds, repeatCaps(len(tds)), workers), nil
+}
+
+func repeatCaps(n int) []allowance.MaxAllowance {
+	mas := make([]allowance.MaxAllowance, n)
+	for i := range mas {
+		mas[i] = caps
+	}
+	return mas
+}
+
+func (s *stub) GetAllowances(at time.Time) (allowance.MaxAllowance, error) {
+	return caps, s.err
+}
+
+type keyStub struct {
+	keys []apikey.Key
+}
+
+func (s *keyStub) CreateKey(k apikey.Key) (apikey.Key, error) { return k, nil }
+func (s *keyStub) Keys() ([]apikey.Key, error)                { return s.keys, nil }
+func (s *keyStub) RevokeKey(id int) (apikey.Key, error)       { return apikey.Key{}, nil }
+func (s *keyStub) TouchKey(id int, at time.Time) error        { return nil }
+func (s *keyStub) KeyByHash(hash string) (apikey.Key, error) {
+	for _, k := range s.keys {
+		if k.Hash == hash {
+			return k, nil
+		}
+	}
+	return apikey.Key{}, apikey.ErrNotFound
+}
+
+func dial(t *testing.T, s *Server) taxpb.TaxServiceClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1024 * 1024)
+	gs := grpc.NewServer(grpc.UnaryInterceptor(s.UnaryInterceptor), grpc.StreamInterceptor(s.StreamInterceptor))
+	s.Register(gs)
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return taxpb.NewTaxServiceClient(conn)
+}
+
+func request() *taxpb.CalculateRequest {
+	return &taxpb.CalculateRequest{
+		TotalIncome: 500000.0,
+		Wht:         25000.0,
+		Allowances:  []*taxpb.Allowance{{AllowanceType: "donation", Amount: 200000.0}, {AllowanceType: "k-receipt", Amount: 70000.0}},
+	}
+}
+
+func TestCalculate(t *testing.T) {
+	t.Run("should return the same numbers as the REST handler", func(t *testing.T) {
+		st := &stub{}
+		got, err := dial(t, New(st, Config{})).Calculate(context.Background(), request())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		e := echo.New()
+		body := `{"totalIncome": 500000.0, "wht": 25000.0, "allowances": [{"allowanceType": "donation", "amount": 200000.0}, {"allowanceType": "k-receipt", "amount": 70000.0}]}`
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		tax.New(st).TaxHandler(e.NewContext(req, rec))
+
+		var want tax.TaxResponse
+		json.Unmarshal(rec.Body.Bytes(), &want)
+
+		if got.Tax != want.Tax || got.TaxRefund != want.TaxRefund || len(got.TaxLevel) != len(want.TaxLevel) {
+			t.Fatalf("expected %+v but got %+v", want, got)
+		}
+		for i, l := range got.TaxLevel {
+			if l.Level != want.TaxLevel[i].Level || l.Tax != want.TaxLevel[i].Tax {
+				t.Errorf("expected level %+v but got %+v", want.TaxLevel[i], l)
+			}
+		}
+	})
+
+	t.Run("should return field violations for an invalid request", func(t *testing.T) {
+		_, err := dial(t, New(&stub{}, Config{})).Calculate(context.Background(), &taxpb.CalculateRequest{TotalIncome: -1.0})
+
+		st := status.Convert(err)
+		if st.Code() != codes.InvalidArgument {
+			t.Fatalf("expected %v but got %v", codes.InvalidArgument, st)
+		}
+
+		var reason, field string
+		for _, d := range st.Details() {
+			switch d := d.(type) {
+			case *errdetails.ErrorInfo:
+				reason = d.Reason
+			case *errdetails.BadRequest:
+				field = d.FieldViolations[0].Field
+			}
+		}
+		if reason != "TAX_TOTAL_INCOME_NEGATIVE" || field != "totalIncome" {
+			t.Errorf("expected TAX_TOTAL_INCOME_NEGATIVE on totalIncome but got %v %v", reason, field)
+		}
+	})
+
+	t.Run("should localize levels and errors", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "accept-language", "th")
+		c := dial(t, New(&stub{}, Config{}))
+
+		got, _ := c.Calculate(ctx, request())
+		if got.TaxLevel[4].Level != "2,000,001 ขึ้นไป" {
+			t.Errorf("expected a Thai label but got %v", got.TaxLevel[4].Level)
+		}
+
+		_, err := c.Calculate(ctx, &taxpb.CalculateRequest{TotalIncome: -1.0})
+		if msg := status.Convert(err).Message(); msg != "เงินได้ทั้งหมดต้องมากกว่าหรือเท่ากับ 0" {
+			t.Errorf("expected a Thai message but got %v", msg)
+		}
+	})
+
+	t.Run("should return internal if the store fails", func(t *testing.T) {
+		_, err := dial(t, New(&stub{err: errors.New("database is down")}, Config{})).Calculate(context.Background(), request())
+
+		if status.Code(err) != codes.Internal {
+			t.Errorf("expected %v but got %v", codes.Internal, err)
+		}
+	})
+}
+
+func TestCalculateBatch(t *testing.T) {
+	send := func(t *testing.T, c taxpb.TaxServiceClient, reqs ...*taxpb.CalculateRequest) (*taxpb.CalculateBatchResponse, error) {
+		t.Helper()
+
+		stream, err := c.CalculateBatch(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range reqs {
+			if err := stream.Send(r); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return stream.CloseAndRecv()
+	}
+
+	t.Run("should return results in stream order with per-item errors", func(t *testing.T) {
+		first := request()
+		first.ReferenceId = "a"
+
+		got, err := send(t, dial(t, New(&stub{}, Config{BatchWorkers: 4})), first, &taxpb.CalculateRequest{ReferenceId: "b", TotalIncome: -1.0})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := tax.CalculateTax(tax.TaxDetails{TotalIncome: 500000.0, Allowances: []allowance.Allowance{{AllowanceType: allowance.Donation, Amount: 200000.0}, {AllowanceType: allowance.KReceipt, Amount: 70000.0}}}.CalculateNetIncome(caps), 25000.0)
+
+		if len(got.Results) != 2 {
+			t.Fatalf("expected 2 results but got %v", got.Results)
+		}
+
+		a, b := got.Results[0], got.Results[1]
+		if a.ReferenceId != "a" || a.Result.GetTax() != want.Tax || a.Result.GetTaxRefund() != want.TaxRefund || len(a.Errors) != 0 {
+			t.Errorf("expected %+v for a but got %+v", want, a)
+		}
+		if b.Index != 1 || b.ReferenceId != "b" || b.Result != nil || len(b.Errors) != 1 || b.Errors[0].Code != "TAX_TOTAL_INCOME_NEGATIVE" {
+			t.Errorf("expected an error for b but got %+v", b)
+		}
+	})
+
+	t.Run("should reject an empty stream", func(t *testing.T) {
+		_, err := send(t, dial(t, New(&stub{}, Config{})))
+
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected %v but got %v", codes.InvalidArgument, err)
+		}
+	})
+
+	t.Run("should reject a stream over the limit", func(t *testing.T) {
+		_, err := send(t, dial(t, New(&stub{}, Config{BatchLimit: 1})), request(), request())
+
+		if status.Code(err) != codes.InvalidArgument || !strings.Contains(status.Convert(err).Message(), "1") {
+			t.Errorf("expected %v but got %v", codes.InvalidArgument, err)
+		}
+	})
+
+	t.Run("should reject a stream as soon as it is over the limit", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		stream, err := dial(t, New(&stub{}, Config{BatchLimit: 1})).CalculateBatch(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stream.Send(request())
+		stream.Send(request())
+
+		err = stream.RecvMsg(&taxpb.CalculateBatchResponse{})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected %v before the stream is closed but got %v", codes.InvalidArgument, err)
+		}
+	})
+}
+
+func TestGetAllowanceCaps(t *testing.T) {
+	got, err := dial(t, New(&stub{}, Config{})).GetAllowanceCaps(context.Background(), &taxpb.GetAllowanceCapsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.Caps) != len(allowance.AllowanceTypes) || got.Caps[0].AllowanceType != "personal" || got.Caps[0].MaxAmount != 60000.0 {
+		t.Errorf("expected every cap but got %v", got.Caps)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	st := &stub{panics: true}
+	c := dial(t, New(st, Config{}))
+
+	_, err := c.Calculate(context.Background(), request())
+	if status.Code(err) != codes.Internal || status.Convert(err).Message() != "internal server error" {
+		t.Errorf("expected %v without the panic value but got %v", codes.Internal, err)
+	}
+
+	stream, err := c.CalculateBatch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Send(request())
+	if _, err := stream.CloseAndRecv(); status.Code(err) != codes.Internal {
+		t.Errorf("expected %v from the stream but got %v", codes.Internal, err)
+	}
+
+	st.panics = false
+	if _, err := c.Calculate(context.Background(), request()); err != nil {
+		t.Errorf("expected the server to keep serving but got %v", err)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	batch := func(c taxpb.TaxServiceClient, ctx context.Context, n int) error {
+		stream, err := c.CalculateBatch(ctx)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			stream.Send(request())
+		}
+		_, err = stream.CloseAndRecv()
+		return err
+	}
+
+	t.Run("should reject calls over the rate limit with a retry delay", func(t *testing.T) {
+		c := dial(t, New(&stub{}, Config{CalcLimiter: ratelimit.New(ratelimit.Limit{Requests: 1, Window: time.Minute})}))
+
+		if _, err := c.Calculate(context.Background(), request()); err != nil {
+			t.Fatal(err)
+		}
+		_, err := c.Calculate(context.Background(), request())
+
+		var reason string
+		var retry time.Duration
+		for _, d := range status.Convert(err).Details() {
+			switch d := d.(type) {
+			case *errdetails.ErrorInfo:
+				reason = d.Reason
+			case *errdetails.RetryInfo:
+				retry = d.RetryDelay.AsDuration()
+			}
+		}
+		if status.Code(err) != codes.ResourceExhausted || reason != "RATE_LIMITED" || retry <= 0 {
+			t.Errorf("expected %v with RATE_LIMITED and a retry delay but got %v %v %v", codes.ResourceExhausted, err, reason, retry)
+		}
+	})
+
+	t.Run("should count unverified api keys under the peer ip", func(t *testing.T) {
+		c := dial(t, New(&stub{}, Config{BatchLimiter: ratelimit.New(ratelimit.Limit{Requests: 1, Window: time.Minute})}))
+		withKey := func(secret string) context.Context {
+			return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", secret)
+		}
+
+		if err := batch(c, withKey("ktx_a"), 1); err != nil {
+			t.Fatal(err)
+		}
+		if err := batch(c, withKey("ktx_b"), 1); status.Code(err) != codes.ResourceExhausted {
+			t.Errorf("expected another key from the same peer to be %v but got %v", codes.ResourceExhausted, err)
+		}
+	})
+
+	t.Run("should take batch rows from the daily quota", func(t *testing.T) {
+		q := ratelimit.NewQuota(3)
+		c := dial(t, New(&stub{}, Config{RowQuota: q, BatchLimit: 5}))
+
+		if err := batch(c, context.Background(), 2); err != nil {
+			t.Fatal(err)
+		}
+
+		err := batch(c, context.Background(), 2)
+		if status.Code(err) != codes.ResourceExhausted || status.Convert(err).Message() != tax.ErrRowQuotaExceeded {
+			t.Errorf("expected %v but got %v", codes.ResourceExhausted, err)
+		}
+
+		if err := batch(c, context.Background(), 6); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected an oversized batch to be %v but got %v", codes.InvalidArgument, err)
+		}
+
+		if err := batch(c, context.Background(), 1); err != nil {
+			t.Errorf("expected the last row to be allowed but got %v", err)
+		}
+	})
+}
+
+func TestAuthorize(t *testing.T) {
+	keys := apikey.New(&keyStub{keys: []apikey.Key{
+		{ID: 1, Name: "payroll", Scopes: []apikey.Scope{apikey.Calc, apikey.Batch}, Hash: apikey.Hash("ktx_payroll")},
+		{ID: 2, Name: "calculator", Scopes: []apikey.Scope{apikey.Calc}, Hash: apikey.Hash("ktx_calculator")},
+	}}, apikey.Config{Anonymous: []apikey.Scope{apikey.Calc}})
+	c := dial(t, New(&stub{}, Config{Keys: keys}))
+
+	withKey := func(secret string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", secret)
+	}
+
+	testCases := []struct {
+		name string
+		call func() error
+		want codes.Code
+	}{
+		{"should allow anonymous calc calls", func() error {
+			_, err := c.Calculate(context.Background(), request())
+			return err
+		}, codes.OK},
+		{"should reject anonymous batch calls", func() error {
+			stream, _ := c.CalculateBatch(context.Background())
+			stream.Send(request())
+			_, err := stream.CloseAndRecv()
+			return err
+		}, codes.Unauthenticated},
+		{"should reject an unknown key", func() error {
+			_, err := c.GetAllowanceCaps(withKey("ktx_unknown"), &taxpb.GetAllowanceCapsRequest{})
+			return err
+		}, codes.Unauthenticated},
+		{"should reject a key without scope", func() error {
+			stream, _ := c.CalculateBatch(withKey("ktx_calculator"))
+			stream.Send(request())
+			_, err := stream.CloseAndRecv()
+			return err
+		}, codes.PermissionDenied},
+		{"should allow a key with scope", func() error {
+			stream, _ := c.CalculateBatch(withKey("ktx_payroll"))
+			stream.Send(request())
+			_, err := stream.CloseAndRecv()
+			return err
+		}, codes.OK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := status.Code(tc.call()); got != tc.want {
+				t.Errorf("expected %v but got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/auth"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+const (
+	DefaultMaxAttempts = 5
+	DefaultBackoff     = time.Second
+	DefaultTimeout     = 10 * time.Second
+)
+
+const (
+	deliveryLimit   = 100
+	maxResponseBody = 64 << 10
+	userAgent       = "assessment-tax-webhooks"
+)
+
+type Storer interface {
+	CreateSubscription(Subscription) (Subscription, error)
+	Subscriptions() ([]Subscription, error)
+	DeleteSubscription(id int) (Subscription, error)
+	RecordDelivery(Delivery) (Delivery, error)
+	Deliveries(subscriptionID, limit int) ([]Delivery, error)
+}
+
+type Publisher interface {
+	Publish(c echo.Context, event string, data interface{})
+}
+
+type Config struct {
+	Client      *http.Client
+	MaxAttempts int
+	Backoff     time.Duration
+}
+
+type Handler struct {
+	store Storer
+	cfg   Config
+	wg    sync.WaitGroup
+	done  chan struct{}
+	once  sync.Once
+}
+
+func New(store Storer, cfg Config) *Handler {
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: DefaultTimeout}
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = DefaultBackoff
+	}
+	return &Handler{store: store, cfg: cfg, done: make(chan struct{})}
+}
+
+func (h *Handler) CreateSubscriptionHandler(c echo.Context) error {
+	ns := NewSubscription{}
+
+	if err := c.Bind(&ns); err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	if err := ns.Validate(); err != nil {
+		return problem.Respond(c, http.StatusBadRequest, err)
+	}
+
+	secret, err := GenerateSecret()
+
+	if err != nil {
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+
+	events := ns.Events
+	if len(events) == 0 {
+		events = Events
+	}
+
+	s, err := h.store.CreateSubscription(Subscription{
+		URL:       ns.URL,
+		Events:    events,
+		Secret:    secret,
+		CreatedBy: auth.Username(c),
+	})
+
+	if err != nil {
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusCreated, IssuedSubscription{Subscription: s, Secret: secret})
+}
+
+func (h *Handler) SubscriptionsHandler(c echo.Context) error {
+	ss, err := h.store.Subscriptions()
+
+	if err != nil {
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, Subscriptions{Subscriptions: ss})
+}
+
+func (h *Handler) DeleteSubscriptionHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return problem.Respond(c, http.StatusBadRequest, errInvalidID)
+	}
+
+	s, err := h.store.DeleteSubscription(id)
+
+	if errors.Is(err, ErrNotFound) {
+		return problem.Respond(c, http.StatusNotFound, err)
+	}
+
+	if err != nil {
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, s)
+}
+
+func (h *Handler) DeliveriesHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return problem.Respond(c, http.StatusBadRequest, errInvalidID)
+	}
+
+	ds, err := h.store.Deliveries(id, deliveryLimit)
+
+	if errors.Is(err, ErrNotFound) {
+		return problem.Respond(c, http.StatusNotFound, err)
+	}
+
+	if err != nil {
+		return problem.Respond(c, http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, Deliveries{Deliveries: ds})
+}
+
+func (h *Handler) Publish(c echo.Context, event string, data interface{}) {
+	logger := c.Logger()
+
+	ss, err := h.store.Subscriptions()
+	if err != nil {
+		logger.Errorf("failed to load webhook subscriptions for %s: %v", event, err)
+		return
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		logger.Errorf("failed to encode webhook event %s: %v", event, err)
+		return
+	}
+
+	id, err := eventID()
+	if err != nil {
+		logger.Errorf("failed to create webhook event id for %s: %v", event, err)
+		return
+	}
+
+	ev := Event{ID: id, Type: event, At: time.Now().UTC(), Actor: auth.Username(c), Data: raw}
+	body, _ := json.Marshal(ev)
+
+	for _, s := range ss {
+		if !s.Active() || !s.Wants(event) {
+			continue
+		}
+
+		h.wg.Add(1)
+		go func(s Subscription) {
+			defer h.wg.Done()
+			h.deliver(logger, s, ev, body)
+		}(s)
+	}
+}
+
+func (h *Handler) Shutdown(ctx context.Context) error {
+	h.once.Do(func() { close(h.done) })
+
+	finished := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (h *Handler) deliver(logger echo.Logger, s Subscription, ev Event, body []byte) {
+	for attempt := 1; attempt <= h.cfg.MaxAttempts; attempt++ {
+		d := h.send(s, ev, body, attempt)
+
+		if _, err := h.store.RecordDelivery(d); err != nil {
+			logger.Errorf("failed to record webhook delivery %s to subscription %d: %v", ev.ID, s.ID, err)
+		}
+
+		if d.Succeeded || attempt == h.cfg.MaxAttempts {
+			return
+		}
+
+		select {
+		case <-time.After(h.cfg.Backoff << (attempt - 1)):
+		case <-h.done:
+			return
+		}
+	}
+}
+
+func (h *Handler) send(s Subscription, ev Event, body []byte, attempt int) Delivery {
+	d := Delivery{SubscriptionID: s.ID, EventID: ev.ID, Event: ev.Type, Attempt: attempt, At: time.Now().UTC()}
+
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		d.Error = err.Error()
+		return d
+	}
+
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(HeaderEvent, ev.Type)
+	req.Header.Set(HeaderDelivery, ev.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(d.At.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(s.Secret, d.At, body))
+
+	res, err := h.cfg.Client.Do(req)
+	d.DurationMS = time.Since(d.At).Milliseconds()
+	if err != nil {
+		d.Error = err.Error()
+		return d
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, maxResponseBody))
+
+	d.StatusCode = res.StatusCode
+	d.Succeeded = res.StatusCode >= 200 && res.StatusCode < 300
+	if !d.Succeeded {
+		d.Error = res.Status
+	}
+	return d
+}
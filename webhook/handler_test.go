@@ -0,0 +1,399 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/varissara-wo/assessment-tax/auth"
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+type stub struct {
+	mu         sync.Mutex
+	subs       []Subscription
+	deliveries []Delivery
+	err        error
+}
+
+func (s *stub) CreateSubscription(ws Subscription) (Subscription, error) {
+	if s.err != nil {
+		return Subscription{}, s.err
+	}
+	ws.ID = len(s.subs) + 1
+	ws.CreatedAt = time.Now()
+	s.subs = append(s.subs, ws)
+	return ws, nil
+}
+
+func (s *stub) Subscriptions() ([]Subscription, error) {
+	return s.subs, s.err
+}
+
+func (s *stub) DeleteSubscription(id int) (Subscription, error) {
+	if s.err != nil {
+		return Subscription{}, s.err
+	}
+	for i, ws := range s.subs {
+		if ws.ID == id {
+			now := time.Now()
+			s.subs[i].DeletedAt = &now
+			return s.subs[i], nil
+		}
+	}
+	return Subscription{}, ErrNotFound
+}
+
+func (s *stub) RecordDelivery(d Delivery) (Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d.ID = int64(len(s.deliveries) + 1)
+	s.deliveries = append(s.deliveries, d)
+	return d, nil
+}
+
+func (s *stub) Deliveries(subscriptionID, limit int) ([]Delivery, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if subscriptionID > len(s.subs) {
+		return nil, ErrNotFound
+	}
+	ds := []Delivery{}
+	for _, d := range s.deliveries {
+		if d.SubscriptionID == subscriptionID {
+			ds = append(ds, d)
+		}
+	}
+	return ds, nil
+}
+
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+type receiver struct {
+	mu       sync.Mutex
+	requests []received
+	fails    int32
+	calls    int32
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	r.requests = append(r.requests, received{header: req.Header.Clone(), body: body})
+	r.mu.Unlock()
+
+	if atomic.AddInt32(&r.calls, 1) <= atomic.LoadInt32(&r.fails) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func publish(h *Handler, event string, data interface{}) {
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/admin/deductions/personal", nil), httptest.NewRecorder())
+	c.Set(auth.IdentityContextKey, auth.Identity{Username: "approver"})
+
+	h.Publish(c, event, data)
+	h.wg.Wait()
+}
+
+func TestCreateSubscriptionHandler(t *testing.T) {
+	t.Run("should return 400 if url is invalid", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/admin/webhooks", bytes.NewBufferString(`{"url": "payroll"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := New(&stub{}, Config{})
+		err := h.CreateSubscriptionHandler(c)
+
+		if err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		var got problem.Problem
+		json.Unmarshal(rec.Body.Bytes(), &got)
+
+		if rec.Code != http.StatusBadRequest || got.Detail != ErrInvalidURL {
+			t.Errorf("expected 400 %v but got %v %v", ErrInvalidURL, rec.Code, got.Detail)
+		}
+	})
+
+	t.Run("should return 201 with the secret once and subscribe to every event by default", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/admin/webhooks", bytes.NewBufferString(`{"url": "https://payroll.example.com/hooks"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set(auth.IdentityContextKey, auth.Identity{Username: "alice"})
+
+		st := &stub{}
+		h := New(st, Config{})
+		err := h.CreateSubscriptionHandler(c)
+
+		if err != nil {
+			t.Errorf("expected nil but got %v", err)
+		}
+
+		var got IssuedSubscription
+		json.Unmarshal(rec.Body.Bytes(), &got)
+
+		if rec.Code != http.StatusCreated || got.CreatedBy != "alice" || len(got.Events) != len(Events) {
+			t.Errorf("expected 201 with all events created by alice but got %v %+v", rec.Code, got)
+		}
+
+		if got.Secret == "" || st.subs[0].Secret != got.Secret {
+			t.Errorf("expected the stored secret to be returned but got %q", got.Secret)
+		}
+	})
+}
+
+func TestSubscriptionsHandler(t *testing.T) {
+	t.Run("should list subscriptions without secrets", func(t *testing.T) {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/admin/webhooks", nil), rec)
+
+		h := New(&stub{subs: []Subscription{{ID: 1, URL: "https://payroll.example.com/hooks", Secret: "whsec_hidden"}}}, Config{})
+		h.SubscriptionsHandler(c)
+
+		var got Subscriptions
+		json.Unmarshal(rec.Body.Bytes(), &got)
+
+		if rec.Code != http.StatusOK || len(got.Subscriptions) != 1 || strings.Contains(rec.Body.String(), "whsec_hidden") {
+			t.Errorf("expected one subscription without its secret but got %v", rec.Body.String())
+		}
+	})
+}
+
+func TestDeleteSubscriptionHandler(t *testing.T) {
+	testCases := []struct {
+		name       string
+		id         string
+		err        error
+		wantStatus int
+	}{
+		{"should return 400 if id is invalid", "abc", nil, http.StatusBadRequest},
+		{"should return 404 if subscription does not exist", "9", nil, http.StatusNotFound},
+		{"should return 500 if store fails", "1", errors.New("db down"), http.StatusInternalServerError},
+		{"should return 200 and mark the subscription deleted", "1", nil, http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodDelete, "/", nil), rec)
+			c.SetPath("/admin/webhooks/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(tc.id)
+
+			st := &stub{subs: []Subscription{{ID: 1, URL: "https://payroll.example.com/hooks"}}, err: tc.err}
+			h := New(st, Config{})
+			h.DeleteSubscriptionHandler(c)
+
+			if rec.Code != tc.wantStatus {
+				t.Errorf("expected status code %v but got %v", tc.wantStatus, rec.Code)
+			}
+
+			if tc.wantStatus == http.StatusOK && st.subs[0].Active() {
+				t.Errorf("expected subscription to be deleted")
+			}
+		})
+	}
+}
+
+func TestDeliveriesHandler(t *testing.T) {
+	testCases := []struct {
+		name       string
+		id         string
+		wantStatus int
+		wantCount  int
+	}{
+		{"should return 400 if id is invalid", "0", http.StatusBadRequest, 0},
+		{"should return 404 if subscription does not exist", "9", http.StatusNotFound, 0},
+		{"should return the delivery log of the subscription", "1", http.StatusOK, 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+			c.SetPath("/admin/webhooks/:id/deliveries")
+			c.SetParamNames("id")
+			c.SetParamValues(tc.id)
+
+			st := &stub{
+				subs:       []Subscription{{ID: 1}, {ID: 2}},
+				deliveries: []Delivery{{SubscriptionID: 1, Attempt: 1}, {SubscriptionID: 2, Attempt: 1}, {SubscriptionID: 1, Attempt: 2}},
+			}
+			h := New(st, Config{})
+			h.DeliveriesHandler(c)
+
+			var got Deliveries
+			json.Unmarshal(rec.Body.Bytes(), &got)
+
+			if rec.Code != tc.wantStatus || len(got.Deliveries) != tc.wantCount {
+				t.Errorf("expected %v with %d deliveries but got %v with %d", tc.wantStatus, tc.wantCount, rec.Code, len(got.Deliveries))
+			}
+		})
+	}
+}
+
+func TestPublish(t *testing.T) {
+	proposal := map[string]interface{}{"id": 7, "allowanceType": "personal", "amount": 70000.0}
+
+	t.Run("should post a signed event to matching subscriptions", func(t *testing.T) {
+		r := &receiver{}
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+
+		st := &stub{subs: []Subscription{
+			{ID: 1, URL: srv.URL, Events: Events, Secret: "whsec_one"},
+			{ID: 2, URL: srv.URL, Events: []string{EventDeductionRejected}, Secret: "whsec_two"},
+		}}
+		h := New(st, Config{})
+
+		publish(h, EventDeductionApproved, proposal)
+
+		if len(r.requests) != 1 {
+			t.Fatalf("expected one delivery but got %d", len(r.requests))
+		}
+
+		got := r.requests[0]
+		if !Verify("whsec_one", got.header.Get(HeaderTimestamp), got.header.Get(HeaderSignature), got.body, time.Now()) {
+			t.Errorf("expected a valid signature but got %v", got.header.Get(HeaderSignature))
+		}
+
+		var ev Event
+		json.Unmarshal(got.body, &ev)
+
+		if ev.Type != EventDeductionApproved || ev.Actor != "approver" || got.header.Get(HeaderEvent) != ev.Type || got.header.Get(HeaderDelivery) != ev.ID {
+			t.Errorf("unexpected event %+v with headers %v", ev, got.header)
+		}
+
+		if !strings.Contains(string(ev.Data), `"amount":70000`) {
+			t.Errorf("expected the proposal as data but got %s", ev.Data)
+		}
+
+		if len(st.deliveries) != 1 || !st.deliveries[0].Succeeded || st.deliveries[0].StatusCode != http.StatusNoContent {
+			t.Errorf("expected one successful delivery logged but got %+v", st.deliveries)
+		}
+	})
+
+	t.Run("should skip deleted subscriptions", func(t *testing.T) {
+		r := &receiver{}
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+
+		deleted := time.Now()
+		h := New(&stub{subs: []Subscription{{ID: 1, URL: srv.URL, Events: Events, DeletedAt: &deleted}}}, Config{})
+
+		publish(h, EventDeductionProposed, proposal)
+
+		if len(r.requests) != 0 {
+			t.Errorf("expected no delivery but got %d", len(r.requests))
+		}
+	})
+
+	t.Run("should retry failed deliveries and log every attempt", func(t *testing.T) {
+		r := &receiver{fails: 2}
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+
+		st := &stub{subs: []Subscription{{ID: 1, URL: srv.URL, Events: Events, Secret: "whsec_one"}}}
+		h := New(st, Config{Backoff: time.Millisecond})
+
+		publish(h, EventDeductionApproved, proposal)
+
+		if len(st.deliveries) != 3 {
+			t.Fatalf("expected 3 attempts but got %d", len(st.deliveries))
+		}
+
+		for i, d := range st.deliveries {
+			if d.Attempt != i+1 || d.EventID != st.deliveries[0].EventID {
+				t.Errorf("expected attempt %d of the same event but got %+v", i+1, d)
+			}
+		}
+
+		if st.deliveries[0].Succeeded || st.deliveries[0].StatusCode != http.StatusServiceUnavailable || !st.deliveries[2].Succeeded {
+			t.Errorf("expected two failures then success but got %+v", st.deliveries)
+		}
+	})
+
+	t.Run("should give up after max attempts", func(t *testing.T) {
+		r := &receiver{fails: 100}
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+
+		st := &stub{subs: []Subscription{{ID: 1, URL: srv.URL, Events: Events}}}
+		h := New(st, Config{MaxAttempts: 3, Backoff: time.Millisecond})
+
+		publish(h, EventDeductionRejected, proposal)
+
+		if len(st.deliveries) != 3 || r.calls != 3 {
+			t.Fatalf("expected 3 attempts but got %d", len(st.deliveries))
+		}
+
+		if st.deliveries[2].Succeeded || st.deliveries[2].Error == "" {
+			t.Errorf("expected the last attempt to be logged as failed but got %+v", st.deliveries[2])
+		}
+	})
+
+	t.Run("should log connection errors", func(t *testing.T) {
+		srv := httptest.NewServer(&receiver{})
+		url := srv.URL
+		srv.Close()
+
+		st := &stub{subs: []Subscription{{ID: 1, URL: url, Events: Events}}}
+		h := New(st, Config{MaxAttempts: 1})
+
+		publish(h, EventDeductionProposed, proposal)
+
+		if len(st.deliveries) != 1 || st.deliveries[0].Succeeded || st.deliveries[0].Error == "" || st.deliveries[0].StatusCode != 0 {
+			t.Errorf("expected a failed delivery with an error but got %+v", st.deliveries)
+		}
+	})
+
+	t.Run("should stop retrying on shutdown", func(t *testing.T) {
+		r := &receiver{fails: 100}
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+
+		st := &stub{subs: []Subscription{{ID: 1, URL: srv.URL, Events: Events}}}
+		h := New(st, Config{Backoff: time.Hour})
+
+		e := echo.New()
+		c := e.NewContext(httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder())
+		h.Publish(c, EventDeductionProposed, proposal)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		if err := h.Shutdown(ctx); err != nil {
+			t.Fatalf("expected shutdown to finish but got %v", err)
+		}
+
+		if atomic.LoadInt32(&r.calls) > 1 {
+			t.Errorf("expected at most one attempt but got %d", r.calls)
+		}
+	})
+}
@@ -0,0 +1,180 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const (
+	EventDeductionProposed = "deduction.proposed"
+	EventDeductionApproved = "deduction.approved"
+	EventDeductionRejected = "deduction.rejected"
+)
+
+var Events = []string{EventDeductionProposed, EventDeductionApproved, EventDeductionRejected}
+
+const (
+	secretPrefix    = "whsec_"
+	signaturePrefix = "sha256="
+	maxURLLength    = 2048
+)
+
+// Tolerance is how far a delivery's timestamp may be from the receiver's
+// clock before Verify rejects it as a replay.
+const Tolerance = 5 * time.Minute
+
+const (
+	ErrInvalidURL   = "url must be an absolute http or https url of at most 2048 characters"
+	ErrInvalidEvent = "events must be deduction.proposed, deduction.approved or deduction.rejected"
+	ErrInvalidID    = "webhook id must be a positive integer"
+)
+
+var (
+	ErrNotFound = problem.New("WEBHOOK_NOT_FOUND", "id", "webhook not found")
+
+	errInvalidURL   = problem.New("WEBHOOK_URL_INVALID", "url", ErrInvalidURL)
+	errInvalidEvent = problem.New("WEBHOOK_EVENT_INVALID", "events", ErrInvalidEvent)
+	errInvalidID    = problem.New("WEBHOOK_ID_INVALID", "id", ErrInvalidID)
+)
+
+type Subscription struct {
+	ID        int        `json:"id"`
+	URL       string     `json:"url"`
+	Events    []string   `json:"events"`
+	Secret    string     `json:"-"`
+	CreatedBy string     `json:"createdBy"`
+	CreatedAt time.Time  `json:"createdAt"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+}
+
+type Subscriptions struct {
+	Subscriptions []Subscription `json:"subscriptions"`
+}
+
+type NewSubscription struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+type IssuedSubscription struct {
+	Subscription
+	Secret string `json:"secret"`
+}
+
+type Event struct {
+	ID    string          `json:"id"`
+	Type  string          `json:"type"`
+	At    time.Time       `json:"at"`
+	Actor string          `json:"actor"`
+	Data  json.RawMessage `json:"data"`
+}
+
+type Delivery struct {
+	ID             int64     `json:"id"`
+	SubscriptionID int       `json:"subscriptionId"`
+	EventID        string    `json:"eventId"`
+	Event          string    `json:"event"`
+	Attempt        int       `json:"attempt"`
+	StatusCode     int       `json:"statusCode,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	Succeeded      bool      `json:"succeeded"`
+	DurationMS     int64     `json:"durationMs"`
+	At             time.Time `json:"at"`
+}
+
+type Deliveries struct {
+	Deliveries []Delivery `json:"deliveries"`
+}
+
+func ValidateEvent(e string) error {
+	for _, v := range Events {
+		if e == v {
+			return nil
+		}
+	}
+	return errInvalidEvent
+}
+
+func (ns NewSubscription) Validate() error {
+	var es problem.Errors
+
+	u, err := url.Parse(ns.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(ns.URL) > maxURLLength {
+		es.Add(errInvalidURL)
+	}
+
+	for i, e := range ns.Events {
+		if err := ValidateEvent(e); err != nil {
+			es.Add(problem.Nest(err, fmt.Sprintf("[%d]", i), nil))
+		}
+	}
+
+	return es.Err()
+}
+
+func (s Subscription) Active() bool {
+	return s.DeletedAt == nil
+}
+
+func (s Subscription) Wants(event string) bool {
+	for _, e := range s.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func GenerateSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}
+
+func Sign(secret string, at time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(at.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a delivery received at now and rejects
+// timestamps more than Tolerance away from it.
+func Verify(secret, timestamp, signature string, body []byte, now time.Time) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	at := time.Unix(ts, 0)
+	if d := now.Sub(at); d > Tolerance || d < -Tolerance {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, at, body)))
+}
+
+func eventID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,100 @@
+package webhook
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/varissara-wo/assessment-tax/problem"
+)
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		ns      NewSubscription
+		wantErr []string
+	}{
+		{"should accept https url with known events", NewSubscription{URL: "https://payroll.example.com/hooks", Events: []string{EventDeductionApproved}}, nil},
+		{"should accept url without events", NewSubscription{URL: "http://localhost:8080/hooks"}, nil},
+		{"should reject relative url", NewSubscription{URL: "/hooks"}, []string{"url"}},
+		{"should reject non http scheme", NewSubscription{URL: "ftp://example.com/hooks"}, []string{"url"}},
+		{"should reject too long url", NewSubscription{URL: "https://example.com/" + strings.Repeat("a", maxURLLength)}, []string{"url"}},
+		{"should reject unknown event", NewSubscription{URL: "https://example.com", Events: []string{EventDeductionApproved, "cap.changed"}}, []string{"[1].events"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.ns.Validate()
+
+			if tc.wantErr == nil {
+				if err != nil {
+					t.Errorf("expected nil but got %v", err)
+				}
+				return
+			}
+
+			var got problem.Errors
+			if !errors.As(err, &got) || len(got) != len(tc.wantErr) {
+				t.Fatalf("expected %v errors but got %v", len(tc.wantErr), err)
+			}
+			for i, field := range tc.wantErr {
+				if got[i].Field != field {
+					t.Errorf("expected error on %v but got %v", field, got[i].Field)
+				}
+			}
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"type":"deduction.approved"}`)
+	at := time.Unix(1700000000, 0)
+	ts := strconv.FormatInt(at.Unix(), 10)
+
+	t.Run("should verify its own signature", func(t *testing.T) {
+		sig := Sign("whsec_test", at, body)
+
+		if !strings.HasPrefix(sig, signaturePrefix) || !Verify("whsec_test", ts, sig, body, at) {
+			t.Errorf("expected %v to verify", sig)
+		}
+	})
+
+	t.Run("should reject a different secret, body or timestamp", func(t *testing.T) {
+		sig := Sign("whsec_test", at, body)
+
+		if Verify("whsec_other", ts, sig, body, at) {
+			t.Errorf("expected a different secret to fail")
+		}
+		if Verify("whsec_test", ts, sig, []byte(`{}`), at) {
+			t.Errorf("expected a different body to fail")
+		}
+		if Verify("whsec_test", "1700000001", sig, body, at) || Verify("whsec_test", "now", sig, body, at) {
+			t.Errorf("expected a different timestamp to fail")
+		}
+	})
+
+	t.Run("should reject a timestamp outside the tolerance", func(t *testing.T) {
+		sig := Sign("whsec_test", at, body)
+
+		if !Verify("whsec_test", ts, sig, body, at.Add(Tolerance)) {
+			t.Errorf("expected a delivery at the tolerance to verify")
+		}
+		if Verify("whsec_test", ts, sig, body, at.Add(Tolerance+time.Second)) {
+			t.Errorf("expected a replayed delivery to fail")
+		}
+		if Verify("whsec_test", ts, sig, body, at.Add(-Tolerance-time.Second)) {
+			t.Errorf("expected a delivery from the future to fail")
+		}
+	})
+
+	t.Run("should generate distinct prefixed secrets", func(t *testing.T) {
+		a, _ := GenerateSecret()
+		b, _ := GenerateSecret()
+
+		if !strings.HasPrefix(a, secretPrefix) || a == b {
+			t.Errorf("expected distinct secrets but got %v and %v", a, b)
+		}
+	})
+}